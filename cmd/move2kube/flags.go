@@ -41,10 +41,30 @@ const (
 	preSetFlag = "preset"
 	// overwriteFlag is the name of the flag that lets you overwrite the output directory if it exists
 	overwriteFlag = "overwrite"
+	// kindFlag is the name of the flag that contains the list of kinds to parameterize
+	kindFlag = "kind"
+	// outputTypeFlag is the name of the flag that contains the list of output types to generate while parameterizing
+	outputTypeFlag = "outputtype"
 	// customizationsFlag is the path to customizations directory
 	customizationsFlag = "customizations"
-	qadisablecliFlag   = "qadisablecli"
-	qaportFlag         = "qaport"
+	// overwriteValuesFlag is the name of the flag that lets you overwrite the existing Helm values files instead of merging into them
+	overwriteValuesFlag = "overwritevalues"
+	// validatePackFlag is the name of the flag that only validates the key expressions in the customizations
+	validatePackFlag = "validatepack"
+	// diffFlag is the name of the flag that contains the path to write the diff between the source and the parameterized output
+	diffFlag = "diff"
+	// answersFlag is the name of the flag that contains the path to a JSON file with the answers to the questions
+	answersFlag = "answers"
+	// headerFlag is the name of the flag that starts each parameterized file with a header noting the source file
+	headerFlag = "header"
+	// reproducibleFlag is the name of the flag that omits the timestamps so that the output is the same on every run
+	reproducibleFlag = "reproducible"
+	// helmChartNameFlag is the name of the flag that contains the name of the generated Helm chart
+	helmChartNameFlag = "chartname"
+	// helmChartVersionFlag is the name of the flag that contains the version of the generated Helm chart
+	helmChartVersionFlag = "chartversion"
+	qadisablecliFlag     = "qadisablecli"
+	qaportFlag           = "qaport"
 )
 
 type qaflags struct {
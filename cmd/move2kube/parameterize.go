@@ -17,59 +17,256 @@
 package main
 
 import (
+	"context"
+	"io"
+	"io/ioutil"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"strings"
+	"syscall"
 
 	"github.com/konveyor/move2kube/internal/common"
 	"github.com/konveyor/move2kube/lib"
+	"github.com/konveyor/move2kube/qaengine"
+	parameterizertypes "github.com/konveyor/move2kube/types/parameterizer"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
 
+// stdoutPath is the path that writes to stdout instead of a file
+const stdoutPath = "-"
+
 type parameterizeFlags struct {
 	// outpath contains the path to the output folder
 	outpath string
-	// SourceFlag contains path to the source folder
-	srcpath string
+	// srcpaths contains the paths to the source folders
+	srcpaths []string
 	// customizationsPath contains path to the pack folder
 	customizationsPath string
 	// overwrite: if the output folder exists then it will be overwritten
 	overwrite bool
+	// kinds contains the list of kinds to parameterize. If empty, all kinds are parameterized
+	kinds []string
+	// outputTypes contains the list of output types to generate. If empty, the default output types are generated
+	outputTypes []string
+	// overwriteValues: overwrite the existing Helm values files instead of merging the new values into them
+	overwriteValues bool
+	// validatePack: only validate the key expressions in the customizations without parameterizing
+	validatePack bool
+	// diffPath contains the path to write the diff between the source and the parameterized output. "-" means stdout
+	diffPath string
+	// helmChartName is the name of the Helm chart. It overrides the name in the customizations
+	helmChartName string
+	// helmChartVersion is the version of the Helm chart. It overrides the version in the customizations
+	helmChartVersion string
+	// answersPath contains the path to a JSON file that maps the question ids to their answers
+	answersPath string
+	// header: start each parameterized file with a header noting the source file and the time of generation
+	header bool
+	// reproducible: omit the timestamps so that the output is the same on every run
+	reproducible bool
 	qaflags
 }
 
 func parameterizeHandler(_ *cobra.Command, flags parameterizeFlags) {
 	var err error
-	if flags.srcpath, err = filepath.Abs(flags.srcpath); err != nil {
-		logrus.Fatalf("Failed to make the source directory path %q absolute. Error: %q", flags.srcpath, err)
+	if flags.validatePack {
+		validatePack(flags.customizationsPath)
+		return
+	}
+	if len(flags.srcpaths) == 0 {
+		logrus.Fatalf("The --%s flag is required", sourceFlag)
 	}
-	if flags.outpath, err = filepath.Abs(flags.outpath); err != nil {
+	if flags.outpath == "" {
+		logrus.Fatalf("The --%s flag is required", outputFlag)
+	}
+	for i, srcpath := range flags.srcpaths {
+		if flags.srcpaths[i], err = filepath.Abs(srcpath); err != nil {
+			logrus.Fatalf("Failed to make the source directory path %q absolute. Error: %q", srcpath, err)
+		}
+	}
+	toStdout := flags.outpath == stdoutPath
+	if toStdout {
+		if flags.diffPath == stdoutPath {
+			logrus.Fatalf("The diff and the output can't both be written to stdout")
+		}
+		// the logs go to stderr but the info logs are suppressed so that the output can be piped without noise
+		if logrus.GetLevel() == logrus.InfoLevel {
+			logrus.SetLevel(logrus.WarnLevel)
+		}
+		tempOutpath, err := ioutil.TempDir(common.TempPath, "parameterize-output-")
+		if err != nil {
+			logrus.Fatalf("Failed to create a temporary directory for the output. Error: %q", err)
+		}
+		cleanup := func() {
+			if err := os.RemoveAll(tempOutpath); err != nil {
+				logrus.Errorf("Failed to remove the temporary directory %s . Error: %q", tempOutpath, err)
+			}
+		}
+		defer cleanup()
+		logrus.RegisterExitHandler(cleanup)
+		flags.outpath = tempOutpath
+	} else if flags.outpath, err = filepath.Abs(flags.outpath); err != nil {
 		logrus.Fatalf("Failed to make the output directory path %q absolute. Error: %q", flags.outpath, err)
 	}
 	if flags.customizationsPath, err = filepath.Abs(flags.customizationsPath); err != nil {
 		logrus.Fatalf("Failed to make the pack directory path %q absolute. Error: %q", flags.customizationsPath, err)
 	}
 
-	checkSourcePath(flags.srcpath)
-	checkOutputPath(flags.outpath, flags.overwrite)
-	if flags.srcpath == flags.outpath || common.IsParent(flags.outpath, flags.srcpath) || common.IsParent(flags.srcpath, flags.outpath) {
-		logrus.Fatalf("The source path %s and output path %s overlap.", flags.srcpath, flags.outpath)
+	targets := []parameterizertypes.ParamTargetT{}
+	validTargets := []string{string(parameterizertypes.TargetHelm), string(parameterizertypes.TargetKustomize), string(parameterizertypes.TargetOCTemplates), string(parameterizertypes.TargetKubernetes)}
+	for _, outputType := range flags.outputTypes {
+		outputType = strings.ToLower(outputType)
+		if !common.IsStringPresent(validTargets, outputType) {
+			logrus.Fatalf("The output type %s is not supported. Valid output types are: %+v", outputType, validTargets)
+		}
+		targets = append(targets, parameterizertypes.ParamTargetT(outputType))
 	}
+
+	for i, srcpath := range flags.srcpaths {
+		if ext := common.GetArchiveExt(srcpath); ext != "" {
+			if fi, err := os.Stat(srcpath); err == nil && !fi.IsDir() {
+				extractedPath, cleanup := extractSourceArchive(srcpath, ext)
+				defer cleanup()
+				logrus.RegisterExitHandler(cleanup)
+				flags.srcpaths[i] = extractedPath
+				continue
+			}
+		}
+		checkSourcePath(srcpath)
+	}
+	if !toStdout {
+		checkOutputPath(flags.outpath, flags.overwrite)
+	}
+	for _, srcpath := range flags.srcpaths {
+		if common.PathsOverlap(srcpath, flags.outpath) {
+			logrus.Fatalf("The source path %s and output path %s overlap.", srcpath, flags.outpath)
+		}
+	}
+	startQA(flags.qaflags)
+	if err := qaengine.AddEnvEngine(); err != nil {
+		logrus.Fatalf("Failed to add the environment variable answers. Error: %q", err)
+	}
+	if flags.answersPath != "" {
+		if err := qaengine.AddAnswersFile(flags.answersPath); err != nil {
+			logrus.Fatalf("Failed to load the answers. Error: %q", err)
+		}
+	}
+	_, err = os.Stat(flags.outpath)
+	createdOutpath := os.IsNotExist(err)
 	if err := os.MkdirAll(flags.outpath, common.DefaultDirectoryPermission); err != nil {
 		logrus.Fatalf("Failed to create the output directory at path %s Error: %q", flags.outpath, err)
 	}
-	startQA(flags.qaflags)
+
+	var diffOut io.Writer
+	if flags.diffPath == stdoutPath {
+		diffOut = os.Stdout
+	} else if flags.diffPath != "" {
+		diffFile, err := os.Create(flags.diffPath)
+		if err != nil {
+			logrus.Fatalf("Failed to create the diff file at path %s . Error: %q", flags.diffPath, err)
+		}
+		defer diffFile.Close()
+		diffOut = diffFile
+	}
 
 	// Parameterization
-	filesWritten, err := lib.Parameterize(flags.srcpath, flags.customizationsPath, flags.outpath)
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+	go func() {
+		// stop catching the signals after the first one so that a second Ctrl-C exits immediately
+		<-ctx.Done()
+		cancel()
+	}()
+	filesWritten, err := lib.Parameterize(ctx, lib.ParameterizeOptions{
+		SrcDirs:          flags.srcpaths,
+		PackDir:          flags.customizationsPath,
+		OutDir:           flags.outpath,
+		Kinds:            flags.kinds,
+		Targets:          targets,
+		OverwriteValues:  flags.overwriteValues,
+		HelmChartName:    flags.helmChartName,
+		HelmChartVersion: flags.helmChartVersion,
+		DiffOut:          diffOut,
+		Header:           flags.header,
+		Reproducible:     flags.reproducible,
+	})
+	if ctx.Err() != nil {
+		handleParameterizeInterrupt(flags.outpath, createdOutpath, filesWritten)
+	}
+	if err := qaengine.WriteStoresToDisk(); err != nil {
+		logrus.Warnf("Failed to write the stores to disk. Error: %q", err)
+	}
 	if err != nil {
 		logrus.Fatalf("Failed to apply all the parameterizations. Error: %q", err)
 	}
 	logrus.Debugf("filesWritten: %+v", filesWritten)
+	if toStdout {
+		if err := lib.WriteFilesToStream(os.Stdout, flags.outpath, filesWritten); err != nil {
+			logrus.Fatalf("Failed to write the parameterized artifacts to stdout. Error: %q", err)
+		}
+		return
+	}
 	logrus.Infof("Parameterized artifacts can be found at [%s].", flags.outpath)
 }
 
+// handleParameterizeInterrupt saves the answers given so far and exits with a non zero code.
+// If nothing was written, the output directory is removed if it was created for this run.
+func handleParameterizeInterrupt(outpath string, createdOutpath bool, filesWritten []string) {
+	if err := qaengine.WriteStoresToDisk(); err != nil {
+		logrus.Warnf("Failed to write the stores to disk. Error: %q", err)
+	}
+	if len(filesWritten) > 0 {
+		logrus.Fatalf("Parameterization was cancelled. Partially parameterized artifacts can be found at [%s].", outpath)
+	}
+	if createdOutpath {
+		if err := os.RemoveAll(outpath); err != nil {
+			logrus.Errorf("Failed to remove the incomplete output directory at path %s . Error: %q", outpath, err)
+		}
+	}
+	logrus.Fatalf("Parameterization was cancelled before any artifacts were written.")
+}
+
+// validatePack reports the syntax errors in the key expressions of the customizations
+func validatePack(customizationsPath string) {
+	keyErrs, err := lib.ValidatePack(logrus.StandardLogger(), customizationsPath)
+	if err != nil {
+		logrus.Fatalf("Failed to validate the customizations at path %s . Error: %q", customizationsPath, err)
+	}
+	for _, keyErr := range keyErrs {
+		logrus.Error(keyErr.Error())
+	}
+	if len(keyErrs) > 0 {
+		logrus.Fatalf("Found %d invalid keys in the customizations at path %s", len(keyErrs), customizationsPath)
+	}
+	logrus.Infof("All the keys in the customizations at path %s are valid.", customizationsPath)
+}
+
+// extractSourceArchive extracts the source archive into a temporary directory.
+// It returns the path of the extracted source directory and a function to remove it.
+func extractSourceArchive(archivePath, ext string) (string, func()) {
+	tempDir, err := ioutil.TempDir(common.TempPath, "source-archive-")
+	if err != nil {
+		logrus.Fatalf("Failed to create a temporary directory to extract the source archive %s into. Error: %q", archivePath, err)
+	}
+	cleanup := func() {
+		if err := os.RemoveAll(tempDir); err != nil {
+			logrus.Errorf("Failed to remove the temporary directory %s . Error: %q", tempDir, err)
+		}
+	}
+	// use the name of the archive as the directory name so that it can be used to prefix conflicting file names
+	archiveName := filepath.Base(archivePath)
+	srcpath := filepath.Join(tempDir, archiveName[:len(archiveName)-len(ext)])
+	if err := common.ExtractArchive(archivePath, srcpath); err != nil {
+		cleanup()
+		logrus.Fatalf("Failed to extract the source archive %s . Error: %q", archivePath, err)
+	}
+	logrus.Infof("Extracted the source archive %s", archivePath)
+	return srcpath, cleanup
+}
+
 func getParameterizeCommand() *cobra.Command {
 	must := func(err error) {
 		if err != nil {
@@ -83,14 +280,25 @@ func getParameterizeCommand() *cobra.Command {
 		Use:   "parameterize",
 		Short: "Parameterize fields in k8s resources",
 		Long:  "Parameterize fields in k8s resources",
+		Args:  cobra.NoArgs,
 		Run:   func(cmd *cobra.Command, _ []string) { parameterizeHandler(cmd, flags) },
 	}
 
 	// Basic options
-	parameterizeCmd.Flags().StringVarP(&flags.srcpath, sourceFlag, "s", "", "Specify the directory containing the source code to parameterize.")
-	parameterizeCmd.Flags().StringVarP(&flags.outpath, outputFlag, "o", "", "Specify the directory where the output should be written.")
+	parameterizeCmd.Flags().StringArrayVarP(&flags.srcpaths, sourceFlag, "s", []string{}, "Specify the directory containing the source code to parameterize. It can also be a .tar, .tar.gz or .zip archive. Can be specified multiple times to parameterize several directories together.")
+	parameterizeCmd.Flags().StringVarP(&flags.outpath, outputFlag, "o", "", "Specify the directory where the output should be written. Use - to write all the files to stdout as a multi document YAML stream.")
 	parameterizeCmd.Flags().StringVarP(&flags.customizationsPath, customizationsFlag, "c", "", "Specify directory where customizations are stored.")
 	parameterizeCmd.Flags().BoolVar(&flags.overwrite, overwriteFlag, false, "Overwrite the output directory if it exists. By default we don't overwrite.")
+	parameterizeCmd.Flags().StringArrayVar(&flags.kinds, kindFlag, []string{}, "Specify the kinds of k8s resources to parameterize. By default all kinds are parameterized.")
+	parameterizeCmd.Flags().StringArrayVar(&flags.outputTypes, outputTypeFlag, []string{}, "Specify the output types to generate (helm, kustomize, openshifttemplates, kubernetes). The kubernetes output type has the concrete k8s resources and a separate patch file for each env. By default the Helm chart, the Kustomize overlays and the Openshift templates are generated.")
+	parameterizeCmd.Flags().BoolVar(&flags.overwriteValues, overwriteValuesFlag, false, "Overwrite the existing Helm values files in the output directory. By default the new values are merged into them.")
+	parameterizeCmd.Flags().BoolVar(&flags.validatePack, validatePackFlag, false, "Only check the syntax of the keys in the customizations and report the errors. The source is not read.")
+	parameterizeCmd.Flags().StringVar(&flags.diffPath, diffFlag, "", "Write a unified diff between each source file and its parameterized Helm template to this file. The path must be given as --diff=path since --diff without a value prints the diff to stdout.")
+	parameterizeCmd.Flags().StringVar(&flags.helmChartName, helmChartNameFlag, "", "Specify the name of the Helm chart. By default the name in the customizations is used, or "+common.DefaultProjectName+" if there is none.")
+	parameterizeCmd.Flags().StringVar(&flags.helmChartVersion, helmChartVersionFlag, "", "Specify the version of the Helm chart. It must be a semantic version. By default the version in the customizations is used, or 0.1.0 if there is none.")
+	parameterizeCmd.Flags().StringVar(&flags.answersPath, answersFlag, "", "Specify a JSON file that maps the question ids to their answers. The answers must be strings, bools or arrays of strings.")
+	parameterizeCmd.Flags().BoolVar(&flags.header, headerFlag, false, "Start each parameterized file with a header noting the source file and the time of generation.")
+	parameterizeCmd.Flags().BoolVar(&flags.reproducible, reproducibleFlag, false, "Omit the timestamps so that the output is the same on every run. Set SOURCE_DATE_EPOCH to pin the timestamps instead.")
 	parameterizeCmd.Flags().StringVar(&flags.configOut, configOutFlag, ".", "Specify config file output location")
 	parameterizeCmd.Flags().StringVar(&flags.qaCacheOut, qaCacheOutFlag, ".", "Specify cache file output location")
 
@@ -99,8 +307,8 @@ func getParameterizeCommand() *cobra.Command {
 	parameterizeCmd.Flags().BoolVar(&flags.qaskip, qaSkipFlag, false, "Enable/disable the default answers to questions posed in QA Cli sub-system. If disabled, you will have to answer the questions posed by QA during interaction.")
 	parameterizeCmd.Flags().IntVar(&flags.qaport, qaportFlag, 0, "Port for the QA service. By default it chooses a random free port.")
 
-	must(parameterizeCmd.MarkFlagRequired(sourceFlag))
-	must(parameterizeCmd.MarkFlagRequired(outputFlag))
+	parameterizeCmd.Flags().Lookup(diffFlag).NoOptDefVal = stdoutPath
+
 	must(parameterizeCmd.MarkFlagRequired(customizationsFlag))
 
 	must(parameterizeCmd.Flags().MarkHidden(qadisablecliFlag))
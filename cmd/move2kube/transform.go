@@ -32,6 +32,8 @@ type transformFlags struct {
 	qaflags
 	// ignoreEnv tells us whether to use data collected from the local machine
 	ignoreEnv bool
+	// reproducible omits the timestamps so that the output is the same on every run
+	reproducible bool
 	// planfile is contains the path to the plan file
 	planfile string
 	// outpath contains the path to the output folder
@@ -64,6 +66,7 @@ func transformHandler(cmd *cobra.Command, flags transformFlags) {
 
 	// Global settings
 	common.IgnoreEnvironment = flags.ignoreEnv
+	common.Reproducible = flags.reproducible
 	// Global settings
 
 	// Parameter cleaning and curate plan
@@ -168,6 +171,7 @@ func getTransformCommand() *cobra.Command {
 
 	// Advanced options
 	transformCmd.Flags().BoolVar(&flags.ignoreEnv, ignoreEnvFlag, false, "Ignore data from local machine.")
+	transformCmd.Flags().BoolVar(&flags.reproducible, reproducibleFlag, false, "Omit the timestamps so that the output is the same on every run. Set SOURCE_DATE_EPOCH to pin the timestamps instead.")
 
 	// Hidden options
 	transformCmd.Flags().BoolVar(&flags.qadisablecli, qadisablecliFlag, false, "Enable/disable the QA Cli sub-system. Without this system, you will have to use the REST API to interact.")
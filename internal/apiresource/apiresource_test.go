@@ -20,9 +20,14 @@ import (
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
+	"github.com/konveyor/move2kube/types/collection"
+	irtypes "github.com/konveyor/move2kube/types/ir"
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	core "k8s.io/kubernetes/pkg/apis/core"
+	policy "k8s.io/kubernetes/pkg/apis/policy"
 )
 
 func createService(name string, ports []v1.ServicePort) runtime.Object {
@@ -135,3 +140,57 @@ func TestDeepMerge(t *testing.T) {
 		})
 	}
 }
+
+func TestGetServicePortsProtocol(t *testing.T) {
+	irService := irtypes.NewServiceWithName("mysvc")
+	irService.AddPortForwarding(irtypes.Port{Number: 8080}, irtypes.Port{Number: 8080}, "")
+	irService.AddPortForwarding(irtypes.Port{Number: 53}, irtypes.Port{Number: 53}, core.ProtocolUDP)
+	servicePorts := (&Service{}).getServicePorts(irService)
+	want := []core.ServicePort{
+		{Name: "port-8080", Port: 8080, TargetPort: intstr.FromInt(8080)},
+		{Name: "port-53", Port: 53, TargetPort: intstr.FromInt(53), Protocol: core.ProtocolUDP},
+	}
+	if !cmp.Equal(servicePorts, want) {
+		t.Fatalf("failed to get the service ports. Differences:\n%s", cmp.Diff(want, servicePorts))
+	}
+}
+
+func TestGetServicePortsMixedProtocols(t *testing.T) {
+	irService := irtypes.NewServiceWithName("mysvc")
+	irService.AddPortForwarding(irtypes.Port{Number: 53}, irtypes.Port{Number: 53}, "")
+	irService.AddPortForwarding(irtypes.Port{Number: 53}, irtypes.Port{Number: 53}, core.ProtocolUDP)
+	if err := irService.AddPortForwarding(irtypes.Port{Number: 53}, irtypes.Port{Number: 53}, core.ProtocolTCP); err == nil {
+		t.Fatalf("expected an error when forwarding the same port with the same protocol twice")
+	}
+	servicePorts := (&Service{}).getServicePorts(irService)
+	want := []core.ServicePort{
+		{Name: "port-53", Port: 53, TargetPort: intstr.FromInt(53)},
+		{Name: "port-53-udp", Port: 53, TargetPort: intstr.FromInt(53), Protocol: core.ProtocolUDP},
+	}
+	if !cmp.Equal(servicePorts, want) {
+		t.Fatalf("failed to get the service ports. Differences:\n%s", cmp.Diff(want, servicePorts))
+	}
+}
+
+func TestCreatePodDisruptionBudgets(t *testing.T) {
+	ir := irtypes.NewEnhancedIRFromIR(irtypes.NewIR())
+	webService := irtypes.NewServiceWithName("web")
+	minAvailable := intstr.FromInt(1)
+	webService.MinAvailable = &minAvailable
+	ir.Services = map[string]irtypes.Service{"web": webService, "worker": irtypes.NewServiceWithName("worker")}
+	objs := (&PodDisruptionBudget{}).createNewResources(ir, []string{podDisruptionBudgetKind}, collection.ClusterMetadata{})
+	want := []runtime.Object{&policy.PodDisruptionBudget{
+		TypeMeta:   metav1.TypeMeta{Kind: podDisruptionBudgetKind, APIVersion: policy.SchemeGroupVersion.String()},
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Labels: getServiceLabels("web")},
+		Spec: policy.PodDisruptionBudgetSpec{
+			MinAvailable: &minAvailable,
+			Selector:     &metav1.LabelSelector{MatchLabels: getServiceLabels("web")},
+		},
+	}}
+	if !cmp.Equal(objs, want) {
+		t.Fatalf("failed to create the pod disruption budgets. Differences:\n%s", cmp.Diff(want, objs))
+	}
+	if objs := (&PodDisruptionBudget{}).createNewResources(ir, []string{}, collection.ClusterMetadata{}); len(objs) != 0 {
+		t.Fatalf("expected no pod disruption budgets when the kind is not supported. Actual: %+v", objs)
+	}
+}
@@ -104,11 +104,11 @@ func (*BuildConfig) getBuildSource(irBuildConfig irtypes.BuildConfig, ir irtypes
 		logrus.Debugf("Unable to identify git repo for %s : %s", irBuildConfig.ContainerBuild.ContextPath, err)
 	}
 	if repoDir != "" {
-		relDockerfilePath, err := filepath.Rel(repoDir, contextPath)
+		relContextPath, err := filepath.Rel(repoDir, contextPath)
 		if err != nil {
 			logrus.Debugf("Failed to make the path %s relative to the path %s Error %q", contextPath, repoDir, err)
 		} else {
-			contextPath = filepath.Dir(relDockerfilePath)
+			contextPath = relContextPath
 		}
 	}
 	gitRepoURL := gitRepoURLPlaceholder
@@ -134,9 +134,9 @@ func (*BuildConfig) getBuildStrategy(irBuildConfig irtypes.BuildConfig, ir irtyp
 	}
 	dockerfilePath := dockerfilePathPlaceholder
 	if repoDir != "" {
-		relDockerfilePath, err := filepath.Rel(repoDir, irBuildConfig.ContainerBuild.ContextPath)
+		relDockerfilePath, err := filepath.Rel(repoDir, irBuildConfig.ContainerBuild.GetDockerfilePath())
 		if err != nil {
-			logrus.Debugf("Failed to make the path %s relative to the path %s Error %q", irBuildConfig.ContainerBuild.ContextPath, repoDir, err)
+			logrus.Debugf("Failed to make the path %s relative to the path %s Error %q", irBuildConfig.ContainerBuild.GetDockerfilePath(), repoDir, err)
 		} else {
 			dockerfilePath = relDockerfilePath
 		}
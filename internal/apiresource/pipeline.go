@@ -114,14 +114,18 @@ func (*Pipeline) createNewResource(irpipeline irtypes.Pipeline, ir irtypes.Enhan
 			contextPath := contextPathPlaceholder
 			// If there is a git repo, set the correct context and dockerfile paths.
 			if repoDir != "" {
-				relDockerfilePath, err := filepath.Rel(repoDir, container.Build.ContextPath)
+				relDockerfilePath, err := filepath.Rel(repoDir, container.Build.GetDockerfilePath())
 				if err != nil {
 					// TODO: Bump up the error after fixing abs path, rel path issues
-					logrus.Debugf("ERROR: Failed to make the path %q relative to the path %q Error %q", repoDir, container.Build.ContextPath, err)
+					logrus.Debugf("ERROR: Failed to make the path %q relative to the path %q Error %q", container.Build.GetDockerfilePath(), repoDir, err)
 				} else {
 					dockerfilePath = relDockerfilePath
-					// We can't figure out the context from the source. So assume the context is the directory containing the dockerfile.
-					contextPath = filepath.Dir(relDockerfilePath)
+				}
+				relContextPath, err := filepath.Rel(repoDir, container.Build.ContextPath)
+				if err != nil {
+					logrus.Debugf("ERROR: Failed to make the path %q relative to the path %q Error %q", container.Build.ContextPath, repoDir, err)
+				} else {
+					contextPath = relContextPath
 				}
 			}
 
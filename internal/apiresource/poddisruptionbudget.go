@@ -0,0 +1,83 @@
+/*
+ *  Copyright IBM Corporation 2021
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package apiresource
+
+import (
+	"github.com/konveyor/move2kube/internal/common"
+	collecttypes "github.com/konveyor/move2kube/types/collection"
+	irtypes "github.com/konveyor/move2kube/types/ir"
+	"github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	policy "k8s.io/kubernetes/pkg/apis/policy"
+)
+
+const podDisruptionBudgetKind = "PodDisruptionBudget"
+
+// PodDisruptionBudget handles PodDisruptionBudget objects
+type PodDisruptionBudget struct {
+}
+
+// getSupportedKinds returns all kinds supported by the class
+func (d *PodDisruptionBudget) getSupportedKinds() []string {
+	return []string{podDisruptionBudgetKind}
+}
+
+// createNewResources converts ir to runtime objects
+func (d *PodDisruptionBudget) createNewResources(ir irtypes.EnhancedIR, supportedKinds []string, targetCluster collecttypes.ClusterMetadata) []runtime.Object {
+	objs := []runtime.Object{}
+	for _, service := range ir.Services {
+		if service.MinAvailable == nil {
+			continue
+		}
+		if !common.IsStringPresent(supportedKinds, podDisruptionBudgetKind) {
+			logrus.Errorf("Could not find a valid resource type in cluster to create a PodDisruptionBudget for the service %s", service.Name)
+			continue
+		}
+		objs = append(objs, d.createPodDisruptionBudget(service))
+	}
+	return objs
+}
+
+// convertToClusterSupportedKinds converts kinds to cluster supported kinds
+func (d *PodDisruptionBudget) convertToClusterSupportedKinds(obj runtime.Object, supportedKinds []string, otherobjs []runtime.Object, _ irtypes.EnhancedIR, targetCluster collecttypes.ClusterMetadata) ([]runtime.Object, bool) {
+	if common.IsStringPresent(d.getSupportedKinds(), obj.GetObjectKind().GroupVersionKind().Kind) {
+		return []runtime.Object{obj}, true
+	}
+	return nil, false
+}
+
+// createPodDisruptionBudget creates a PodDisruptionBudget that selects the pods of the service
+func (d *PodDisruptionBudget) createPodDisruptionBudget(service irtypes.Service) *policy.PodDisruptionBudget {
+	minAvailable := *service.MinAvailable
+	return &policy.PodDisruptionBudget{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       podDisruptionBudgetKind,
+			APIVersion: policy.SchemeGroupVersion.String(),
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   service.Name,
+			Labels: getServiceLabels(service.Name),
+		},
+		Spec: policy.PodDisruptionBudgetSpec{
+			MinAvailable: &minAvailable,
+			Selector: &metav1.LabelSelector{
+				MatchLabels: getServiceLabels(service.Name),
+			},
+		},
+	}
+}
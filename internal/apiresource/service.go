@@ -18,6 +18,7 @@ package apiresource
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/konveyor/move2kube/internal/common"
 	"github.com/konveyor/move2kube/internal/k8sschema"
@@ -79,7 +80,10 @@ func (d *Service) createNewResources(ir irtypes.EnhancedIR, supportedKinds []str
 			logrus.Errorf("Could not find a valid resource type in cluster to create a Service")
 			continue
 		}
-		if exposeobjectcreated || !service.HasValidAnnotation(common.ExposeSelector) {
+		if service.ServiceType != "" {
+			obj := d.createService(service, service.ServiceType)
+			objs = append(objs, obj)
+		} else if exposeobjectcreated || !service.HasValidAnnotation(common.ExposeSelector) {
 			//Create clusterip service
 			obj := d.createService(service, core.ServiceTypeClusterIP)
 			objs = append(objs, obj)
@@ -110,7 +114,7 @@ func (d *Service) convertToClusterSupportedKinds(obj runtime.Object, supportedKi
 			return d.ingressToRoute(*ingress), true
 		}
 		if service, ok := lobj.(*core.Service); ok {
-			if service.Spec.Type == core.ServiceTypeLoadBalancer || service.Spec.Type == core.ServiceTypeNodePort {
+			if (service.Spec.Type == core.ServiceTypeLoadBalancer || service.Spec.Type == core.ServiceTypeNodePort) && !hasServiceTypeFromIR(*service, ir) {
 				return d.serviceToRoutes(*service, ir, targetCluster.Spec), true
 			}
 			return []runtime.Object{obj}, true
@@ -123,7 +127,7 @@ func (d *Service) convertToClusterSupportedKinds(obj runtime.Object, supportedKi
 			return []runtime.Object{obj}, true
 		}
 		if service, ok := lobj.(*core.Service); ok {
-			if service.Spec.Type == core.ServiceTypeLoadBalancer || service.Spec.Type == core.ServiceTypeNodePort {
+			if (service.Spec.Type == core.ServiceTypeLoadBalancer || service.Spec.Type == core.ServiceTypeNodePort) && !hasServiceTypeFromIR(*service, ir) {
 				return d.serviceToIngress(*service, ir, targetCluster.Spec), true
 			}
 			return []runtime.Object{obj}, true
@@ -498,6 +502,13 @@ func (d *Service) createIngress(ir irtypes.EnhancedIR, targetClusterSpec collect
 	return &ingress
 }
 
+// hasServiceTypeFromIR returns true if the service type was explicitly set in the IR.
+// Such services are not converted to routes or ingresses.
+func hasServiceTypeFromIR(service core.Service, ir irtypes.EnhancedIR) bool {
+	irService, ok := ir.Services[service.Name]
+	return ok && irService.ServiceType != "" && irService.ServiceType == service.Spec.Type
+}
+
 // createService creates a service
 func (d *Service) createService(service irtypes.Service, serviceType core.ServiceType) *core.Service {
 	ports := d.getServicePorts(service)
@@ -526,10 +537,18 @@ func (d *Service) createService(service irtypes.Service, serviceType core.Servic
 // GetServicePorts configure the container service ports.
 func (d *Service) getServicePorts(service irtypes.Service) []core.ServicePort {
 	servicePorts := []core.ServicePort{}
+	portCounts := map[int32]int{}
+	for _, forwarding := range service.ServiceToPodPortForwardings {
+		portCounts[forwarding.ServicePort.Number]++
+	}
 	for _, forwarding := range service.ServiceToPodPortForwardings {
 		servicePortName := forwarding.ServicePort.Name
 		if servicePortName == "" {
 			servicePortName = fmt.Sprintf("port-%d", forwarding.ServicePort.Number)
+			// the port names must be unique when the same port is used with different protocols
+			if portCounts[forwarding.ServicePort.Number] > 1 && forwarding.Protocol != "" && forwarding.Protocol != core.ProtocolTCP {
+				servicePortName += "-" + strings.ToLower(string(forwarding.Protocol))
+			}
 		}
 		targetPort := intstr.IntOrString{Type: intstr.String, StrVal: forwarding.PodPort.Name}
 		if forwarding.PodPort.Name == "" {
@@ -540,6 +559,10 @@ func (d *Service) getServicePorts(service irtypes.Service) []core.ServicePort {
 			Name:       servicePortName,
 			Port:       forwarding.ServicePort.Number,
 			TargetPort: targetPort,
+			Protocol:   forwarding.Protocol,
+		}
+		if service.ServiceType == core.ServiceTypeNodePort {
+			servicePort.NodePort = forwarding.NodePort
 		}
 		servicePorts = append(servicePorts, servicePort)
 	}
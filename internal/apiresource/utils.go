@@ -94,9 +94,10 @@ func convertVersion(objs []runtime.Object, clusterSpec collecttypes.ClusterMetad
 	return newobjs, nil
 }
 
+// getFilename returns the name of the file that the object is written to
 func getFilename(obj runtime.Object) string {
 	val := reflect.ValueOf(obj).Elem()
 	typeMeta := val.FieldByName("TypeMeta").Interface().(metav1.TypeMeta)
 	objectMeta := val.FieldByName("ObjectMeta").Interface().(metav1.ObjectMeta)
-	return fmt.Sprintf("%s-%s.yaml", objectMeta.Name, strings.ToLower(typeMeta.Kind))
+	return fmt.Sprintf("%s-%s.yaml", common.SanitizeFilenamePart(objectMeta.Name), common.SanitizeFilenamePart(strings.ToLower(typeMeta.Kind)))
 }
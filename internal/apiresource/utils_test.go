@@ -0,0 +1,60 @@
+/*
+ *  Copyright IBM Corporation 2021
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package apiresource
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/konveyor/move2kube/internal/common"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestGetFilename(t *testing.T) {
+	testcases := []struct {
+		name     string
+		objName  string
+		kind     string
+		filename string
+	}{
+		{name: "normal name", objName: "myapp", kind: "Service", filename: "myapp-service.yaml"},
+		{name: "name with slashes", objName: "foo/bar", kind: "Service", filename: "foo-bar-service.yaml"},
+		{name: "name with colons", objName: "system:controller:foo", kind: "ClusterRole", filename: "system-controller-foo-clusterrole.yaml"},
+		{name: "name with unicode", objName: "café-ünïcode", kind: "Service", filename: "caf---n-code-service.yaml"},
+		{name: "name with only unsafe characters", objName: "../", kind: "Service", filename: "unnamed-service.yaml"},
+	}
+	for _, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			obj := &v1.Service{TypeMeta: metav1.TypeMeta{Kind: testcase.kind}, ObjectMeta: metav1.ObjectMeta{Name: testcase.objName}}
+			if filename := getFilename(obj); filename != testcase.filename {
+				t.Fatalf("failed to get the filename. Expected: %s Actual: %s", testcase.filename, filename)
+			}
+		})
+	}
+	t.Run("long names are truncated and stay unique", func(t *testing.T) {
+		obj1 := &v1.Service{TypeMeta: metav1.TypeMeta{Kind: "Service"}, ObjectMeta: metav1.ObjectMeta{Name: strings.Repeat("a", 200) + "1"}}
+		obj2 := &v1.Service{TypeMeta: metav1.TypeMeta{Kind: "Service"}, ObjectMeta: metav1.ObjectMeta{Name: strings.Repeat("a", 200) + "2"}}
+		filename1, filename2 := getFilename(obj1), getFilename(obj2)
+		if len(filename1) > common.MaxFilenamePartLength+len("-service.yaml") {
+			t.Fatalf("expected the filename to be truncated. Actual: %s", filename1)
+		}
+		if filename1 == filename2 {
+			t.Fatalf("expected the filenames of different objects to be different. Actual: %s", filename1)
+		}
+	})
+}
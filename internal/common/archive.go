@@ -0,0 +1,163 @@
+/*
+ *  Copyright IBM Corporation 2021
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package common
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+var archiveExts = []string{".tar.gz", ".tgz", ".tar", ".zip"}
+
+// GetArchiveExt returns the extension of the archive (.tar, .tar.gz, .tgz or .zip) or an empty string if the path is not an archive
+func GetArchiveExt(path string) string {
+	lowerPath := strings.ToLower(path)
+	for _, ext := range archiveExts {
+		if strings.HasSuffix(lowerPath, ext) {
+			return ext
+		}
+	}
+	return ""
+}
+
+// ExtractArchive extracts the .tar, .tar.gz, .tgz or .zip archive into the destination directory.
+// Archives with entries that would be written outside the destination directory are rejected.
+func ExtractArchive(archivePath, destDir string) error {
+	switch GetArchiveExt(archivePath) {
+	case ".tar":
+		f, err := os.Open(archivePath)
+		if err != nil {
+			return fmt.Errorf("failed to open the archive at path %s . Error: %q", archivePath, err)
+		}
+		defer f.Close()
+		return extractTar(f, destDir)
+	case ".tar.gz", ".tgz":
+		f, err := os.Open(archivePath)
+		if err != nil {
+			return fmt.Errorf("failed to open the archive at path %s . Error: %q", archivePath, err)
+		}
+		defer f.Close()
+		gzr, err := gzip.NewReader(f)
+		if err != nil {
+			return fmt.Errorf("failed to read the gzipped archive at path %s . Error: %q", archivePath, err)
+		}
+		defer gzr.Close()
+		return extractTar(gzr, destDir)
+	case ".zip":
+		return extractZip(archivePath, destDir)
+	default:
+		return fmt.Errorf("the file at path %s is not a supported archive. Supported extensions are: %+v", archivePath, archiveExts)
+	}
+}
+
+// getArchiveEntryPath returns the path where the archive entry should be extracted
+func getArchiveEntryPath(destDir, name string) (string, error) {
+	if filepath.IsAbs(name) || strings.HasPrefix(name, "/") {
+		return "", fmt.Errorf("the archive entry %s has an absolute path", name)
+	}
+	target := filepath.Join(destDir, filepath.FromSlash(name))
+	if !IsParent(target, destDir) {
+		return "", fmt.Errorf("the archive entry %s would be extracted outside the directory %s", name, destDir)
+	}
+	return target, nil
+}
+
+func extractTar(r io.Reader, destDir string) error {
+	tr := tar.NewReader(r)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read the tar archive. Error: %q", err)
+		}
+		target, err := getArchiveEntryPath(destDir, header.Name)
+		if err != nil {
+			return err
+		}
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, DefaultDirectoryPermission); err != nil {
+				return fmt.Errorf("failed to create the directory at path %s . Error: %q", target, err)
+			}
+		case tar.TypeReg, tar.TypeRegA:
+			if err := writeArchiveFile(target, tr, header.FileInfo().Mode()); err != nil {
+				return err
+			}
+		default:
+			logrus.Warnf("Skipping the tar archive entry %s since it is not a regular file or directory", header.Name)
+		}
+	}
+}
+
+func extractZip(archivePath, destDir string) error {
+	zr, err := zip.OpenReader(archivePath)
+	if err != nil {
+		return fmt.Errorf("failed to open the zip archive at path %s . Error: %q", archivePath, err)
+	}
+	defer zr.Close()
+	for _, f := range zr.File {
+		target, err := getArchiveEntryPath(destDir, f.Name)
+		if err != nil {
+			return err
+		}
+		if f.FileInfo().IsDir() {
+			if err := os.MkdirAll(target, DefaultDirectoryPermission); err != nil {
+				return fmt.Errorf("failed to create the directory at path %s . Error: %q", target, err)
+			}
+			continue
+		}
+		if !f.Mode().IsRegular() {
+			logrus.Warnf("Skipping the zip archive entry %s since it is not a regular file or directory", f.Name)
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return fmt.Errorf("failed to open the zip archive entry %s . Error: %q", f.Name, err)
+		}
+		err = writeArchiveFile(target, rc, f.Mode())
+		rc.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeArchiveFile(target string, r io.Reader, mode os.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(target), DefaultDirectoryPermission); err != nil {
+		return fmt.Errorf("failed to create the directory at path %s . Error: %q", filepath.Dir(target), err)
+	}
+	f, err := os.OpenFile(target, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, mode.Perm()|0600)
+	if err != nil {
+		return fmt.Errorf("failed to create the file at path %s . Error: %q", target, err)
+	}
+	defer f.Close()
+	if _, err := io.Copy(f, r); err != nil {
+		return fmt.Errorf("failed to write the file at path %s . Error: %q", target, err)
+	}
+	return f.Close()
+}
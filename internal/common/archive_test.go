@@ -0,0 +1,129 @@
+/*
+ *  Copyright IBM Corporation 2021
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package common_test
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/konveyor/move2kube/internal/common"
+)
+
+func writeTestTar(t *testing.T, w io.Writer, files map[string]string) {
+	tw := tar.NewWriter(w)
+	for name, contents := range files {
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(contents)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatalf("failed to write the tar header. Error: %q", err)
+		}
+		if _, err := tw.Write([]byte(contents)); err != nil {
+			t.Fatalf("failed to write the tar entry. Error: %q", err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatalf("failed to close the tar writer. Error: %q", err)
+	}
+}
+
+func createTestArchive(t *testing.T, name string, files map[string]string) string {
+	archivePath := filepath.Join(t.TempDir(), name)
+	f, err := os.Create(archivePath)
+	if err != nil {
+		t.Fatalf("failed to create the archive at path %s . Error: %q", archivePath, err)
+	}
+	defer f.Close()
+	switch common.GetArchiveExt(name) {
+	case ".tar":
+		writeTestTar(t, f, files)
+	case ".tar.gz", ".tgz":
+		gzw := gzip.NewWriter(f)
+		writeTestTar(t, gzw, files)
+		if err := gzw.Close(); err != nil {
+			t.Fatalf("failed to close the gzip writer. Error: %q", err)
+		}
+	case ".zip":
+		zw := zip.NewWriter(f)
+		for name, contents := range files {
+			w, err := zw.Create(name)
+			if err != nil {
+				t.Fatalf("failed to create the zip entry. Error: %q", err)
+			}
+			if _, err := w.Write([]byte(contents)); err != nil {
+				t.Fatalf("failed to write the zip entry. Error: %q", err)
+			}
+		}
+		if err := zw.Close(); err != nil {
+			t.Fatalf("failed to close the zip writer. Error: %q", err)
+		}
+	default:
+		t.Fatalf("unsupported archive %s", name)
+	}
+	return archivePath
+}
+
+func TestGetArchiveExt(t *testing.T) {
+	testcases := map[string]string{
+		"app.tar":      ".tar",
+		"app.tar.gz":   ".tar.gz",
+		"APP.TGZ":      ".tgz",
+		"/a/b/app.zip": ".zip",
+		"app.gz":       "",
+		"app":          "",
+	}
+	for path, want := range testcases {
+		if actual := common.GetArchiveExt(path); actual != want {
+			t.Fatalf("expected the archive extension of %s to be %q . Actual: %q", path, want, actual)
+		}
+	}
+}
+
+func TestExtractArchive(t *testing.T) {
+	for _, name := range []string{"app.tar", "app.tar.gz", "app.zip"} {
+		t.Run(name, func(t *testing.T) {
+			archivePath := createTestArchive(t, name, map[string]string{"k8s/dep.yaml": "kind: Deployment\n"})
+			destDir := t.TempDir()
+			if err := common.ExtractArchive(archivePath, destDir); err != nil {
+				t.Fatalf("failed to extract the archive. Error: %q", err)
+			}
+			contents, err := ioutil.ReadFile(filepath.Join(destDir, "k8s", "dep.yaml"))
+			if err != nil {
+				t.Fatalf("failed to read the extracted file. Error: %q", err)
+			}
+			if string(contents) != "kind: Deployment\n" {
+				t.Fatalf("the extracted file has the wrong contents: %s", string(contents))
+			}
+		})
+		t.Run(name+" with path traversal", func(t *testing.T) {
+			archivePath := createTestArchive(t, name, map[string]string{"../../evil.yaml": "kind: Evil\n"})
+			destDir := filepath.Join(t.TempDir(), "a", "b")
+			if err := common.ExtractArchive(archivePath, destDir); err == nil {
+				t.Fatalf("expected an error for an archive with path traversal entries")
+			}
+			if _, err := os.Stat(filepath.Join(destDir, "..", "..", "evil.yaml")); !os.IsNotExist(err) {
+				t.Fatalf("expected the file outside the destination directory to not be written. Error: %q", err)
+			}
+		})
+	}
+	if err := common.ExtractArchive(filepath.Join(t.TempDir(), "app.rar"), t.TempDir()); err == nil {
+		t.Fatalf("expected an error for an unsupported archive")
+	}
+}
@@ -54,8 +54,25 @@ const (
 	AnnotationLabelValue = "true"
 	// DefaultServicePort is the default port that will be added to a service.
 	DefaultServicePort = 8080
+	// DefaultDockerfileName is the default name of a Dockerfile
+	DefaultDockerfileName = "Dockerfile"
+	// DefaultContainerfileName is the default name of a Containerfile used by Podman and Buildah
+	DefaultContainerfileName = "Containerfile"
+	// DockerignoreFilename is the name of the file containing the patterns for the files to exclude from the build context
+	DockerignoreFilename = ".dockerignore"
 	// TODOAnnotation is used to annotate with TODO tasks
 	TODOAnnotation = types.GroupName + "/todo."
+	// SourceDockerfileAnnotation is used to annotate resources with the path of the dockerfile they were created from.
+	// The path is relative to the source directory when possible.
+	SourceDockerfileAnnotation = types.GroupName + "/source-dockerfile"
+	// TransformerAnnotation is used to annotate resources with the transformer that created them
+	TransformerAnnotation = types.GroupName + "/transformer"
+	// GeneratedAtAnnotation is used to annotate resources with the time they were generated at
+	GeneratedAtAnnotation = types.GroupName + "/generated-at"
+	// ServiceNameLabel is the dockerfile label that overrides the name of the service created from the dockerfile
+	ServiceNameLabel = types.GroupName + "/service-name"
+	// PortNamesLabel is the dockerfile label that names the exposed ports. Example: 8080=http,9090=metrics
+	PortNamesLabel = types.GroupName + "/port-names"
 )
 
 const (
@@ -144,4 +161,6 @@ var (
 	DefaultPVCSize, _ = resource.ParseQuantity("100Mi")
 	// IgnoreEnvironment indicates whether to ignore the current environment or not
 	IgnoreEnvironment = false
+	// Reproducible indicates whether to omit the timestamps from the generated output so that it is the same on every run
+	Reproducible = false
 )
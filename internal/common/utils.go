@@ -511,6 +511,26 @@ func MakeFileNameCompliant(name string) string {
 	return processedName
 }
 
+// MaxFilenamePartLength is the maximum length of the names returned by SanitizeFilenamePart
+const MaxFilenamePartLength = 100
+
+var unsafeFilenameCharsRegex = regexp.MustCompile(`[^a-zA-Z0-9_.-]+`)
+
+// SanitizeFilenamePart replaces the characters that are not safe to use in a filename.
+// The result has no path separators and is never . or .. so it stays inside the directory it is joined to.
+// Long names are truncated and suffixed with a hash of the original name to keep them unique.
+func SanitizeFilenamePart(name string) string {
+	sanitized := strings.Trim(unsafeFilenameCharsRegex.ReplaceAllLiteralString(name, "-"), "-.")
+	if sanitized == "" {
+		sanitized = "unnamed"
+	}
+	if len(sanitized) > MaxFilenamePartLength {
+		hash := GetSHA256Hash(name)[:8]
+		sanitized = strings.TrimRight(sanitized[:MaxFilenamePartLength-len(hash)-1], "-.") + "-" + hash
+	}
+	return sanitized
+}
+
 // GetSHA256Hash returns the SHA256 hash of the string.
 // The hash is 256 bits/32 bytes and encoded as a 64 char hexadecimal string.
 func GetSHA256Hash(s string) string {
@@ -874,8 +894,15 @@ func ReverseInPlace(xs []string) {
 	}
 }
 
+// PathsOverlap returns true if the paths are the same or if one of them is inside the other.
+// Sibling directories like /a/src and /a/out do not overlap.
+func PathsOverlap(path1, path2 string) bool {
+	return IsParent(path1, path2) || IsParent(path2, path1)
+}
+
 // IsParent can be used to check if a path is one of the parent directories of another path.
-// Also returns true if the paths are the same.
+// Also returns true if the paths are the same. Relative paths are resolved against the current working directory.
+// It is not symmetric, use PathsOverlap to check both directions.
 func IsParent(child, parent string) bool {
 	var err error
 	child, err = filepath.Abs(child)
@@ -903,9 +930,33 @@ func IsParent(child, parent string) bool {
 }
 
 // SplitOnDotExpectInsideQuotes splits a string on dot.
-// Stuff inside double or single quotes will not be split.
+// Stuff inside double or single quotes will not be split, even in the middle of a part like [name='a.b'].
+// Empty parts are ignored.
 func SplitOnDotExpectInsideQuotes(s string) []string {
-	return regexp.MustCompile(`[^."']+|"[^"]*"|'[^']*'`).FindAllString(s, -1)
+	parts := []string{}
+	part := ""
+	var quote rune
+	for _, c := range s {
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '.':
+			if part != "" {
+				parts = append(parts, part)
+			}
+			part = ""
+			continue
+		}
+		part += string(c)
+	}
+	if part != "" {
+		parts = append(parts, part)
+	}
+	return parts
 }
 
 // StripQuotes strips a single layer of double or single quotes from the left and right ends
@@ -922,6 +973,30 @@ func StripQuotes(s string) string {
 	return s
 }
 
+// IsDockerfileName returns true if the file name is Dockerfile or Containerfile, optionally followed by an extension
+func IsDockerfileName(path string) bool {
+	name := filepath.Base(path)
+	for _, dfName := range []string{DefaultDockerfileName, DefaultContainerfileName} {
+		if name == dfName || strings.HasPrefix(name, dfName+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// GetDockerfilePathInDir returns the path to the Dockerfile in the directory.
+// If there is no Dockerfile but there is a Containerfile, the path to the Containerfile is returned.
+func GetDockerfilePathInDir(dir string) string {
+	dockerfilePath := filepath.Join(dir, DefaultDockerfileName)
+	if _, err := os.Stat(dockerfilePath); os.IsNotExist(err) {
+		containerfilePath := filepath.Join(dir, DefaultContainerfileName)
+		if _, err := os.Stat(containerfilePath); err == nil {
+			return containerfilePath
+		}
+	}
+	return dockerfilePath
+}
+
 // GetRuntimeObjectMetadata returns the metadata field from a k8s object.
 func GetRuntimeObjectMetadata(obj runtime.Object) metav1.ObjectMeta {
 	k8sObjValue := reflect.ValueOf(obj).Elem()
@@ -799,6 +799,12 @@ func TestIsParent(t *testing.T) {
 		{"not parent 2", "/c", "/a/b/c", false},
 		{"reverse child and parent 1", "/a", "/a/b/c", false},
 		{"reverse child and parent 2", "/a/b", "/a/b/c", false},
+		{"sibling dirs", "/a/out", "/a/src", false},
+		{"sibling dirs with a common prefix", "/a/src2", "/a/src", false},
+		{"trailing slashes", "/a/src/", "/a/src", true},
+		{"relative paths", "./src/out", ".", true},
+		{"relative sibling paths", "./out", "./src", false},
+		{"relative paths with dot dot", "src/../out", "src", false},
 	}
 	for _, tt := range tts {
 		t.Run(tt.desc, func(t *testing.T) {
@@ -809,6 +815,32 @@ func TestIsParent(t *testing.T) {
 	}
 }
 
+func TestPathsOverlap(t *testing.T) {
+	tts := []struct {
+		desc   string
+		path1  string
+		path2  string
+		answer bool
+	}{
+		{"sibling dirs", "/a/src", "/a/out", false},
+		{"sibling dirs with trailing slashes", "/a/src/", "/a/out/", false},
+		{"sibling dirs with a common prefix", "/a/src", "/a/src-out", false},
+		{"relative sibling dirs", "./src", "./out", false},
+		{"same dir", "/a/src", "/a/src/", true},
+		{"same relative dir", ".", "./", true},
+		{"output inside source", "/a/src", "/a/src/out", true},
+		{"source inside output", "/a/src/in", "/a/src", true},
+		{"relative output inside source", ".", "./out", true},
+	}
+	for _, tt := range tts {
+		t.Run(tt.desc, func(t *testing.T) {
+			if common.PathsOverlap(tt.path1, tt.path2) != tt.answer {
+				t.Fatalf("Failed on test case: %+v", tt)
+			}
+		})
+	}
+}
+
 func TestSplitOnDotExpectInsideQuotes(t *testing.T) {
 	tts := []struct {
 		desc   string
@@ -826,6 +858,25 @@ func TestSplitOnDotExpectInsideQuotes(t *testing.T) {
 				`foo bar`,
 				`enable`,
 			}},
+		{
+			"key with quoted and bracketed parts",
+			`spec.'a.b'.containers.[name='c.d'].[0]."e.f"`,
+			[]string{
+				`spec`,
+				`'a.b'`,
+				`containers`,
+				`[name='c.d']`,
+				`[0]`,
+				`"e.f"`,
+			}},
+		{
+			"double quotes inside single quotes",
+			`a.'b."c.d'.e`,
+			[]string{
+				`a`,
+				`'b."c.d'`,
+				`e`,
+			}},
 	}
 	for _, tt := range tts {
 		t.Run(tt.desc, func(t *testing.T) {
@@ -858,3 +909,45 @@ func TestStripQuotes(t *testing.T) {
 		})
 	}
 }
+
+func TestIsDockerfileName(t *testing.T) {
+	tts := []struct {
+		desc   string
+		path   string
+		answer bool
+	}{
+		{"Dockerfile", "foo/Dockerfile", true},
+		{"Dockerfile with extension", "foo/Dockerfile.prod", true},
+		{"Containerfile", "foo/Containerfile", true},
+		{"Containerfile with extension", "foo/Containerfile.dev", true},
+		{"name with Dockerfile prefix", "foo/Dockerfiles", false},
+		{"other file", "foo/main.go", false},
+	}
+	for _, tt := range tts {
+		t.Run(tt.desc, func(t *testing.T) {
+			if isDockerfile := common.IsDockerfileName(tt.path); isDockerfile != tt.answer {
+				t.Fatalf("Failed to match the path %s properly. Expected: %t Actual: %t", tt.path, tt.answer, isDockerfile)
+			}
+		})
+	}
+}
+
+func TestGetDockerfilePathInDir(t *testing.T) {
+	t.Run("directory with a Containerfile", func(t *testing.T) {
+		dir := t.TempDir()
+		want := filepath.Join(dir, common.DefaultContainerfileName)
+		if err := ioutil.WriteFile(want, []byte("FROM alpine\n"), common.DefaultFilePermission); err != nil {
+			t.Fatalf("Failed to write the Containerfile at path %s . Error: %q", want, err)
+		}
+		if path := common.GetDockerfilePathInDir(dir); path != want {
+			t.Fatalf("Expected: %s Actual: %s", want, path)
+		}
+	})
+	t.Run("directory without a Dockerfile or Containerfile", func(t *testing.T) {
+		dir := t.TempDir()
+		want := filepath.Join(dir, common.DefaultDockerfileName)
+		if path := common.GetDockerfilePathInDir(dir); path != want {
+			t.Fatalf("Expected: %s Actual: %s", want, path)
+		}
+	})
+}
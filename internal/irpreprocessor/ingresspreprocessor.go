@@ -41,7 +41,11 @@ func (opt *ingressPreprocessor) preprocess(ir irtypes.IR) (irtypes.IR, error) {
 		key := common.ConfigServicesKey + common.Delim + `"` + sn + `"` + common.Delim + "urlpath"
 		message := fmt.Sprintf("What URL/path should we expose the service %s on?", sn)
 		hints := []string{"Enter empty string to not expose the service"}
-		exposedServiceRelPath := "/" + sn
+		// use the path set by the transformers as the default
+		exposedServiceRelPath := ir.Services[sn].ServiceRelPath
+		if exposedServiceRelPath == "" {
+			exposedServiceRelPath = "/" + sn
+		}
 		exposedServiceRelPath = strings.TrimSpace(qaengine.FetchStringAnswer(key, message, hints, exposedServiceRelPath))
 		logrus.Debugf("Exposing service %s on path %s", sn, exposedServiceRelPath)
 		tempService := ir.Services[sn]
@@ -86,6 +86,6 @@ func (*portMergePreprocessor) exposePorts(service *irtypes.Service, portToContai
 		// Forward the port on the k8s service to the k8s pod.
 		podPort := irtypes.Port{Number: int32(port)}
 		servicePort := podPort
-		service.AddPortForwarding(servicePort, podPort)
+		service.AddPortForwarding(servicePort, podPort, "")
 	}
 }
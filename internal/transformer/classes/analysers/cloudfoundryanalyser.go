@@ -205,7 +205,7 @@ func (t *CloudFoundry) Transform(newArtifacts []transformertypes.Artifact, oldAr
 					// Forward the port on the k8s service to the k8s pod.
 					podPort := irtypes.Port{Number: int32(port)}
 					servicePort := podPort
-					serviceConfig.AddPortForwarding(servicePort, podPort)
+					serviceConfig.AddPortForwarding(servicePort, podPort, "")
 				}
 				envvar := core.EnvVar{Name: "PORT", Value: cast.ToString(cfinstanceapp.Ports[0])}
 				serviceContainer.Env = append(serviceContainer.Env, envvar)
@@ -216,7 +216,7 @@ func (t *CloudFoundry) Transform(newArtifacts []transformertypes.Artifact, oldAr
 				// Forward the port on the k8s service to the k8s pod.
 				podPort := irtypes.Port{Number: int32(port)}
 				servicePort := podPort
-				serviceConfig.AddPortForwarding(servicePort, podPort)
+				serviceConfig.AddPortForwarding(servicePort, podPort, "")
 				envvar := core.EnvVar{Name: "PORT", Value: cast.ToString(port)}
 				serviceContainer.Env = append(serviceContainer.Env, envvar)
 			}
@@ -141,7 +141,7 @@ func (t *CNBContainerizer) Transform(newArtifacts []transformertypes.Artifact, o
 			// Forward the port on the k8s service to the k8s pod.
 			podPort := irtypes.Port{Number: int32(port)}
 			servicePort := podPort
-			irService.AddPortForwarding(servicePort, podPort)
+			irService.AddPortForwarding(servicePort, podPort, "")
 		}
 		serviceContainer.Ports = serviceContainerPorts
 		irService.Containers = []core.Container{serviceContainer}
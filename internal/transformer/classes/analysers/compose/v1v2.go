@@ -369,7 +369,7 @@ func (c *V1V2Loader) addPorts(composePorts []string, expose []string, service *i
 			// Forward the port on the k8s service to the k8s pod.
 			podPort := irtypes.Port{Number: int32(podPortNumber)}
 			servicePort := irtypes.Port{Number: int32(servicePortNumber)}
-			service.AddPortForwarding(servicePort, podPort)
+			service.AddPortForwarding(servicePort, podPort, "")
 			exist[servicePortNumber] = true
 		}
 	}
@@ -382,7 +382,7 @@ func (c *V1V2Loader) addPorts(composePorts []string, expose []string, service *i
 			// Forward the port on the k8s service to the k8s pod.
 			podPort := irtypes.Port{Number: int32(podPortNumber)}
 			servicePort := irtypes.Port{Number: int32(servicePortNumber)}
-			service.AddPortForwarding(servicePort, podPort)
+			service.AddPortForwarding(servicePort, podPort, "")
 			exist[servicePortNumber] = true
 		}
 	}
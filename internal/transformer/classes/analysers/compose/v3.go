@@ -522,7 +522,7 @@ func (*V3Loader) addPorts(ports []types.ServicePortConfig, expose []string, serv
 		servicePort := irtypes.Port{
 			Number: int32(port.Published),
 		}
-		service.AddPortForwarding(servicePort, podPort)
+		service.AddPortForwarding(servicePort, podPort, "")
 		exist[cast.ToString(port.Target)] = true
 	}
 	for _, port := range expose {
@@ -542,7 +542,7 @@ func (*V3Loader) addPorts(ports []types.ServicePortConfig, expose []string, serv
 		servicePort := irtypes.Port{
 			Number: portNumber,
 		}
-		service.AddPortForwarding(servicePort, podPort)
+		service.AddPortForwarding(servicePort, podPort, "")
 	}
 }
 
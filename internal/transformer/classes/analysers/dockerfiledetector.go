@@ -67,7 +67,11 @@ func (t *DockerfileDetector) BaseDirectoryDetect(dir string) (namedServices map[
 		if info.IsDir() {
 			return nil
 		}
-		if isdf, _ := isDockerFile(path); isdf {
+		isdf, err := isDockerFile(path)
+		if err != nil && common.IsDockerfileName(path) {
+			logrus.Warnf("The file %s is named like a Dockerfile but is not a valid Dockerfile : %s", path, err)
+		}
+		if isdf {
 			trans := plantypes.Transformer{
 				Mode:              t.Config.Spec.Mode,
 				ArtifactTypes:     t.Config.Spec.Artifacts,
@@ -151,7 +155,7 @@ func isDockerFile(path string) (isDockerfile bool, err error) {
 		if dfchild.Value == "from" {
 			r := regexp.MustCompile(`(?i)FROM\s+(--platform=[^\s]+)?[^\s]+(\s+AS\s+[^\s]+)?\s*(#.+)?$`)
 			if r.MatchString(dfchild.Original) {
-				logrus.Debugf("Identified a docker file : " + path)
+				logrus.Debugf("Identified a docker file : %s", path)
 				return true, nil
 			}
 			return false, nil
@@ -0,0 +1,140 @@
+/*
+ *  Copyright IBM Corporation 2021
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package analysers
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/konveyor/move2kube/internal/common"
+	irtypes "github.com/konveyor/move2kube/types/ir"
+	"github.com/sirupsen/logrus"
+	core "k8s.io/kubernetes/pkg/apis/core"
+)
+
+const (
+	// envFileName is the name of the env file that is read from the directory containing the dockerfile
+	envFileName = ".env"
+)
+
+// addEnvFile creates a ConfigMap from the env file next to the dockerfile and adds it to the environment of the container.
+// Nothing is done if there is no env file.
+func addEnvFile(logger *logrus.Entry, ir *irtypes.IR, irService *irtypes.Service, dockerfilepath string) error {
+	envFilePath := filepath.Join(filepath.Dir(dockerfilepath), envFileName)
+	envFileBytes, err := ioutil.ReadFile(envFilePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			logger.Debugf("There is no env file at path %s", envFilePath)
+			return nil
+		}
+		return fmt.Errorf("failed to read the env file at path %s . Error: %q", envFilePath, err)
+	}
+	envs := parseEnvFile(logger, string(envFileBytes))
+	if len(envs) == 0 {
+		logger.Debugf("The env file at path %s has no variables", envFilePath)
+		return nil
+	}
+	configMapName := common.MakeFileNameCompliant(irService.Name + "-env")
+	content := map[string][]byte{}
+	for key, value := range envs {
+		content[key] = []byte(value)
+	}
+	ir.AddStorage(irtypes.Storage{Name: configMapName, StorageType: irtypes.ConfigMapKind, Content: content})
+	envFrom := core.EnvFromSource{ConfigMapRef: &core.ConfigMapEnvSource{LocalObjectReference: core.LocalObjectReference{Name: configMapName}}}
+	irService.Containers[0].EnvFrom = append(irService.Containers[0].EnvFrom, envFrom)
+	logger.Debugf("Added the %d variables in the env file at path %s to the ConfigMap %s", len(envs), envFilePath, configMapName)
+	return nil
+}
+
+// parseEnvFile returns the variables in the env file.
+// Blank lines and lines starting with # are ignored. Values can be single or double quoted.
+// Unquoted values end at a # preceded by whitespace. Escape sequences are only expanded in double quoted values.
+func parseEnvFile(logger *logrus.Entry, contents string) map[string]string {
+	envs := map[string]string{}
+	for i, line := range strings.Split(contents, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+		idx := strings.Index(line, "=")
+		if idx == -1 {
+			logger.Warnf("Ignoring the line %d of the env file since it is not of the form KEY=VALUE : %s", i+1, line)
+			continue
+		}
+		key := strings.TrimSpace(line[:idx])
+		if key == "" {
+			logger.Warnf("Ignoring the line %d of the env file since the key is empty : %s", i+1, line)
+			continue
+		}
+		value, err := parseEnvValue(strings.TrimSpace(line[idx+1:]))
+		if err != nil {
+			logger.Warnf("Ignoring the line %d of the env file : %s", i+1, err)
+			continue
+		}
+		envs[key] = value
+	}
+	return envs
+}
+
+// parseEnvValue removes the quotes and the trailing comment from the value
+func parseEnvValue(value string) (string, error) {
+	if value == "" {
+		return "", nil
+	}
+	switch quote := value[0]; quote {
+	case '\'':
+		end := strings.IndexByte(value[1:], '\'')
+		if end == -1 {
+			return "", fmt.Errorf("the single quoted value %s is not terminated", value)
+		}
+		return value[1 : end+1], nil
+	case '"':
+		unquoted := strings.Builder{}
+		for i := 1; i < len(value); i++ {
+			switch c := value[i]; c {
+			case '"':
+				return unquoted.String(), nil
+			case '\\':
+				if i+1 == len(value) {
+					continue
+				}
+				i++
+				switch value[i] {
+				case 'n':
+					unquoted.WriteByte('\n')
+				case 't':
+					unquoted.WriteByte('\t')
+				default:
+					unquoted.WriteByte(value[i])
+				}
+			default:
+				unquoted.WriteByte(c)
+			}
+		}
+		return "", fmt.Errorf("the double quoted value %s is not terminated", value)
+	}
+	for i := 1; i < len(value); i++ {
+		if value[i] == '#' && (value[i-1] == ' ' || value[i-1] == '\t') {
+			return strings.TrimSpace(value[:i]), nil
+		}
+	}
+	return value, nil
+}
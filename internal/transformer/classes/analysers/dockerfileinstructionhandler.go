@@ -0,0 +1,62 @@
+/*
+ *  Copyright IBM Corporation 2021
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package analysers
+
+import (
+	"sync"
+
+	dockerparser "github.com/moby/buildkit/frontend/dockerfile/parser"
+	core "k8s.io/kubernetes/pkg/apis/core"
+)
+
+// DockerfileInstructionHandler is called by the DockerfileParser for each instruction in the dockerfile.
+// The node is a top level node of the dockerfile AST. node.Value is the lower case instruction name (like "expose"),
+// node.Next is the linked list of its arguments and node.Original is the line as written in the dockerfile.
+// The handler is called after the default handling, so it can augment or override the container of the service.
+// Dockerfiles are parsed concurrently, so the handler must be safe for concurrent use.
+type DockerfileInstructionHandler interface {
+	HandleInstruction(node *dockerparser.Node, container *core.Container) error
+}
+
+// NoopInstructionHandler is the default DockerfileInstructionHandler and it does nothing
+type NoopInstructionHandler struct{}
+
+// HandleInstruction does nothing
+func (NoopInstructionHandler) HandleInstruction(*dockerparser.Node, *core.Container) error {
+	return nil
+}
+
+var (
+	instructionHandlersMutex sync.RWMutex
+	instructionHandlers      = map[string]DockerfileInstructionHandler{}
+)
+
+// RegisterDockerfileInstructionHandler registers a handler that can be selected using the
+// instructionHandler field in the config of the DockerfileParser transformer
+func RegisterDockerfileInstructionHandler(name string, handler DockerfileInstructionHandler) {
+	instructionHandlersMutex.Lock()
+	defer instructionHandlersMutex.Unlock()
+	instructionHandlers[name] = handler
+}
+
+// getDockerfileInstructionHandler returns the registered handler with the given name
+func getDockerfileInstructionHandler(name string) (DockerfileInstructionHandler, bool) {
+	instructionHandlersMutex.RLock()
+	defer instructionHandlersMutex.RUnlock()
+	handler, ok := instructionHandlers[name]
+	return handler, ok
+}
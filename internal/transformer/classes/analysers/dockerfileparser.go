@@ -17,30 +17,188 @@
 package analysers
 
 import (
+	"errors"
+	"fmt"
+	"io/ioutil"
 	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"runtime"
 	"strconv"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/konveyor/move2kube/environment"
 	"github.com/konveyor/move2kube/internal/common"
+	"github.com/konveyor/move2kube/parameterizer"
+	"github.com/konveyor/move2kube/qaengine"
 	irtypes "github.com/konveyor/move2kube/types/ir"
 	plantypes "github.com/konveyor/move2kube/types/plan"
 	transformertypes "github.com/konveyor/move2kube/types/transformer"
 	"github.com/konveyor/move2kube/types/transformer/artifacts"
+	"github.com/moby/buildkit/frontend/dockerfile/dockerignore"
 	dockerparser "github.com/moby/buildkit/frontend/dockerfile/parser"
+	dockershell "github.com/moby/buildkit/frontend/dockerfile/shell"
 	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/validation"
 	core "k8s.io/kubernetes/pkg/apis/core"
 )
 
+var (
+	windowsImageRegex = regexp.MustCompile(`(?i)(windows|nanoserver|servercore)`)
+	// images like node:onbuild and python:3-onbuild carry ONBUILD triggers
+	onbuildImageRegex = regexp.MustCompile(`(?i):.*onbuild`)
+	// matches heredoc markers like <<EOF, <<-EOF and <<"EOF" but not the here-string <<<
+	heredocMarkerRegex   = regexp.MustCompile(`(^|[^<])<<(-?)(["']?)([A-Za-z_][A-Za-z0-9_]*)["']?`)
+	syntaxDirectiveRegex = regexp.MustCompile(`(?i)^#\s*syntax\s*=\s*(\S+)$`)
+)
+
+// ports below privilegedPortLimit can only be bound by root or with the NET_BIND_SERVICE capability
+const (
+	privilegedPortLimit                      = 1024
+	netBindServiceCapability core.Capability = "NET_BIND_SERVICE"
+)
+
+// the valid range of container ports and the maximum length of a port name (IANA_SVC_NAME)
+const (
+	minPort           = 1
+	maxPort           = 65535
+	maxPortNameLength = 15
+)
+
+// node ports are allocated from this range by default in kubernetes
+const (
+	minNodePort = 30000
+	maxNodePort = 32767
+)
+
+// maxSkippedDockerfileInstructions is the number of instructions that can fail to parse before the dockerfile is considered invalid
+const maxSkippedDockerfileInstructions = 10
+
+// defaultMinAvailable is the minimum number of pods kept available by the PodDisruptionBudget
+const defaultMinAvailable = 1
+
+// platformFlagPrefix is the prefix of the flag of the FROM instruction that selects the platform. Example: --platform=linux/arm64
+const platformFlagPrefix = "--platform="
+
+// defaultPlatformArch is the architecture that doesn't need a node selector since most clusters run on it
+const defaultPlatformArch = "amd64"
+
+// dockerfileParserTransformerName is the value of the transformer annotation on the services created by the DockerfileParser
+const dockerfileParserTransformerName = "DockerfileParser"
+
+// httpPorts are the ports typically used by web servers
+var httpPorts = []int{80, 443, 3000, 5000, 8000, 8080, 8443, 8888}
+
 // DockerfileParser implements Transformer interface
 type DockerfileParser struct {
-	TConfig transformertypes.Transformer
-	Env     *environment.Environment
+	TConfig                transformertypes.Transformer
+	DockerfileParserConfig DockerfileParserYamlConfig
+	Env                    *environment.Environment
+	usedNodePorts          map[int32]bool
+	usedNodePortsMutex     sync.Mutex
+	instructionHandler     DockerfileInstructionHandler
+}
+
+// DockerfileParserYamlConfig represents the configuration of the DockerfileParser
+type DockerfileParserYamlConfig struct {
+	CPURequest    string `yaml:"cpuRequest"`
+	CPULimit      string `yaml:"cpuLimit"`
+	MemoryRequest string `yaml:"memoryRequest"`
+	MemoryLimit   string `yaml:"memoryLimit"`
+	// AddNetBindServiceCapability adds the NET_BIND_SERVICE capability to containers that expose privileged ports
+	AddNetBindServiceCapability bool `yaml:"addNetBindServiceCapability"`
+	// Registry is prefixed to the image names. Example: quay.io/myorg
+	Registry string `yaml:"registry"`
+	// ImagePullPolicy is one of Always, IfNotPresent or Never
+	ImagePullPolicy string `yaml:"imagePullPolicy"`
+	// AddIngressPath exposes services with a typical HTTP port on the ingress path /
+	AddIngressPath bool `yaml:"addIngressPath"`
+	// BaseDockerfiles maps base image names to their dockerfiles so that the ports exposed by their ONBUILD triggers can be detected
+	BaseDockerfiles map[string]string `yaml:"baseDockerfiles"`
+	// NodePort creates NodePort services instead of ClusterIP services
+	NodePort bool `yaml:"nodePort"`
+	// AssignNodePorts assigns node ports derived from the exposed ports when NodePort is set.
+	// Otherwise the node ports are chosen by the cluster.
+	AssignNodePorts bool `yaml:"assignNodePorts"`
+	// InstructionHandler is the name of a handler registered using RegisterDockerfileInstructionHandler
+	InstructionHandler string `yaml:"instructionHandler"`
+	// Naming is applied to the service name, the image name and the project name
+	Naming NamingConfig `yaml:"naming"`
+	// EnvFile adds the variables in the .env file next to the dockerfile to the container using a ConfigMap
+	EnvFile bool `yaml:"envFile"`
+	// DefaultTCPProbe adds a TCP readiness probe on the first exposed port if the dockerfile doesn't have a HEALTHCHECK
+	DefaultTCPProbe bool `yaml:"defaultTCPProbe"`
+	// DetectPorts looks for the ports in well known framework files like package.json and application.properties
+	// next to the dockerfile when the dockerfile doesn't have an EXPOSE instruction
+	DetectPorts bool `yaml:"detectPorts"`
+	// DetectCommandPorts looks for port flags like --port 3000 and -p 3000 in the ENTRYPOINT and CMD
+	// when the dockerfile doesn't have an EXPOSE instruction
+	DetectCommandPorts bool `yaml:"detectCommandPorts"`
+	// PodDisruptionBudget creates a PodDisruptionBudget for services with a typical HTTP port.
+	// It keeps at least one pod of the service available during voluntary disruptions like node drains.
+	PodDisruptionBudget bool `yaml:"podDisruptionBudget"`
+	// Concurrency is the number of dockerfiles parsed in parallel. By default it is the number of CPUs.
+	Concurrency int `yaml:"concurrency"`
+}
+
+// NamingConfig transforms the names of the generated resources
+type NamingConfig struct {
+	Prefix    string `yaml:"prefix"`
+	Suffix    string `yaml:"suffix"`
+	Lowercase bool   `yaml:"lowercase"`
+	// DNSLabel makes the names valid DNS-1123 labels. Names longer than 63 characters are truncated and a hash is added to keep them unique.
+	DNSLabel bool `yaml:"dnsLabel"`
+}
+
+// apply returns the name after applying the naming config
+func (n NamingConfig) apply(name string) string {
+	name = n.Prefix + name + n.Suffix
+	if n.Lowercase {
+		name = strings.ToLower(name)
+	}
+	if n.DNSLabel && name != "" {
+		name = common.MakeStringDNSLabelNameCompliant(name)
+	}
+	return name
+}
+
+// applyToImageName applies the naming config to the repository name of the image, leaving the registry and the tag unchanged
+func (n NamingConfig) applyToImageName(imageName string) string {
+	prefix, name, tag := "", imageName, ""
+	if idx := strings.LastIndex(name, "/"); idx != -1 {
+		prefix, name = name[:idx+1], name[idx+1:]
+	}
+	if idx := strings.LastIndex(name, ":"); idx != -1 {
+		name, tag = name[:idx], name[idx:]
+	}
+	return prefix + n.apply(name) + tag
 }
 
 // Init Initializes the transformer
 func (t *DockerfileParser) Init(tc transformertypes.Transformer, env *environment.Environment) (err error) {
 	t.TConfig = tc
 	t.Env = env
+	t.DockerfileParserConfig = DockerfileParserYamlConfig{}
+	t.usedNodePorts = map[int32]bool{}
+	err = common.GetObjFromInterface(t.TConfig.Spec.Config, &t.DockerfileParserConfig)
+	if err != nil {
+		logrus.Errorf("unable to load config for Transformer %+v into %T : %s", t.TConfig.Spec.Config, t.DockerfileParserConfig, err)
+		return err
+	}
+	t.instructionHandler = NoopInstructionHandler{}
+	if name := t.DockerfileParserConfig.InstructionHandler; name != "" {
+		handler, ok := getDockerfileInstructionHandler(name)
+		if !ok {
+			return fmt.Errorf("the instruction handler %s in the config of the transformer %s is not registered", name, t.TConfig.Name)
+		}
+		t.instructionHandler = handler
+	}
 	return nil
 }
 
@@ -59,9 +217,19 @@ func (t *DockerfileParser) DirectoryDetect(dir string) (namedServices map[string
 	return nil, nil, nil
 }
 
-// Transform transforms the artifacts
+// dockerfileJob is a dockerfile that has to be parsed by the Transform workers
+type dockerfileJob struct {
+	path        string
+	contextPath string
+	imageName   string
+	serviceName string
+}
+
+// Transform transforms the artifacts.
+// The image names are decided first since that can involve questions to the user.
+// The dockerfiles are then parsed concurrently and the artifacts are returned in the same order as the dockerfiles.
 func (t *DockerfileParser) Transform(newArtifacts []transformertypes.Artifact, oldArtifacts []transformertypes.Artifact) ([]transformertypes.PathMapping, []transformertypes.Artifact, error) {
-	nartifacts := []transformertypes.Artifact{}
+	jobs := []dockerfileJob{}
 	processedImages := map[string]bool{}
 	for _, a := range newArtifacts {
 		if a.Artifact != artifacts.DockerfileForServiceArtifactType {
@@ -78,86 +246,1114 @@ func (t *DockerfileParser) Transform(newArtifacts []transformertypes.Artifact, o
 			logrus.Debugf("unable to load config for Transformer into %T : %s", sImageName, err)
 		}
 		if sImageName.ImageName == "" {
-			sImageName.ImageName = common.MakeStringContainerImageNameCompliant(a.Name)
+			sImageName.ImageName = t.getImageName(a.Name)
 		}
 		if processedImages[sImageName.ImageName] {
 			continue
 		}
 		processedImages[sImageName.ImageName] = true
+		contextPath := ""
+		if pps := a.Paths[artifacts.ProjectPathPathType]; len(pps) > 0 {
+			contextPath = pps[0]
+		}
 		for _, path := range a.Paths[artifacts.DockerfilePathType] {
-			na := t.getIRFromDockerfile(path, sImageName.ImageName, sConfig.ServiceName)
-			if na != nil {
-				nartifacts = append(nartifacts, *na)
+			jobs = append(jobs, dockerfileJob{path: path, contextPath: contextPath, imageName: sImageName.ImageName, serviceName: sConfig.ServiceName})
+		}
+	}
+	results := make([]*transformertypes.Artifact, len(jobs))
+	jobIdxs := make(chan int)
+	wg := sync.WaitGroup{}
+	for w := 0; w < t.getConcurrency(len(jobs)); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobIdxs {
+				job := jobs[i]
+				results[i] = t.getIRFromDockerfile(job.path, job.contextPath, job.imageName, job.serviceName)
 			}
+		}()
+	}
+	for i := range jobs {
+		jobIdxs <- i
+	}
+	close(jobIdxs)
+	wg.Wait()
+	nartifacts := []transformertypes.Artifact{}
+	for _, na := range results {
+		if na != nil {
+			nartifacts = append(nartifacts, *na)
 		}
 	}
 	return nil, nartifacts, nil
 }
 
-func (t *DockerfileParser) getIRFromDockerfile(dockerfilepath, imageName, serviceName string) *transformertypes.Artifact {
-	df, err := t.getDockerFileAST(dockerfilepath)
+// getConcurrency returns the number of workers used to parse the dockerfiles.
+// Assigning node ports depends on the order of the dockerfiles, so a single worker is used to keep the node ports deterministic.
+func (t *DockerfileParser) getConcurrency(numJobs int) int {
+	workers := t.DockerfileParserConfig.Concurrency
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	if t.DockerfileParserConfig.NodePort && t.DockerfileParserConfig.AssignNodePorts {
+		workers = 1
+	}
+	if workers > numJobs {
+		workers = numJobs
+	}
+	return workers
+}
+
+// getImageName asks the user to confirm the image name derived from the service name.
+// An empty answer selects the default image name.
+func (t *DockerfileParser) getImageName(serviceName string) string {
+	defImageName := common.MakeStringContainerImageNameCompliant(serviceName)
+	qaKey := common.ConfigServicesKey + common.Delim + `"` + serviceName + `"` + common.Delim + "imagename"
+	desc := fmt.Sprintf("What should be the name of the container image for the service %s?", serviceName)
+	hints := []string{"Enter empty string to use the default name " + defImageName}
+	imageName := strings.TrimSpace(qaengine.FetchStringAnswer(qaKey, desc, hints, defImageName))
+	if imageName == "" {
+		return defImageName
+	}
+	return imageName
+}
+
+// getIRFromDockerfile creates an IR artifact from the dockerfile.
+// If the context path is empty, the directory containing the dockerfile is used as the build context.
+func (t *DockerfileParser) getIRFromDockerfile(dockerfilepath, contextPath, imageName, serviceName string) *transformertypes.Artifact {
+	ir, dfMetadata, err := t.getIRAndMetadataFromDockerfile(dockerfilepath, contextPath, imageName, serviceName)
 	if err != nil {
-		logrus.Errorf("Unable to parse dockerfile : %s", err)
+		getDockerfileLogger(dockerfilepath, t.getImageNameWithRegistry(imageName), serviceName).Errorf("Unable to parse dockerfile : %s", err)
 		return nil
 	}
+	return &transformertypes.Artifact{
+		Name:     t.Env.GetProjectName(),
+		Artifact: irtypes.IRArtifactType,
+		Configs: map[string]interface{}{
+			irtypes.IRConfigType:                   ir,
+			artifacts.DockerfileMetadataConfigType: dfMetadata,
+		}}
+}
+
+// GetIRFromDockerfile returns the IR for the dockerfile after applying the config of the transformer.
+// If the context path is empty, the directory containing the dockerfile is used as the build context.
+func (t *DockerfileParser) GetIRFromDockerfile(dockerfilepath, contextPath, imageName, serviceName string) (irtypes.IR, error) {
+	ir, _, err := t.getIRAndMetadataFromDockerfile(dockerfilepath, contextPath, imageName, serviceName)
+	return ir, err
+}
+
+// getIRAndMetadataFromDockerfile returns the IR for the dockerfile along with the metadata collected from it
+func (t *DockerfileParser) getIRAndMetadataFromDockerfile(dockerfilepath, contextPath, imageName, serviceName string) (irtypes.IR, artifacts.DockerfileMetadataConfig, error) {
+	naming := t.DockerfileParserConfig.Naming
+	imageName = t.getImageNameWithRegistry(naming.applyToImageName(imageName))
+	ir, dfMetadata, err := parseDockerfile(getDockerfileLogger(dockerfilepath, imageName, serviceName), dockerfilepath, contextPath, naming.apply(t.Env.GetProjectName()), imageName, serviceName, naming, t.DockerfileParserConfig.BaseDockerfiles, t.instructionHandler, t.DockerfileParserConfig.DetectPorts, t.DockerfileParserConfig.DetectCommandPorts)
+	if err != nil {
+		return ir, dfMetadata, err
+	}
+	// the IR has a single service whose name can come from the service name label
+	for name := range ir.Services {
+		serviceName = name
+	}
+	logger := getDockerfileLogger(dockerfilepath, imageName, serviceName)
+	irService := ir.Services[serviceName]
+	irService.Containers[0].Resources = t.getResourceRequirements(logger)
+	irService.Containers[0].ImagePullPolicy = t.getImagePullPolicy(logger)
+	if t.DockerfileParserConfig.AddIngressPath {
+		addIngressPath(logger, &irService)
+	}
+	if t.DockerfileParserConfig.PodDisruptionBudget {
+		addPodDisruptionBudget(logger, &irService)
+	}
+	t.handlePrivilegedPorts(logger, &irService.Containers[0], dockerfilepath)
+	if t.DockerfileParserConfig.NodePort {
+		t.makeNodePortService(logger, &irService)
+	}
+	if t.DockerfileParserConfig.DefaultTCPProbe && !dfMetadata.HasHealthcheck {
+		addDefaultTCPProbe(logger, &irService.Containers[0])
+	}
+	if t.DockerfileParserConfig.EnvFile {
+		if err := addEnvFile(logger, &ir, &irService, dockerfilepath); err != nil {
+			logger.Errorf("Unable to add the env file : %s", err)
+		}
+	}
+	t.addSourceAnnotations(&irService, dockerfilepath)
+	ir.Services[serviceName] = irService
+	return ir, dfMetadata, nil
+}
+
+// addSourceAnnotations records the dockerfile and the transformer that the service was created from, and the time it was created at.
+// The time is omitted for reproducible output, unless it is pinned using the SOURCE_DATE_EPOCH environment variable.
+func (t *DockerfileParser) addSourceAnnotations(irService *irtypes.Service, dockerfilepath string) {
+	if irService.Annotations == nil {
+		irService.Annotations = map[string]string{}
+	}
+	sourceDockerfile := dockerfilepath
+	if t.Env != nil && t.Env.Source != "" {
+		if relPath, err := filepath.Rel(t.Env.Source, dockerfilepath); err == nil && !strings.HasPrefix(relPath, "..") {
+			sourceDockerfile = filepath.ToSlash(relPath)
+		}
+	}
+	irService.Annotations[common.SourceDockerfileAnnotation] = sourceDockerfile
+	irService.Annotations[common.TransformerAnnotation] = dockerfileParserTransformerName
+	timestamp, err := parameterizer.GetTimestamp(common.Reproducible)
+	if err != nil {
+		logrus.Warnf("Skipping the %s annotation. Error: %q", common.GeneratedAtAnnotation, err)
+		return
+	}
+	if !timestamp.IsZero() {
+		irService.Annotations[common.GeneratedAtAnnotation] = timestamp.UTC().Format(time.RFC3339)
+	}
+}
+
+// ParseDockerfileToIR creates an IR containing a single service from the dockerfile.
+// The directory containing the dockerfile is used as the build context.
+func ParseDockerfileToIR(dockerfilePath, projectName, imageName, serviceName string) (irtypes.IR, error) {
+	ir, _, err := parseDockerfile(getDockerfileLogger(dockerfilePath, imageName, serviceName), dockerfilePath, "", projectName, imageName, serviceName, NamingConfig{}, nil, nil, false, false)
+	return ir, err
+}
+
+// getDockerfileLogger returns a logger that adds the service, image and dockerfile to every message
+func getDockerfileLogger(dockerfilepath, imageName, serviceName string) *logrus.Entry {
+	return logrus.WithFields(logrus.Fields{"service": serviceName, "image": imageName, "dockerfile": dockerfilepath})
+}
+
+// parseDockerfile creates an IR and collects the metadata from the dockerfile.
+// baseDockerfiles maps base image names to their dockerfiles so that the ONBUILD triggers of the base images can be used.
+// If the instruction handler is not nil, it is called for each instruction with the container of the service.
+// The service name is replaced by the value of the service name label if the dockerfile sets it, and then the naming config is applied to it.
+// If detectCommandPorts is true and the dockerfile doesn't expose any ports, the ports are detected from the port flags in the ENTRYPOINT and CMD.
+// If detectPorts is true and the ports are still not found, the ports are detected from the framework files next to the dockerfile.
+func parseDockerfile(logger *logrus.Entry, dockerfilepath, contextPath, projectName, imageName, serviceName string, naming NamingConfig, baseDockerfiles map[string]string, instructionHandler DockerfileInstructionHandler, detectPorts, detectCommandPorts bool) (irtypes.IR, artifacts.DockerfileMetadataConfig, error) {
+	df, err := getDockerFileAST(logger, dockerfilepath)
+	if err != nil {
+		return irtypes.IR{}, artifacts.DockerfileMetadataConfig{}, err
+	}
+	dfInfo, dfMetadata, err := parseDockerfileInstructions(logger, df, baseDockerfiles)
+	if err != nil {
+		return irtypes.IR{}, dfMetadata, fmt.Errorf("failed to parse the instructions of the dockerfile %s . Error: %q", dockerfilepath, err)
+	}
 	ir := irtypes.NewIR()
-	ir.Name = t.Env.GetProjectName()
+	ir.Name = projectName
 	container := irtypes.NewContainer()
-	for _, dfchild := range df.AST.Children {
-		if dfchild.Value == "expose" {
-			for {
-				dfchild = dfchild.Next
-				if dfchild == nil {
-					break
-				}
-				p, err := strconv.Atoi(dfchild.Value)
-				if err != nil {
-					logrus.Errorf("Unable to parse port %s as int in %s", dfchild.Value, dockerfilepath)
-					continue
-				}
-				container.AddExposedPort(p)
-			}
+	// the protocols exposed for each port. TCP is stored as an empty protocol since it is the default.
+	protocols := map[int][]core.Protocol{}
+	for _, port := range dfInfo.Ports {
+		container.AddExposedPort(int(port.ContainerPort))
+		protocols[int(port.ContainerPort)] = append(protocols[int(port.ContainerPort)], port.Protocol)
+	}
+	entrypoint, cmd := dfInfo.Entrypoint, dfInfo.Cmd
+	if len(container.ExposedPorts) == 0 && detectCommandPorts {
+		for _, port := range detectPortsFromCommand(logger, append(append([]string{}, entrypoint...), cmd...)) {
+			container.AddExposedPort(port)
+		}
+	}
+	if len(container.ExposedPorts) == 0 && detectPorts {
+		for _, port := range detectFrameworkPorts(logger, filepath.Dir(dockerfilepath)) {
+			container.AddExposedPort(port)
 		}
 	}
 	if len(container.ExposedPorts) == 0 {
-		logrus.Warnf("Unable to find ports in Dockerfile : %s. Using default port", dockerfilepath)
+		logger.Warnf("Unable to find ports in Dockerfile : %s. Using default port", dockerfilepath)
 		container.AddExposedPort(common.DefaultServicePort)
 	}
+	if contextPath == "" {
+		contextPath = filepath.Dir(dockerfilepath)
+	}
+	ignorePatterns, err := getDockerignorePatterns(logger, contextPath, filepath.Dir(dockerfilepath))
+	if err != nil {
+		return irtypes.IR{}, dfMetadata, err
+	}
+	dfMetadata.IgnorePatterns = ignorePatterns
+	container.Build = irtypes.ContainerBuild{
+		ContainerBuildType: irtypes.DockerfileContainerBuildType,
+		ContextPath:        contextPath,
+		Artifacts: map[irtypes.ContainerBuildArtifactTypeValue][]string{
+			irtypes.DockerfileContainerBuildArtifactTypeValue: {dockerfilepath},
+		},
+	}
 	ir.AddContainer(imageName, container)
+	serviceName = naming.apply(getServiceNameFromLabels(logger, dfMetadata.Labels, serviceName))
 	serviceContainer := core.Container{Name: serviceName}
 	serviceContainer.Image = imageName
+	serviceContainer.Command = entrypoint
+	serviceContainer.Args = cmd
 	irService := irtypes.NewServiceWithName(serviceName)
+	addDockerfileInfo(logger, dfInfo, &irService, &serviceContainer)
+	portNames := getPortNamesFromLabels(logger, dfMetadata.Labels)
 	serviceContainerPorts := []core.ContainerPort{}
 	for _, port := range container.ExposedPorts {
-		// Add the port to the k8s pod.
-		serviceContainerPort := core.ContainerPort{ContainerPort: int32(port)}
-		serviceContainerPorts = append(serviceContainerPorts, serviceContainerPort)
-		// Forward the port on the k8s service to the k8s pod.
-		podPort := irtypes.Port{Number: int32(port)}
-		servicePort := podPort
-		irService.AddPortForwarding(servicePort, podPort)
+		portProtocols := protocols[port]
+		if len(portProtocols) == 0 {
+			portProtocols = []core.Protocol{""}
+		}
+		// the same port can be exposed with different protocols. Example: EXPOSE 53/tcp 53/udp
+		for i, protocol := range portProtocols {
+			// Only the first protocol gets the name from the label since the port names must be unique.
+			// The other protocols get generated names.
+			portName := ""
+			if i == 0 {
+				portName = portNames[port]
+			}
+			// Add the port to the k8s pod.
+			serviceContainerPort := core.ContainerPort{Name: portName, ContainerPort: int32(port), Protocol: protocol}
+			serviceContainerPorts = append(serviceContainerPorts, serviceContainerPort)
+			// Forward the port on the k8s service to the k8s pod.
+			podPort := irtypes.Port{Name: portName, Number: int32(port)}
+			servicePort := podPort
+			irService.AddPortForwarding(servicePort, podPort, protocol)
+		}
 	}
 	serviceContainer.Ports = serviceContainerPorts
+	if instructionHandler != nil {
+		for _, dfchild := range df.AST.Children {
+			if err := instructionHandler.HandleInstruction(dfchild, &serviceContainer); err != nil {
+				logger.Errorf("The instruction handler failed on the instruction %s : %s", dfchild.Original, err)
+			}
+		}
+	}
+	makePortNamesUnique(logger, serviceContainer.Ports)
 	irService.Containers = []core.Container{serviceContainer}
+	if dfInfo.PlatformArch != "" && dfInfo.PlatformArch != defaultPlatformArch {
+		irService.NodeSelector = map[string]string{corev1.LabelArchStable: dfInfo.PlatformArch}
+	}
 	ir.Services[serviceName] = irService
-	return &transformertypes.Artifact{
-		Name:     t.Env.GetProjectName(),
-		Artifact: irtypes.IRArtifactType,
-		Configs: map[string]interface{}{
-			irtypes.IRConfigType: ir,
-		}}
+	return ir, dfMetadata, nil
+}
+
+// DockerfileInfo contains the results of parsing the instructions of the final stage of a dockerfile
+type DockerfileInfo struct {
+	// Ports are the ports exposed by all the stages in the order they were exposed. TCP is stored as an empty protocol since it is the default.
+	Ports []core.ContainerPort
+	// EnvVars are the environment variables set by the ENV instructions
+	EnvVars []core.EnvVar
+	// Volumes are the mount points created by the VOLUME instructions
+	Volumes []string
+	// WorkingDir is the directory set by the last WORKDIR instruction. Relative directories are resolved against the earlier ones.
+	WorkingDir string
+	// User is the user set by the last USER instruction
+	User string
+	// Entrypoint is the ENTRYPOINT. Shell form commands are prefixed with the shell.
+	Entrypoint []string
+	// Cmd is the CMD. It is empty if the ENTRYPOINT uses the shell form since the CMD is ignored in that case.
+	Cmd []string
+	// Labels are the labels set by the LABEL instructions
+	Labels map[string]string
+	// IsWindows is true if the base image is a Windows image
+	IsWindows bool
+	// PlatformArch is the architecture from the --platform flag of the FROM instruction
+	PlatformArch string
+}
+
+// ParseDockerfileInstructions returns the information in the instructions of the final stage of the dockerfile.
+// The ports are collected from all the stages. It fails if the dockerfile does not have a FROM instruction.
+func ParseDockerfileInstructions(ast *dockerparser.Result) (DockerfileInfo, error) {
+	dfInfo, _, err := parseDockerfileInstructions(logrus.NewEntry(logrus.StandardLogger()), ast, nil)
+	return dfInfo, err
+}
+
+// parseDockerfileInstructions returns the information in the instructions of the final stage of the dockerfile
+// along with the metadata collected from all the stages. The ports are collected from all the stages.
+// baseDockerfiles maps base image names to their dockerfiles so that the ports exposed by their ONBUILD triggers are included.
+func parseDockerfileInstructions(logger *logrus.Entry, df *dockerparser.Result, baseDockerfiles map[string]string) (DockerfileInfo, artifacts.DockerfileMetadataConfig, error) {
+	dfInfo := DockerfileInfo{}
+	dfMetadata := artifacts.DockerfileMetadataConfig{}
+	hasFrom, isShellFormEntrypoint := false, false
+	var shell []string
+	// the ARG instructions before the first FROM declare global build args that can be used in the FROM instructions
+	globalArgs := map[string]string{}
+	addExposedPorts := func(exposeNode *dockerparser.Node, source string) {
+		for _, exposedPort := range getNodeArgs(exposeNode) {
+			p, protocol, err := parseExposedPort(exposedPort)
+			if err != nil {
+				logger.Errorf("Unable to parse port %s in %s : %s", exposedPort, source, err)
+				continue
+			}
+			if protocol == core.ProtocolTCP {
+				protocol = ""
+			}
+			if !isContainerPortPresent(dfInfo.Ports, p, protocol) {
+				dfInfo.Ports = append(dfInfo.Ports, core.ContainerPort{ContainerPort: int32(p), Protocol: protocol})
+			}
+		}
+	}
+	for _, dfchild := range df.AST.Children {
+		switch dfchild.Value {
+		case "from":
+			// the shell, entrypoint, cmd and the other settings of the final stage are the ones that are used
+			hasFrom = true
+			isShellFormEntrypoint = false
+			shell = nil
+			dfInfo = DockerfileInfo{Ports: dfInfo.Ports}
+			dfMetadata.HasHealthcheck = false
+			dfMetadata.Labels = nil
+			dfInfo.PlatformArch = getPlatformArch(resolveBuildArgs(logger, df.EscapeToken, getPlatformFlag(dfchild), globalArgs))
+			if dfchild.Next == nil {
+				dfInfo.IsWindows = isWindowsContainer(dfchild, "")
+				continue
+			}
+			baseImage := resolveBuildArgs(logger, df.EscapeToken, dfchild.Next.Value, globalArgs)
+			dfInfo.IsWindows = isWindowsContainer(dfchild, baseImage)
+			dfBaseImage := getBaseImage(dfchild, baseImage, dfMetadata.BaseImages)
+			dfMetadata.BaseImages = append(dfMetadata.BaseImages, dfBaseImage)
+			if dfBaseImage.FromStage {
+				continue
+			}
+			if baseDockerfilePath, ok := baseDockerfiles[baseImage]; ok {
+				// the ONBUILD triggers of the base image run right after the FROM instruction
+				baseDf, err := getDockerFileAST(logger, baseDockerfilePath)
+				if err != nil {
+					logger.Errorf("Unable to parse the dockerfile %s of the base image %s : %s", baseDockerfilePath, baseImage, err)
+					continue
+				}
+				for _, trigger := range getOnbuildTriggers(baseDf.AST) {
+					if trigger.Value == "expose" {
+						addExposedPorts(trigger, baseDockerfilePath)
+					}
+				}
+			} else if onbuildImageRegex.MatchString(baseImage) {
+				logger.Warnf("The base image %s might have ONBUILD triggers that expose ports. Add its dockerfile to baseDockerfiles in the config of the transformer to detect them.", baseImage)
+			}
+		case "onbuild":
+			logger.Debugf("Ignoring the ONBUILD instruction %s since it only applies to images built from this image", dfchild.Original)
+		case "shell":
+			shell = getNodeArgs(dfchild)
+		case "entrypoint":
+			dfInfo.Entrypoint = getCommandFromNode(dfchild, shell, dfInfo.IsWindows)
+			isShellFormEntrypoint = !dfchild.Attributes["json"]
+		case "cmd":
+			dfInfo.Cmd = getCommandFromNode(dfchild, shell, dfInfo.IsWindows)
+		case "expose":
+			addExposedPorts(dfchild, "the dockerfile")
+		case "env":
+			for n := dfchild.Next; n != nil && n.Next != nil; n = n.Next.Next {
+				dfInfo.EnvVars = setEnvVar(dfInfo.EnvVars, n.Value, common.StripQuotes(n.Next.Value))
+			}
+		case "volume":
+			dfInfo.Volumes = common.MergeStringSlices(dfInfo.Volumes, getNodeArgs(dfchild)...)
+		case "workdir":
+			if dfchild.Next != nil {
+				dfInfo.WorkingDir = getWorkingDir(dfInfo.WorkingDir, common.StripQuotes(dfchild.Next.Value), dfInfo.IsWindows)
+			}
+		case "user":
+			if dfchild.Next != nil {
+				dfInfo.User = dfchild.Next.Value
+			}
+		case "healthcheck":
+			dfMetadata.HasHealthcheck = dfchild.Next == nil || !strings.EqualFold(dfchild.Next.Value, "none")
+		case "copy", "add":
+			dfMetadata.CopySources = common.MergeStringSlices(dfMetadata.CopySources, getCopySources(dfchild)...)
+		case "label":
+			for key, value := range getLabels(dfchild) {
+				if dfInfo.Labels == nil {
+					dfInfo.Labels = map[string]string{}
+				}
+				dfInfo.Labels[key] = value
+			}
+			dfMetadata.Labels = dfInfo.Labels
+		case "arg":
+			buildArgs := getBuildArgs(dfchild)
+			if !hasFrom {
+				for i, buildArg := range buildArgs {
+					buildArgs[i].Global = true
+					if buildArg.Default != nil {
+						// the default value can refer to the earlier global build args
+						globalArgs[buildArg.Name] = resolveBuildArgs(logger, df.EscapeToken, *buildArg.Default, globalArgs)
+					}
+				}
+			}
+			dfMetadata.BuildArgs = append(dfMetadata.BuildArgs, buildArgs...)
+		}
+	}
+	if !hasFrom {
+		return dfInfo, dfMetadata, fmt.Errorf("the dockerfile does not have a FROM instruction")
+	}
+	if isShellFormEntrypoint {
+		// a shell form entrypoint ignores the cmd
+		dfInfo.Cmd = nil
+	}
+	return dfInfo, dfMetadata, nil
+}
+
+// addDockerfileInfo sets the environment variables, the working directory, the user and the volumes of the final stage on the container.
+// Environment variables that refer to other variables are skipped since they are expanded when the image is built.
+// The user is only set if it is a numeric user ID. Each volume is backed by an emptyDir volume.
+func addDockerfileInfo(logger *logrus.Entry, dfInfo DockerfileInfo, irService *irtypes.Service, container *core.Container) {
+	for _, envVar := range dfInfo.EnvVars {
+		if strings.Contains(envVar.Value, "$") {
+			logger.Debugf("Skipping the environment variable %s since it refers to other variables : %s", envVar.Name, envVar.Value)
+			continue
+		}
+		container.Env = append(container.Env, envVar)
+	}
+	container.WorkingDir = dfInfo.WorkingDir
+	if dfInfo.User != "" {
+		user, group := dfInfo.User, ""
+		if idx := strings.Index(user, ":"); idx != -1 {
+			user, group = user[:idx], user[idx+1:]
+		}
+		if uid, err := strconv.ParseInt(user, 10, 64); err == nil {
+			container.SecurityContext = &core.SecurityContext{RunAsUser: &uid}
+			if gid, err := strconv.ParseInt(group, 10, 64); err == nil {
+				container.SecurityContext.RunAsGroup = &gid
+			}
+		} else {
+			logger.Debugf("Ignoring the user %s since it is not a numeric user ID", dfInfo.User)
+		}
+	}
+	for i, volume := range dfInfo.Volumes {
+		volumeName := fmt.Sprintf("dockerfile-volume-%d", i)
+		irService.AddVolume(core.Volume{Name: volumeName, VolumeSource: core.VolumeSource{EmptyDir: &core.EmptyDirVolumeSource{}}})
+		container.VolumeMounts = append(container.VolumeMounts, core.VolumeMount{Name: volumeName, MountPath: volume})
+	}
+}
+
+// isContainerPortPresent returns true if the port is already present with the same protocol
+func isContainerPortPresent(ports []core.ContainerPort, port int, protocol core.Protocol) bool {
+	for _, p := range ports {
+		if int(p.ContainerPort) == port && p.Protocol == protocol {
+			return true
+		}
+	}
+	return false
+}
+
+// setEnvVar sets the value of the environment variable, adding it if it is not present
+func setEnvVar(envVars []core.EnvVar, name, value string) []core.EnvVar {
+	for i, envVar := range envVars {
+		if envVar.Name == name {
+			envVars[i].Value = value
+			return envVars
+		}
+	}
+	return append(envVars, core.EnvVar{Name: name, Value: value})
+}
+
+// getWorkingDir returns the working directory after a WORKDIR instruction.
+// Relative directories are resolved against the current working directory.
+func getWorkingDir(current, dir string, isWindows bool) string {
+	if current == "" || isWindows || path.IsAbs(dir) {
+		return dir
+	}
+	return path.Join(current, dir)
+}
+
+// addIngressPath exposes the service on the ingress path / if it has a typical HTTP port.
+// The HTTP port becomes the primary port of the service.
+func addIngressPath(logger *logrus.Entry, irService *irtypes.Service) {
+	for i, forwarding := range irService.ServiceToPodPortForwardings {
+		if !common.IsIntPresent(httpPorts, int(forwarding.ServicePort.Number)) {
+			continue
+		}
+		irService.ServiceToPodPortForwardings = append(
+			[]irtypes.ServiceToPodPortForwarding{forwarding},
+			append(irService.ServiceToPodPortForwardings[:i:i], irService.ServiceToPodPortForwardings[i+1:]...)...,
+		)
+		irService.ServiceRelPath = "/"
+		if irService.Annotations == nil {
+			irService.Annotations = map[string]string{}
+		}
+		irService.Annotations[common.ExposeSelector] = common.AnnotationLabelValue
+		logger.Debugf("Exposing the service %s on the ingress path / using the port %d", irService.Name, forwarding.ServicePort.Number)
+		return
+	}
+	logger.Debugf("Not exposing the service %s on an ingress path since it doesn't have a typical HTTP port", irService.Name)
+}
+
+// addDefaultTCPProbe adds a readiness probe that checks if the first exposed port accepts TCP connections
+func addDefaultTCPProbe(logger *logrus.Entry, container *core.Container) {
+	if container.ReadinessProbe != nil {
+		return
+	}
+	for _, port := range container.Ports {
+		if port.Protocol != "" && port.Protocol != core.ProtocolTCP {
+			continue
+		}
+		container.ReadinessProbe = &core.Probe{
+			Handler: core.Handler{TCPSocket: &core.TCPSocketAction{Port: intstr.FromInt(int(port.ContainerPort))}},
+		}
+		logger.Debugf("Added a TCP readiness probe on the port %d", port.ContainerPort)
+		return
+	}
+	logger.Debugf("Not adding a TCP readiness probe since there are no TCP ports")
+}
+
+// addPodDisruptionBudget keeps at least one pod of the service available if it has a typical HTTP port
+func addPodDisruptionBudget(logger *logrus.Entry, irService *irtypes.Service) {
+	for _, forwarding := range irService.ServiceToPodPortForwardings {
+		if !common.IsIntPresent(httpPorts, int(forwarding.ServicePort.Number)) {
+			continue
+		}
+		minAvailable := intstr.FromInt(defaultMinAvailable)
+		irService.MinAvailable = &minAvailable
+		logger.Debugf("Added a PodDisruptionBudget with minAvailable %d since the service has the HTTP port %d", defaultMinAvailable, forwarding.ServicePort.Number)
+		return
+	}
+	logger.Debugf("Not adding a PodDisruptionBudget since there are no HTTP ports")
 }
 
-func (t *DockerfileParser) getDockerFileAST(path string) (*dockerparser.Result, error) {
+// makeNodePortService marks the service as a NodePort service and optionally assigns the node ports
+func (t *DockerfileParser) makeNodePortService(logger *logrus.Entry, irService *irtypes.Service) {
+	irService.ServiceType = core.ServiceTypeNodePort
+	if !t.DockerfileParserConfig.AssignNodePorts {
+		return
+	}
+	for i, forwarding := range irService.ServiceToPodPortForwardings {
+		nodePort := t.getFreeNodePort(forwarding.ServicePort.Number)
+		if nodePort == 0 {
+			logger.Warnf("All the node ports in the range %d-%d are in use. Not assigning a node port for the port %d", minNodePort, maxNodePort, forwarding.ServicePort.Number)
+			continue
+		}
+		irService.ServiceToPodPortForwardings[i].NodePort = nodePort
+		logger.Debugf("Assigned the node port %d to the port %d", nodePort, forwarding.ServicePort.Number)
+	}
+}
+
+// getFreeNodePort returns an unused node port derived from the port.
+// Ports already in the node port range are used as is if free. Otherwise the next free node port is used.
+// It returns 0 if all the node ports are in use.
+func (t *DockerfileParser) getFreeNodePort(port int32) int32 {
+	t.usedNodePortsMutex.Lock()
+	defer t.usedNodePortsMutex.Unlock()
+	if t.usedNodePorts == nil {
+		t.usedNodePorts = map[int32]bool{}
+	}
+	rangeSize := int32(maxNodePort - minNodePort + 1)
+	start := port
+	if start < minNodePort || start > maxNodePort {
+		start = minNodePort + port%rangeSize
+	}
+	for i := int32(0); i < rangeSize; i++ {
+		nodePort := minNodePort + (start-minNodePort+i)%rangeSize
+		if !t.usedNodePorts[nodePort] {
+			t.usedNodePorts[nodePort] = true
+			return nodePort
+		}
+	}
+	return 0
+}
+
+// getImageNameWithRegistry prefixes the image name with the registry specified in the transformer config
+func (t *DockerfileParser) getImageNameWithRegistry(imageName string) string {
+	registry := strings.TrimSuffix(t.DockerfileParserConfig.Registry, "/")
+	if registry == "" || strings.HasPrefix(imageName, registry+"/") {
+		return imageName
+	}
+	return registry + "/" + imageName
+}
+
+// getImagePullPolicy returns the image pull policy specified in the transformer config
+func (t *DockerfileParser) getImagePullPolicy(logger *logrus.Entry) core.PullPolicy {
+	if t.DockerfileParserConfig.ImagePullPolicy == "" {
+		return ""
+	}
+	for _, pullPolicy := range []core.PullPolicy{core.PullAlways, core.PullIfNotPresent, core.PullNever} {
+		if strings.EqualFold(t.DockerfileParserConfig.ImagePullPolicy, string(pullPolicy)) {
+			return pullPolicy
+		}
+	}
+	logger.Errorf("The image pull policy %s in the config of the transformer %s is invalid. Valid pull policies are: %s, %s and %s", t.DockerfileParserConfig.ImagePullPolicy, t.TConfig.Name, core.PullAlways, core.PullIfNotPresent, core.PullNever)
+	return ""
+}
+
+// getDockerignorePatterns returns the patterns in the .dockerignore file at the root of the build context.
+// If the build context doesn't have one, the .dockerignore file next to the dockerfile is used.
+// If neither exists, no patterns are returned and all the files are included.
+func getDockerignorePatterns(logger *logrus.Entry, contextPath, dockerfileDir string) ([]string, error) {
+	for _, dir := range []string{contextPath, dockerfileDir} {
+		dockerignorePath := filepath.Join(dir, common.DockerignoreFilename)
+		f, err := os.Open(dockerignorePath)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, fmt.Errorf("failed to open the .dockerignore file at path %s . Error: %q", dockerignorePath, err)
+		}
+		defer f.Close()
+		patterns, err := dockerignore.ReadAll(f)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse the .dockerignore file at path %s . Error: %q", dockerignorePath, err)
+		}
+		logger.Debugf("Found the .dockerignore file at path %s with the patterns %+v", dockerignorePath, patterns)
+		return patterns, nil
+	}
+	return nil, nil
+}
+
+// getResourceRequirements returns the default resource requests and limits specified in the transformer config
+func (t *DockerfileParser) getResourceRequirements(logger *logrus.Entry) core.ResourceRequirements {
+	resources := core.ResourceRequirements{}
+	addQuantity := func(list *core.ResourceList, name core.ResourceName, value string) {
+		if value == "" {
+			return
+		}
+		quantity, err := resource.ParseQuantity(value)
+		if err != nil {
+			logger.Errorf("Unable to parse the %s quantity %s in the config of the transformer %s . Error: %q", name, value, t.TConfig.Name, err)
+			return
+		}
+		if *list == nil {
+			*list = core.ResourceList{}
+		}
+		(*list)[name] = quantity
+	}
+	addQuantity(&resources.Requests, core.ResourceCPU, t.DockerfileParserConfig.CPURequest)
+	addQuantity(&resources.Requests, core.ResourceMemory, t.DockerfileParserConfig.MemoryRequest)
+	addQuantity(&resources.Limits, core.ResourceCPU, t.DockerfileParserConfig.CPULimit)
+	addQuantity(&resources.Limits, core.ResourceMemory, t.DockerfileParserConfig.MemoryLimit)
+	if len(resources.Requests) == 0 && len(resources.Limits) == 0 {
+		logger.Debugf("No resource requests or limits were applied. They can be set using cpuRequest, cpuLimit, memoryRequest and memoryLimit in the config of the transformer %s", t.TConfig.Name)
+	}
+	return resources
+}
+
+// handlePrivilegedPorts warns about exposed ports in the privileged range and optionally adds the NET_BIND_SERVICE capability
+func (t *DockerfileParser) handlePrivilegedPorts(logger *logrus.Entry, container *core.Container, dockerfilepath string) {
+	privilegedPorts := []string{}
+	for _, port := range container.Ports {
+		if port.ContainerPort < privilegedPortLimit {
+			privilegedPorts = append(privilegedPorts, strconv.Itoa(int(port.ContainerPort)))
+		}
+	}
+	if len(privilegedPorts) == 0 {
+		return
+	}
+	logger.Warnf("The Dockerfile %s exposes the privileged ports %s . The container might fail to start on Kubernetes unless it runs as root or has the NET_BIND_SERVICE capability in its securityContext. Consider using ports above %d instead.",
+		dockerfilepath, strings.Join(privilegedPorts, ", "), privilegedPortLimit-1)
+	if !t.DockerfileParserConfig.AddNetBindServiceCapability {
+		logger.Debugf("The NET_BIND_SERVICE capability can be added by setting addNetBindServiceCapability in the config of the transformer %s", t.TConfig.Name)
+		return
+	}
+	if container.SecurityContext == nil {
+		container.SecurityContext = &core.SecurityContext{}
+	}
+	if container.SecurityContext.Capabilities == nil {
+		container.SecurityContext.Capabilities = &core.Capabilities{}
+	}
+	for _, capability := range container.SecurityContext.Capabilities.Add {
+		if capability == netBindServiceCapability {
+			return
+		}
+	}
+	container.SecurityContext.Capabilities.Add = append(container.SecurityContext.Capabilities.Add, netBindServiceCapability)
+}
+
+// parseExposedPort parses a port like 8080 or 53/udp specified in an EXPOSE instruction
+func parseExposedPort(exposedPort string) (int, core.Protocol, error) {
+	parts := strings.SplitN(exposedPort, "/", 2)
+	port, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return 0, "", fmt.Errorf("failed to parse the port %s as an integer. Error: %q", parts[0], err)
+	}
+	if err := validatePort(port); err != nil {
+		return 0, "", err
+	}
+	if len(parts) == 1 {
+		return port, core.ProtocolTCP, nil
+	}
+	switch protocol := core.Protocol(strings.ToUpper(parts[1])); protocol {
+	case core.ProtocolTCP, core.ProtocolUDP, core.ProtocolSCTP:
+		return port, protocol, nil
+	default:
+		return 0, "", fmt.Errorf("the protocol %s is not supported", parts[1])
+	}
+}
+
+// validatePort returns an error if the port can't be used as a container port
+func validatePort(port int) error {
+	if port < minPort || port > maxPort {
+		return fmt.Errorf("the port %d is not in the range %d-%d", port, minPort, maxPort)
+	}
+	return nil
+}
+
+// makePortNamesUnique renames the container ports that have the same name as an earlier port by adding a numeric suffix.
+// The names are kept within the maximum length of a port name. Unnamed ports are left as is.
+func makePortNamesUnique(logger *logrus.Entry, ports []core.ContainerPort) {
+	usedNames := map[string]bool{}
+	for i, port := range ports {
+		if port.Name == "" {
+			continue
+		}
+		name := port.Name
+		for suffix := 2; usedNames[name]; suffix++ {
+			suffixStr := "-" + strconv.Itoa(suffix)
+			base := port.Name
+			if len(base)+len(suffixStr) > maxPortNameLength {
+				base = strings.TrimRight(base[:maxPortNameLength-len(suffixStr)], "-")
+			}
+			name = base + suffixStr
+		}
+		if name != port.Name {
+			logger.Warnf("Renamed the port %d from %s to %s since the port names must be unique", port.ContainerPort, port.Name, name)
+			ports[i].Name = name
+		}
+		usedNames[name] = true
+	}
+}
+
+// getBaseImage returns the image used by the FROM instruction.
+// ref is the image reference with the global build args resolved.
+// previous contains the images of the earlier stages so that references to them can be detected.
+func getBaseImage(fromNode *dockerparser.Node, ref string, previous []artifacts.DockerfileBaseImage) artifacts.DockerfileBaseImage {
+	baseImage := artifacts.DockerfileBaseImage{Reference: ref}
+	if asNode := fromNode.Next.Next; asNode != nil && strings.EqualFold(asNode.Value, "as") && asNode.Next != nil {
+		baseImage.Stage = asNode.Next.Value
+	}
+	for _, prev := range previous {
+		if prev.Stage != "" && strings.EqualFold(prev.Stage, baseImage.Reference) {
+			baseImage.Repository = baseImage.Reference
+			baseImage.FromStage = true
+			return baseImage
+		}
+	}
+	baseImage.Repository, baseImage.Tag, baseImage.Digest = parseImageReference(baseImage.Reference)
+	return baseImage
+}
+
+// parseImageReference splits an image reference like quay.io/org/app:1.0@sha256:abcd into the repository, tag and digest
+func parseImageReference(ref string) (repository, tag, digest string) {
+	repository = ref
+	if idx := strings.Index(repository, "@"); idx != -1 {
+		repository, digest = repository[:idx], repository[idx+1:]
+	}
+	// the colon of a tag comes after the last slash. A colon before it separates the registry host and port.
+	if idx := strings.LastIndex(repository, ":"); idx != -1 && idx > strings.LastIndex(repository, "/") {
+		repository, tag = repository[:idx], repository[idx+1:]
+	}
+	return repository, tag, digest
+}
+
+// getOnbuildTriggers returns the instructions wrapped by the ONBUILD instructions in the final stage of the dockerfile
+func getOnbuildTriggers(ast *dockerparser.Node) []*dockerparser.Node {
+	triggers := []*dockerparser.Node{}
+	for _, dfchild := range ast.Children {
+		switch dfchild.Value {
+		case "from":
+			triggers = []*dockerparser.Node{}
+		case "onbuild":
+			if dfchild.Next != nil && len(dfchild.Next.Children) > 0 {
+				triggers = append(triggers, dfchild.Next.Children[0])
+			}
+		}
+	}
+	return triggers
+}
+
+// getNodeArgs returns the arguments of a dockerfile instruction
+func getNodeArgs(node *dockerparser.Node) []string {
+	args := []string{}
+	for n := node.Next; n != nil; n = n.Next {
+		args = append(args, n.Value)
+	}
+	return args
+}
+
+// getCommandFromNode returns the command specified by a CMD or ENTRYPOINT instruction.
+// Commands in shell form are wrapped using the shell specified by the SHELL instruction.
+// If there is no SHELL instruction, the default shell for the platform is used.
+func getCommandFromNode(node *dockerparser.Node, shell []string, isWindows bool) []string {
+	args := getNodeArgs(node)
+	if node.Attributes["json"] || len(args) == 0 {
+		return args
+	}
+	if len(shell) == 0 {
+		shell = getDefaultShell(isWindows)
+	}
+	return append(append([]string{}, shell...), strings.Join(args, " "))
+}
+
+// getDefaultShell returns the shell used by shell form commands when there is no SHELL instruction
+func getDefaultShell(isWindows bool) []string {
+	if isWindows {
+		return []string{"cmd", "/S", "/C"}
+	}
+	return []string{"/bin/sh", "-c"}
+}
+
+// isWindowsContainer returns true if the FROM instruction refers to a Windows base image
+func isWindowsContainer(fromNode *dockerparser.Node, baseImage string) bool {
+	for _, flag := range fromNode.Flags {
+		if strings.HasPrefix(strings.ToLower(flag), "--platform=windows") {
+			return true
+		}
+	}
+	return windowsImageRegex.MatchString(baseImage)
+}
+
+// getPlatformFlag returns the value of the --platform flag of the FROM instruction
+func getPlatformFlag(fromNode *dockerparser.Node) string {
+	for _, flag := range fromNode.Flags {
+		if strings.HasPrefix(strings.ToLower(flag), platformFlagPrefix) {
+			return flag[len(platformFlagPrefix):]
+		}
+	}
+	return ""
+}
+
+// getPlatformArch returns the architecture of a platform like linux/arm64/v8 in the form used by the kubernetes.io/arch label.
+// The variant is ignored since there is no well known node label for it.
+// It returns an empty string if the platform doesn't specify an architecture or still refers to build args like $TARGETPLATFORM.
+func getPlatformArch(platform string) string {
+	if platform == "" || strings.Contains(platform, "$") {
+		return ""
+	}
+	parts := strings.Split(strings.ToLower(platform), "/")
+	if len(parts) < 2 {
+		return ""
+	}
+	switch arch := parts[1]; arch {
+	case "x86_64", "x86-64":
+		return "amd64"
+	case "aarch64":
+		return "arm64"
+	default:
+		return arch
+	}
+}
+
+// resolveBuildArgs replaces the build args like $BASE and ${BASE} in the word with their values.
+// Build args without a value are replaced by an empty string, the same as docker build does.
+// The word is returned as is if it can't be resolved.
+func resolveBuildArgs(logger *logrus.Entry, escapeToken rune, word string, buildArgs map[string]string) string {
+	if !strings.Contains(word, "$") {
+		return word
+	}
+	resolved, err := dockershell.NewLex(escapeToken).ProcessWordWithMap(word, buildArgs)
+	if err != nil {
+		logger.Warnf("Unable to resolve the build args in %s : %s", word, err)
+		return word
+	}
+	if resolved == "" {
+		logger.Warnf("The build args in %s resolve to an empty string. Using it as is.", word)
+		return word
+	}
+	logger.Debugf("Resolved %s to %s using the global build args", word, resolved)
+	return resolved
+}
+
+// getBuildArgs returns the build arguments declared by an ARG instruction
+func getBuildArgs(node *dockerparser.Node) []artifacts.DockerfileBuildArg {
+	buildArgs := []artifacts.DockerfileBuildArg{}
+	for _, arg := range getNodeArgs(node) {
+		parts := strings.SplitN(arg, "=", 2)
+		buildArg := artifacts.DockerfileBuildArg{Name: parts[0]}
+		if len(parts) == 2 {
+			defaultValue := common.StripQuotes(parts[1])
+			buildArg.Default = &defaultValue
+		}
+		buildArgs = append(buildArgs, buildArg)
+	}
+	return buildArgs
+}
+
+// getLabels returns the key value pairs set by a LABEL instruction
+func getLabels(node *dockerparser.Node) map[string]string {
+	labels := map[string]string{}
+	for n := node.Next; n != nil && n.Next != nil; n = n.Next.Next {
+		labels[common.StripQuotes(n.Value)] = common.StripQuotes(n.Next.Value)
+	}
+	return labels
+}
+
+// getServiceNameFromLabels returns the service name set using the service name label.
+// The name is made a valid DNS label. If the label is not set, the given service name is returned.
+func getServiceNameFromLabels(logger *logrus.Entry, labels map[string]string, serviceName string) string {
+	labelServiceName := strings.TrimSpace(labels[common.ServiceNameLabel])
+	if labelServiceName == "" {
+		return serviceName
+	}
+	labelServiceName = common.MakeStringDNSLabelNameCompliant(labelServiceName)
+	logger.Infof("Using the service name %s from the label %s instead of %s", labelServiceName, common.ServiceNameLabel, serviceName)
+	return labelServiceName
+}
+
+// getPortNamesFromLabels returns the port names set using the port names label.
+// The label value is a comma separated list of port=name pairs. Example: 8080=http,9090=metrics
+// Invalid pairs are skipped.
+func getPortNamesFromLabels(logger *logrus.Entry, labels map[string]string) map[int]string {
+	portNames := map[int]string{}
+	for _, pair := range strings.Split(labels[common.PortNamesLabel], ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		parts := strings.SplitN(pair, "=", 2)
+		if len(parts) != 2 {
+			logger.Warnf("Ignoring the entry %s in the label %s since it is not of the form port=name", pair, common.PortNamesLabel)
+			continue
+		}
+		port, err := strconv.Atoi(strings.TrimSpace(parts[0]))
+		if err != nil {
+			logger.Warnf("Ignoring the entry %s in the label %s since the port is not a number. Error: %q", pair, common.PortNamesLabel, err)
+			continue
+		}
+		if err := validatePort(port); err != nil {
+			logger.Warnf("Ignoring the entry %s in the label %s . Error: %q", pair, common.PortNamesLabel, err)
+			continue
+		}
+		name := strings.TrimSpace(parts[1])
+		if errs := validation.IsValidPortName(name); len(errs) > 0 {
+			logger.Warnf("Ignoring the entry %s in the label %s since the port name is not valid: %s", pair, common.PortNamesLabel, strings.Join(errs, ", "))
+			continue
+		}
+		portNames[port] = name
+	}
+	return portNames
+}
+
+// getCopySources returns the paths in the build context used by a COPY or ADD instruction.
+// Copies from other stages and remote URLs are ignored. Wildcards are replaced by the directory containing them.
+func getCopySources(node *dockerparser.Node) []string {
+	for _, flag := range node.Flags {
+		if strings.HasPrefix(flag, "--from") {
+			return nil
+		}
+	}
+	args := []string{}
+	for n := node.Next; n != nil; n = n.Next {
+		args = append(args, n.Value)
+	}
+	if len(args) < 2 {
+		return nil
+	}
+	sources := []string{}
+	// the last argument is the destination
+	for _, src := range args[:len(args)-1] {
+		// heredocs like COPY <<EOF /app/config are inline files and not paths in the build context
+		if strings.Contains(src, "://") || strings.HasPrefix(src, "git@") || strings.HasPrefix(src, "<<") {
+			continue
+		}
+		if idx := strings.IndexAny(src, "*?["); idx != -1 {
+			src = filepath.Dir(src[:idx])
+		}
+		sources = common.MergeStringSlices(sources, filepath.Clean(src))
+	}
+	return sources
+}
+
+func getDockerFileAST(logger *logrus.Entry, path string) (*dockerparser.Result, error) {
 	f, err := os.Open(path)
 	if err != nil {
-		logrus.Debugf("Unable to open file %s : %s", path, err)
+		logger.Debugf("Unable to open file %s : %s", path, err)
 		return nil, err
 	}
 	defer f.Close()
-	res, err := dockerparser.Parse(f)
+	dfBytes, err := ioutil.ReadAll(f)
+	if err != nil {
+		logger.Debugf("Unable to read file %s : %s", path, err)
+		return nil, err
+	}
+	if syntax := getSyntaxDirective(string(dfBytes)); syntax != "" {
+		logger.Debugf("The dockerfile %s uses the syntax %s", path, syntax)
+	}
+	// the parser doesn't understand heredocs, so their bodies would be read as instructions
+	res, err := parsePartially(logger, path, removeHeredocBodies(string(dfBytes)))
 	if err != nil {
-		logrus.Debugf("Unable to parse file %s as Docker files : %s", path, err)
+		logger.Debugf("Unable to parse file %s as Docker files : %s", path, err)
 	}
 	return res, err
 }
+
+// parsePartially parses the dockerfile, skipping the instructions that fail to parse.
+// It fails if the dockerfile can't be read or if none of the instructions can be parsed.
+func parsePartially(logger *logrus.Entry, path, dockerfile string) (*dockerparser.Result, error) {
+	lines := strings.Split(dockerfile, "\n")
+	skipped := []string{}
+	for attempt := 0; attempt < maxSkippedDockerfileInstructions; attempt++ {
+		res, err := dockerparser.Parse(strings.NewReader(strings.Join(lines, "\n")))
+		if err == nil || (res != nil && len(res.AST.Children) > 0) {
+			if err != nil {
+				// the instructions before the error were parsed. Example: a line that is too long
+				skipped = append(skipped, err.Error())
+			}
+			if len(skipped) > 0 {
+				logger.Warnf("The dockerfile %s was only partially parsed. Skipped the instructions that failed to parse : %s", path, strings.Join(skipped, " ; "))
+			}
+			return res, nil
+		}
+		var errLocation *dockerparser.ErrorLocation
+		if !errors.As(err, &errLocation) || len(errLocation.Location) == 0 {
+			return nil, err
+		}
+		start, end := errLocation.Location[0].Start.Line, errLocation.Location[len(errLocation.Location)-1].End.Line
+		if start < 1 || end > len(lines) || start > end {
+			return nil, err
+		}
+		removed := false
+		for i := start - 1; i < end; i++ {
+			removed = removed || strings.TrimSpace(lines[i]) != ""
+			lines[i] = ""
+		}
+		if !removed {
+			// the error isn't caused by an instruction. Example: a file with no instructions
+			return nil, err
+		}
+		skipped = append(skipped, fmt.Sprintf("line %d: %s", start, err))
+	}
+	return nil, fmt.Errorf("more than %d instructions failed to parse", maxSkippedDockerfileInstructions)
+}
+
+// getSyntaxDirective returns the frontend image from a directive like # syntax=docker/dockerfile:1 at the start of the dockerfile
+func getSyntaxDirective(dockerfile string) string {
+	for _, line := range strings.Split(dockerfile, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		// directives must come before any other comment or instruction
+		matches := syntaxDirectiveRegex.FindStringSubmatch(line)
+		if matches == nil {
+			if strings.HasPrefix(line, "#") && strings.Contains(line, "=") {
+				continue
+			}
+			return ""
+		}
+		return matches[1]
+	}
+	return ""
+}
+
+// removeHeredocBodies replaces the bodies of the heredocs like RUN <<EOF ... EOF with empty lines.
+// The line numbers are preserved and the heredoc markers are left in the instructions.
+func removeHeredocBodies(dockerfile string) string {
+	lines := strings.Split(dockerfile, "\n")
+	isContinued := false
+	instruction := ""
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		trimmed := strings.TrimSpace(line)
+		if !isContinued {
+			if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+				continue
+			}
+			instruction = strings.ToLower(strings.Fields(trimmed)[0])
+		}
+		isContinued = strings.HasSuffix(trimmed, "\\")
+		if instruction != "run" && instruction != "copy" && instruction != "add" {
+			continue
+		}
+		// the bodies of multiple heredocs on the same line follow one after the other
+		for _, marker := range heredocMarkerRegex.FindAllStringSubmatch(line, -1) {
+			stripTabs, delimiter := marker[2] == "-", marker[4]
+			end := -1
+			for j := i + 1; j < len(lines); j++ {
+				body := strings.TrimRight(lines[j], "\r")
+				if stripTabs {
+					body = strings.TrimLeft(body, "\t")
+				}
+				if body == delimiter {
+					end = j
+					break
+				}
+			}
+			if end == -1 {
+				// not a heredoc since it is never terminated. Example: RUN echo "a<<b"
+				continue
+			}
+			for i < end {
+				i++
+				lines[i] = ""
+			}
+		}
+	}
+	return strings.Join(lines, "\n")
+}
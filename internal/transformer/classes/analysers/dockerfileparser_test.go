@@ -0,0 +1,1267 @@
+/*
+ *  Copyright IBM Corporation 2021
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package analysers
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/konveyor/move2kube/environment"
+	"github.com/konveyor/move2kube/internal/common"
+	"github.com/konveyor/move2kube/qaengine"
+	irtypes "github.com/konveyor/move2kube/types/ir"
+	transformertypes "github.com/konveyor/move2kube/types/transformer"
+	"github.com/konveyor/move2kube/types/transformer/artifacts"
+	dockerparser "github.com/moby/buildkit/frontend/dockerfile/parser"
+	"github.com/sirupsen/logrus"
+	core "k8s.io/kubernetes/pkg/apis/core"
+)
+
+func writeDockerfile(t *testing.T, contents string) string {
+	t.Helper()
+	dockerfilePath := filepath.Join(t.TempDir(), common.DefaultDockerfileName)
+	if err := ioutil.WriteFile(dockerfilePath, []byte(contents), common.DefaultFilePermission); err != nil {
+		t.Fatalf("failed to write the Dockerfile at path %s . Error: %q", dockerfilePath, err)
+	}
+	return dockerfilePath
+}
+
+func getIRFromArtifact(t *testing.T, dockerfilePath, contextPath string) irtypes.IR {
+	t.Helper()
+	parser := DockerfileParser{Env: &environment.Environment{ProjectName: "myproject"}}
+	a := parser.getIRFromDockerfile(dockerfilePath, contextPath, "myimage", "mysvc")
+	if a == nil {
+		t.Fatalf("failed to get the IR from the Dockerfile at path %s", dockerfilePath)
+	}
+	ir, ok := a.Configs[irtypes.IRConfigType].(irtypes.IR)
+	if !ok {
+		t.Fatalf("expected the artifact to contain an IR. Actual: %+v", a.Configs)
+	}
+	return ir
+}
+
+func TestBuildContextPath(t *testing.T) {
+	t.Run("context path defaults to the directory containing the Dockerfile", func(t *testing.T) {
+		dockerfilePath := writeDockerfile(t, "FROM alpine\nEXPOSE 8080\n")
+		ir := getIRFromArtifact(t, dockerfilePath, "")
+		build := ir.ContainerImages["myimage"].Build
+		fi, err := os.Stat(build.ContextPath)
+		if err != nil {
+			t.Fatalf("failed to stat the context path %s . Error: %q", build.ContextPath, err)
+		}
+		if !fi.IsDir() {
+			t.Fatalf("expected the context path %s to be a directory", build.ContextPath)
+		}
+		if build.ContextPath != filepath.Dir(dockerfilePath) {
+			t.Fatalf("expected the context path to be %s . Actual: %s", filepath.Dir(dockerfilePath), build.ContextPath)
+		}
+		if build.GetDockerfilePath() != dockerfilePath {
+			t.Fatalf("expected the Dockerfile path to be %s . Actual: %s", dockerfilePath, build.GetDockerfilePath())
+		}
+	})
+	t.Run("context path is taken from the project path", func(t *testing.T) {
+		dockerfilePath := writeDockerfile(t, "FROM alpine\nEXPOSE 8080\n")
+		contextPath := t.TempDir()
+		ir := getIRFromArtifact(t, dockerfilePath, contextPath)
+		build := ir.ContainerImages["myimage"].Build
+		if build.ContextPath != contextPath {
+			t.Fatalf("expected the context path to be %s . Actual: %s", contextPath, build.ContextPath)
+		}
+		if build.GetDockerfilePath() != dockerfilePath {
+			t.Fatalf("expected the Dockerfile path to be %s . Actual: %s", dockerfilePath, build.GetDockerfilePath())
+		}
+	})
+}
+
+func TestCommandAndArgs(t *testing.T) {
+	testcases := []struct {
+		name        string
+		dockerfile  string
+		wantCommand []string
+		wantArgs    []string
+	}{
+		{
+			name:       "shell form cmd on linux",
+			dockerfile: "FROM alpine\nCMD node server.js\n",
+			wantArgs:   []string{"/bin/sh", "-c", "node server.js"},
+		},
+		{
+			name:       "exec form cmd",
+			dockerfile: "FROM alpine\nCMD [\"node\", \"server.js\"]\n",
+			wantArgs:   []string{"node", "server.js"},
+		},
+		{
+			name:       "shell form cmd on windows",
+			dockerfile: "FROM mcr.microsoft.com/windows/servercore:ltsc2019\nCMD app.exe --serve\n",
+			wantArgs:   []string{"cmd", "/S", "/C", "app.exe --serve"},
+		},
+		{
+			name:        "shell form entrypoint with a custom shell",
+			dockerfile:  "FROM mcr.microsoft.com/windows/servercore:ltsc2019\nSHELL [\"powershell\", \"-Command\"]\nENTRYPOINT ./app.ps1\nCMD [\"ignored\"]\n",
+			wantCommand: []string{"powershell", "-Command", "./app.ps1"},
+		},
+		{
+			name:        "exec form entrypoint and cmd",
+			dockerfile:  "FROM golang AS builder\nCMD [\"ignored\"]\nFROM alpine\nENTRYPOINT [\"/app\"]\nCMD [\"--port\", \"8080\"]\n",
+			wantCommand: []string{"/app"},
+			wantArgs:    []string{"--port", "8080"},
+		},
+	}
+	for _, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			ir := getIRFromArtifact(t, writeDockerfile(t, testcase.dockerfile), "")
+			containers := ir.Services["mysvc"].Containers
+			if len(containers) != 1 {
+				t.Fatalf("expected a single container. Actual: %+v", containers)
+			}
+			if !cmp.Equal(containers[0].Command, testcase.wantCommand, cmpopts.EquateEmpty()) {
+				t.Fatalf("failed to get the command. Differences:\n%s", cmp.Diff(testcase.wantCommand, containers[0].Command))
+			}
+			if !cmp.Equal(containers[0].Args, testcase.wantArgs, cmpopts.EquateEmpty()) {
+				t.Fatalf("failed to get the args. Differences:\n%s", cmp.Diff(testcase.wantArgs, containers[0].Args))
+			}
+		})
+	}
+}
+
+func TestBuildArgs(t *testing.T) {
+	dockerfilePath := writeDockerfile(t, "ARG BASE=alpine\nFROM ${BASE}\nARG VERSION\nARG GREETING=\"hello world\"\nEXPOSE 8080\n")
+	parser := DockerfileParser{Env: &environment.Environment{ProjectName: "myproject"}}
+	a := parser.getIRFromDockerfile(dockerfilePath, "", "myimage", "mysvc")
+	if a == nil {
+		t.Fatalf("failed to get the IR from the Dockerfile at path %s", dockerfilePath)
+	}
+	dfMetadata := artifacts.DockerfileMetadataConfig{}
+	if err := a.GetConfig(artifacts.DockerfileMetadataConfigType, &dfMetadata); err != nil {
+		t.Fatalf("failed to get the Dockerfile metadata from the artifact. Error: %q", err)
+	}
+	base, greeting := "alpine", "hello world"
+	want := []artifacts.DockerfileBuildArg{{Name: "BASE", Default: &base, Global: true}, {Name: "VERSION"}, {Name: "GREETING", Default: &greeting}}
+	if !cmp.Equal(dfMetadata.BuildArgs, want) {
+		t.Fatalf("failed to get the build args. Differences:\n%s", cmp.Diff(want, dfMetadata.BuildArgs))
+	}
+}
+
+func TestDockerignore(t *testing.T) {
+	getIgnorePatterns := func(t *testing.T, dockerfilePath string) []string {
+		parser := DockerfileParser{Env: &environment.Environment{ProjectName: "myproject"}}
+		a := parser.getIRFromDockerfile(dockerfilePath, "", "myimage", "mysvc")
+		if a == nil {
+			t.Fatalf("failed to get the IR from the Dockerfile at path %s", dockerfilePath)
+		}
+		dfMetadata := artifacts.DockerfileMetadataConfig{}
+		if err := a.GetConfig(artifacts.DockerfileMetadataConfigType, &dfMetadata); err != nil {
+			t.Fatalf("failed to get the Dockerfile metadata from the artifact. Error: %q", err)
+		}
+		return dfMetadata.IgnorePatterns
+	}
+	t.Run("no .dockerignore", func(t *testing.T) {
+		if patterns := getIgnorePatterns(t, writeDockerfile(t, "FROM alpine\n")); len(patterns) != 0 {
+			t.Fatalf("expected no ignore patterns. Actual: %+v", patterns)
+		}
+	})
+	t.Run(".dockerignore next to the Dockerfile", func(t *testing.T) {
+		dockerfilePath := writeDockerfile(t, "FROM alpine\n")
+		dockerignorePath := filepath.Join(filepath.Dir(dockerfilePath), common.DockerignoreFilename)
+		if err := ioutil.WriteFile(dockerignorePath, []byte("# comment\n*.md\n!README.md\n\n/tmp/\n"), common.DefaultFilePermission); err != nil {
+			t.Fatalf("failed to write the .dockerignore file. Error: %q", err)
+		}
+		want := []string{"*.md", "!README.md", "tmp"}
+		if patterns := getIgnorePatterns(t, dockerfilePath); !cmp.Equal(patterns, want) {
+			t.Fatalf("failed to get the ignore patterns. Differences:\n%s", cmp.Diff(want, patterns))
+		}
+	})
+}
+
+func TestResourceRequirements(t *testing.T) {
+	dockerfilePath := writeDockerfile(t, "FROM alpine\nEXPOSE 8080\n")
+	t.Run("no resources when the config is empty", func(t *testing.T) {
+		ir := getIRFromArtifact(t, dockerfilePath, "")
+		resources := ir.Services["mysvc"].Containers[0].Resources
+		if len(resources.Requests) != 0 || len(resources.Limits) != 0 {
+			t.Fatalf("expected no resource requests or limits. Actual: %+v", resources)
+		}
+	})
+	t.Run("resources are taken from the config", func(t *testing.T) {
+		parser := DockerfileParser{
+			Env:                    &environment.Environment{ProjectName: "myproject"},
+			DockerfileParserConfig: DockerfileParserYamlConfig{CPURequest: "100m", MemoryLimit: "512Mi", CPULimit: "invalid"},
+		}
+		a := parser.getIRFromDockerfile(dockerfilePath, "", "myimage", "mysvc")
+		if a == nil {
+			t.Fatalf("failed to get the IR from the Dockerfile at path %s", dockerfilePath)
+		}
+		resources := a.Configs[irtypes.IRConfigType].(irtypes.IR).Services["mysvc"].Containers[0].Resources
+		if cpu := resources.Requests[core.ResourceCPU]; cpu.String() != "100m" {
+			t.Fatalf("expected the cpu request to be 100m . Actual: %s", cpu.String())
+		}
+		if memory := resources.Limits[core.ResourceMemory]; memory.String() != "512Mi" {
+			t.Fatalf("expected the memory limit to be 512Mi . Actual: %s", memory.String())
+		}
+		if _, ok := resources.Limits[core.ResourceCPU]; ok {
+			t.Fatalf("expected the invalid cpu limit to be ignored. Actual: %+v", resources.Limits)
+		}
+	})
+}
+
+func TestPrivilegedPorts(t *testing.T) {
+	t.Run("no capability is added by default", func(t *testing.T) {
+		ir := getIRFromArtifact(t, writeDockerfile(t, "FROM nginx\nEXPOSE 80\n"), "")
+		if securityContext := ir.Services["mysvc"].Containers[0].SecurityContext; securityContext != nil {
+			t.Fatalf("expected no security context. Actual: %+v", securityContext)
+		}
+	})
+	t.Run("capability is added for privileged ports when enabled", func(t *testing.T) {
+		parser := DockerfileParser{
+			Env:                    &environment.Environment{ProjectName: "myproject"},
+			DockerfileParserConfig: DockerfileParserYamlConfig{AddNetBindServiceCapability: true},
+		}
+		a := parser.getIRFromDockerfile(writeDockerfile(t, "FROM nginx\nEXPOSE 80 443\n"), "", "myimage", "mysvc")
+		if a == nil {
+			t.Fatalf("failed to get the IR from the Dockerfile")
+		}
+		securityContext := a.Configs[irtypes.IRConfigType].(irtypes.IR).Services["mysvc"].Containers[0].SecurityContext
+		if securityContext == nil || securityContext.Capabilities == nil || !reflect.DeepEqual(securityContext.Capabilities.Add, []core.Capability{"NET_BIND_SERVICE"}) {
+			t.Fatalf("expected the NET_BIND_SERVICE capability to be added once. Actual: %+v", securityContext)
+		}
+	})
+	t.Run("capability is not added for unprivileged ports", func(t *testing.T) {
+		parser := DockerfileParser{
+			Env:                    &environment.Environment{ProjectName: "myproject"},
+			DockerfileParserConfig: DockerfileParserYamlConfig{AddNetBindServiceCapability: true},
+		}
+		a := parser.getIRFromDockerfile(writeDockerfile(t, "FROM nginx\nEXPOSE 8080\n"), "", "myimage", "mysvc")
+		if a == nil {
+			t.Fatalf("failed to get the IR from the Dockerfile")
+		}
+		if securityContext := a.Configs[irtypes.IRConfigType].(irtypes.IR).Services["mysvc"].Containers[0].SecurityContext; securityContext != nil {
+			t.Fatalf("expected no security context. Actual: %+v", securityContext)
+		}
+	})
+}
+
+func TestRegistryAndPullPolicy(t *testing.T) {
+	dockerfilePath := writeDockerfile(t, "FROM alpine\nEXPOSE 8080\n")
+	t.Run("image name and pull policy are unchanged by default", func(t *testing.T) {
+		ir := getIRFromArtifact(t, dockerfilePath, "")
+		container := ir.Services["mysvc"].Containers[0]
+		if container.Image != "myimage" || container.ImagePullPolicy != "" {
+			t.Fatalf("expected the image myimage without a pull policy. Actual: %s %s", container.Image, container.ImagePullPolicy)
+		}
+	})
+	t.Run("registry and pull policy are taken from the config", func(t *testing.T) {
+		parser := DockerfileParser{
+			Env:                    &environment.Environment{ProjectName: "myproject"},
+			DockerfileParserConfig: DockerfileParserYamlConfig{Registry: "quay.io/myorg/", ImagePullPolicy: "always"},
+		}
+		a := parser.getIRFromDockerfile(dockerfilePath, "", "myimage", "mysvc")
+		if a == nil {
+			t.Fatalf("failed to get the IR from the Dockerfile at path %s", dockerfilePath)
+		}
+		ir := a.Configs[irtypes.IRConfigType].(irtypes.IR)
+		container := ir.Services["mysvc"].Containers[0]
+		if container.Image != "quay.io/myorg/myimage" {
+			t.Fatalf("expected the image name to be prefixed with the registry. Actual: %s", container.Image)
+		}
+		if _, ok := ir.ContainerImages["quay.io/myorg/myimage"]; !ok {
+			t.Fatalf("expected the container image to use the prefixed name. Actual: %+v", ir.ContainerImages)
+		}
+		if container.ImagePullPolicy != core.PullAlways {
+			t.Fatalf("expected the pull policy to be %s . Actual: %s", core.PullAlways, container.ImagePullPolicy)
+		}
+	})
+	t.Run("invalid pull policy is ignored", func(t *testing.T) {
+		parser := DockerfileParser{
+			Env:                    &environment.Environment{ProjectName: "myproject"},
+			DockerfileParserConfig: DockerfileParserYamlConfig{ImagePullPolicy: "Sometimes"},
+		}
+		a := parser.getIRFromDockerfile(dockerfilePath, "", "myimage", "mysvc")
+		if a == nil {
+			t.Fatalf("failed to get the IR from the Dockerfile at path %s", dockerfilePath)
+		}
+		if pullPolicy := a.Configs[irtypes.IRConfigType].(irtypes.IR).Services["mysvc"].Containers[0].ImagePullPolicy; pullPolicy != "" {
+			t.Fatalf("expected the invalid pull policy to be ignored. Actual: %s", pullPolicy)
+		}
+	})
+}
+
+func TestIngressPath(t *testing.T) {
+	getService := func(t *testing.T, dockerfile string, addIngressPath bool) irtypes.Service {
+		parser := DockerfileParser{
+			Env:                    &environment.Environment{ProjectName: "myproject"},
+			DockerfileParserConfig: DockerfileParserYamlConfig{AddIngressPath: addIngressPath},
+		}
+		dockerfilePath := writeDockerfile(t, dockerfile)
+		a := parser.getIRFromDockerfile(dockerfilePath, "", "myimage", "mysvc")
+		if a == nil {
+			t.Fatalf("failed to get the IR from the Dockerfile at path %s", dockerfilePath)
+		}
+		return a.Configs[irtypes.IRConfigType].(irtypes.IR).Services["mysvc"]
+	}
+	t.Run("disabled by default", func(t *testing.T) {
+		if service := getService(t, "FROM nginx\nEXPOSE 80\n", false); service.ServiceRelPath != "/mysvc" {
+			t.Fatalf("expected the default ingress path. Actual: %s", service.ServiceRelPath)
+		}
+	})
+	t.Run("http port is exposed on /", func(t *testing.T) {
+		service := getService(t, "FROM nginx\nEXPOSE 9090 8080\n", true)
+		if service.ServiceRelPath != "/" {
+			t.Fatalf("expected the ingress path to be / . Actual: %s", service.ServiceRelPath)
+		}
+		if port := service.ServiceToPodPortForwardings[0].ServicePort.Number; port != 8080 {
+			t.Fatalf("expected the http port to be the primary port. Actual: %d", port)
+		}
+		if len(service.ServiceToPodPortForwardings) != 2 {
+			t.Fatalf("expected both the ports to be forwarded. Actual: %+v", service.ServiceToPodPortForwardings)
+		}
+		if service.Annotations[common.ExposeSelector] != common.AnnotationLabelValue {
+			t.Fatalf("expected the service to be exposed. Actual: %+v", service.Annotations)
+		}
+	})
+	t.Run("non http services are not affected", func(t *testing.T) {
+		if service := getService(t, "FROM redis\nEXPOSE 6379\n", true); service.ServiceRelPath != "/mysvc" {
+			t.Fatalf("expected the default ingress path. Actual: %s", service.ServiceRelPath)
+		}
+	})
+}
+
+func TestOnbuildTriggers(t *testing.T) {
+	baseDockerfilePath := writeDockerfile(t, "FROM node\nEXPOSE 9000\nONBUILD COPY . /app\nONBUILD EXPOSE 3000 5353/udp\n")
+	dockerfilePath := writeDockerfile(t, "FROM mybase:latest\nEXPOSE 8080\nONBUILD EXPOSE 7000\n")
+	getPorts := func(t *testing.T, baseDockerfiles map[string]string) []int {
+		parser := DockerfileParser{
+			Env:                    &environment.Environment{ProjectName: "myproject"},
+			DockerfileParserConfig: DockerfileParserYamlConfig{BaseDockerfiles: baseDockerfiles},
+		}
+		a := parser.getIRFromDockerfile(dockerfilePath, "", "myimage", "mysvc")
+		if a == nil {
+			t.Fatalf("failed to get the IR from the Dockerfile at path %s", dockerfilePath)
+		}
+		return a.Configs[irtypes.IRConfigType].(irtypes.IR).ContainerImages["myimage"].ExposedPorts
+	}
+	t.Run("own ONBUILD instructions are ignored", func(t *testing.T) {
+		if ports := getPorts(t, nil); !cmp.Equal(ports, []int{8080}) {
+			t.Fatalf("expected only the port 8080. Actual: %+v", ports)
+		}
+	})
+	t.Run("ONBUILD triggers of the base image are used", func(t *testing.T) {
+		if ports := getPorts(t, map[string]string{"mybase:latest": baseDockerfilePath}); !cmp.Equal(ports, []int{3000, 5353, 8080}) {
+			t.Fatalf("expected the ports from the ONBUILD triggers. Actual: %+v", ports)
+		}
+	})
+}
+
+func TestParseDockerfileToIR(t *testing.T) {
+	t.Run("exposed ports", func(t *testing.T) {
+		dockerfilePath := writeDockerfile(t, "FROM alpine\nEXPOSE 8080 9090\nEXPOSE 8080\n")
+		ir, err := ParseDockerfileToIR(dockerfilePath, "myproject", "myimage", "mysvc")
+		if err != nil {
+			t.Fatalf("failed to parse the Dockerfile at path %s . Error: %q", dockerfilePath, err)
+		}
+		if ir.Name != "myproject" {
+			t.Fatalf("expected the project name to be myproject . Actual: %s", ir.Name)
+		}
+		want := []int{8080, 9090}
+		if exposedPorts := ir.ContainerImages["myimage"].ExposedPorts; !cmp.Equal(exposedPorts, want) {
+			t.Fatalf("failed to get the exposed ports. Differences:\n%s", cmp.Diff(want, exposedPorts))
+		}
+	})
+	t.Run("exposed ports with protocols", func(t *testing.T) {
+		dockerfilePath := writeDockerfile(t, "FROM alpine\nEXPOSE 8080/tcp 53/udp 9090/foo\n")
+		ir, err := ParseDockerfileToIR(dockerfilePath, "myproject", "myimage", "mysvc")
+		if err != nil {
+			t.Fatalf("failed to parse the Dockerfile at path %s . Error: %q", dockerfilePath, err)
+		}
+		want := []irtypes.ServiceToPodPortForwarding{
+			{ServicePort: irtypes.Port{Number: 8080}, PodPort: irtypes.Port{Number: 8080}},
+			{ServicePort: irtypes.Port{Number: 53}, PodPort: irtypes.Port{Number: 53}, Protocol: core.ProtocolUDP},
+		}
+		if forwardings := ir.Services["mysvc"].ServiceToPodPortForwardings; !cmp.Equal(forwardings, want) {
+			t.Fatalf("failed to get the port forwardings. Differences:\n%s", cmp.Diff(want, forwardings))
+		}
+		wantPorts := []core.ContainerPort{{ContainerPort: 8080}, {ContainerPort: 53, Protocol: core.ProtocolUDP}}
+		if ports := ir.Services["mysvc"].Containers[0].Ports; !cmp.Equal(ports, wantPorts) {
+			t.Fatalf("failed to get the container ports. Differences:\n%s", cmp.Diff(wantPorts, ports))
+		}
+	})
+	t.Run("same port with mixed protocols", func(t *testing.T) {
+		dockerfilePath := writeDockerfile(t, "FROM alpine\nEXPOSE 80 443/tcp 53/udp 53/tcp\n")
+		ir, err := ParseDockerfileToIR(dockerfilePath, "myproject", "myimage", "mysvc")
+		if err != nil {
+			t.Fatalf("failed to parse the Dockerfile at path %s . Error: %q", dockerfilePath, err)
+		}
+		want := []irtypes.ServiceToPodPortForwarding{
+			{ServicePort: irtypes.Port{Number: 80}, PodPort: irtypes.Port{Number: 80}},
+			{ServicePort: irtypes.Port{Number: 443}, PodPort: irtypes.Port{Number: 443}},
+			{ServicePort: irtypes.Port{Number: 53}, PodPort: irtypes.Port{Number: 53}, Protocol: core.ProtocolUDP},
+			{ServicePort: irtypes.Port{Number: 53}, PodPort: irtypes.Port{Number: 53}},
+		}
+		if forwardings := ir.Services["mysvc"].ServiceToPodPortForwardings; !cmp.Equal(forwardings, want) {
+			t.Fatalf("failed to get the port forwardings. Differences:\n%s", cmp.Diff(want, forwardings))
+		}
+		wantPorts := []core.ContainerPort{{ContainerPort: 80}, {ContainerPort: 443}, {ContainerPort: 53, Protocol: core.ProtocolUDP}, {ContainerPort: 53}}
+		if ports := ir.Services["mysvc"].Containers[0].Ports; !cmp.Equal(ports, wantPorts) {
+			t.Fatalf("failed to get the container ports. Differences:\n%s", cmp.Diff(wantPorts, ports))
+		}
+	})
+	t.Run("windows base image", func(t *testing.T) {
+		dockerfilePath := writeDockerfile(t, "FROM mcr.microsoft.com/windows/servercore:ltsc2019\nEXPOSE 80\nCMD app.exe --port 80\n")
+		ir, err := ParseDockerfileToIR(dockerfilePath, "myproject", "myimage", "mysvc")
+		if err != nil {
+			t.Fatalf("failed to parse the Dockerfile at path %s . Error: %q", dockerfilePath, err)
+		}
+		want := []string{"cmd", "/S", "/C", "app.exe --port 80"}
+		if args := ir.Services["mysvc"].Containers[0].Args; !cmp.Equal(args, want) {
+			t.Fatalf("failed to get the args. Differences:\n%s", cmp.Diff(want, args))
+		}
+	})
+	t.Run("Dockerfile without FROM", func(t *testing.T) {
+		dockerfilePath := writeDockerfile(t, "EXPOSE 8080\nCMD [\"/app\"]\n")
+		if _, err := ParseDockerfileToIR(dockerfilePath, "myproject", "myimage", "mysvc"); err == nil {
+			t.Fatalf("expected an error for a Dockerfile without a FROM instruction")
+		}
+		parser := DockerfileParser{Env: &environment.Environment{ProjectName: "myproject"}}
+		if a := parser.getIRFromDockerfile(dockerfilePath, "", "myimage", "mysvc"); a != nil {
+			t.Fatalf("expected the Dockerfile without a FROM instruction to be skipped. Actual: %+v", a)
+		}
+	})
+	t.Run("missing Dockerfile", func(t *testing.T) {
+		if _, err := ParseDockerfileToIR(filepath.Join(t.TempDir(), common.DefaultDockerfileName), "myproject", "myimage", "mysvc"); err == nil {
+			t.Fatalf("expected an error for a missing Dockerfile")
+		}
+	})
+}
+
+func TestNodePort(t *testing.T) {
+	getService := func(t *testing.T, parser *DockerfileParser, dockerfile string) irtypes.Service {
+		dockerfilePath := writeDockerfile(t, dockerfile)
+		a := parser.getIRFromDockerfile(dockerfilePath, "", "myimage", "mysvc")
+		if a == nil {
+			t.Fatalf("failed to get the IR from the Dockerfile at path %s", dockerfilePath)
+		}
+		return a.Configs[irtypes.IRConfigType].(irtypes.IR).Services["mysvc"]
+	}
+	env := &environment.Environment{ProjectName: "myproject"}
+	t.Run("cluster ip by default", func(t *testing.T) {
+		parser := &DockerfileParser{Env: env}
+		if service := getService(t, parser, "FROM nginx\nEXPOSE 8080\n"); service.ServiceType != "" {
+			t.Fatalf("expected the service type to not be set. Actual: %s", service.ServiceType)
+		}
+	})
+	t.Run("node ports are chosen by the cluster", func(t *testing.T) {
+		parser := &DockerfileParser{Env: env, DockerfileParserConfig: DockerfileParserYamlConfig{NodePort: true}}
+		service := getService(t, parser, "FROM nginx\nEXPOSE 8080\n")
+		if service.ServiceType != core.ServiceTypeNodePort {
+			t.Fatalf("expected a NodePort service. Actual: %s", service.ServiceType)
+		}
+		if nodePort := service.ServiceToPodPortForwardings[0].NodePort; nodePort != 0 {
+			t.Fatalf("expected the node port to not be assigned. Actual: %d", nodePort)
+		}
+	})
+	t.Run("node ports are assigned without collisions", func(t *testing.T) {
+		parser := &DockerfileParser{Env: env, DockerfileParserConfig: DockerfileParserYamlConfig{NodePort: true, AssignNodePorts: true}}
+		service := getService(t, parser, "FROM nginx\nEXPOSE 80 2848 30500\n")
+		nodePorts := []int32{}
+		for _, forwarding := range service.ServiceToPodPortForwardings {
+			nodePorts = append(nodePorts, forwarding.NodePort)
+		}
+		// 80 and 2848 both map to 30080 and 30500 is already in the node port range
+		if want := []int32{30080, 30081, 30500}; !cmp.Equal(nodePorts, want) {
+			t.Fatalf("expected the node ports %+v . Actual: %+v", want, nodePorts)
+		}
+		service = getService(t, parser, "FROM nginx\nEXPOSE 80\n")
+		if nodePort := service.ServiceToPodPortForwardings[0].NodePort; nodePort != 30082 {
+			t.Fatalf("expected the node port to not collide with the other services. Actual: %d", nodePort)
+		}
+	})
+}
+
+type labelToEnvHandler struct{}
+
+func (labelToEnvHandler) HandleInstruction(node *dockerparser.Node, container *core.Container) error {
+	if node.Value != "label" {
+		return nil
+	}
+	for n := node.Next; n != nil && n.Next != nil; n = n.Next.Next {
+		container.Env = append(container.Env, core.EnvVar{Name: n.Value, Value: n.Next.Value})
+	}
+	return nil
+}
+
+func TestInstructionHandler(t *testing.T) {
+	RegisterDockerfileInstructionHandler("labeltoenv", labelToEnvHandler{})
+	dockerfilePath := writeDockerfile(t, "FROM nginx\nLABEL APP_VERSION=1.0\nEXPOSE 8080\n")
+	getContainer := func(t *testing.T, config map[string]interface{}) core.Container {
+		parser := DockerfileParser{}
+		tc := transformertypes.Transformer{}
+		tc.Spec.Config = config
+		if err := parser.Init(tc, &environment.Environment{ProjectName: "myproject"}); err != nil {
+			t.Fatalf("failed to initialize the transformer. Error: %q", err)
+		}
+		a := parser.getIRFromDockerfile(dockerfilePath, "", "myimage", "mysvc")
+		if a == nil {
+			t.Fatalf("failed to get the IR from the Dockerfile at path %s", dockerfilePath)
+		}
+		return a.Configs[irtypes.IRConfigType].(irtypes.IR).Services["mysvc"].Containers[0]
+	}
+	if container := getContainer(t, nil); len(container.Env) != 0 {
+		t.Fatalf("expected the default handler to not change the container. Actual: %+v", container.Env)
+	}
+	container := getContainer(t, map[string]interface{}{"instructionHandler": "labeltoenv"})
+	if want := []core.EnvVar{{Name: "APP_VERSION", Value: "1.0"}}; !cmp.Equal(container.Env, want) {
+		t.Fatalf("expected the handler to add the env vars %+v . Actual: %+v", want, container.Env)
+	}
+	tc := transformertypes.Transformer{}
+	tc.Spec.Config = map[string]interface{}{"instructionHandler": "missing"}
+	if err := (&DockerfileParser{}).Init(tc, &environment.Environment{}); err == nil {
+		t.Fatalf("expected an error for a handler that is not registered")
+	}
+}
+
+func TestGetIRFromDockerfile(t *testing.T) {
+	parser := DockerfileParser{
+		Env:                    &environment.Environment{ProjectName: "myproject"},
+		DockerfileParserConfig: DockerfileParserYamlConfig{Registry: "quay.io/myorg"},
+	}
+	dockerfilePath := writeDockerfile(t, "FROM nginx\nEXPOSE 8080\n")
+	ir, err := parser.GetIRFromDockerfile(dockerfilePath, "", "myimage", "mysvc")
+	if err != nil {
+		t.Fatalf("failed to get the IR from the Dockerfile at path %s . Error: %q", dockerfilePath, err)
+	}
+	if ir.Name != "myproject" {
+		t.Fatalf("expected the IR name to be myproject . Actual: %s", ir.Name)
+	}
+	if image := ir.Services["mysvc"].Containers[0].Image; image != "quay.io/myorg/myimage" {
+		t.Fatalf("expected the config of the transformer to be applied to the IR. Actual image: %s", image)
+	}
+	if _, err := parser.GetIRFromDockerfile(writeDockerfile(t, "EXPOSE 8080\n"), "", "myimage", "mysvc"); err == nil {
+		t.Fatalf("expected an error for a Dockerfile without a FROM instruction")
+	}
+}
+
+func TestNaming(t *testing.T) {
+	naming := NamingConfig{Prefix: "Team-", Suffix: "-v2", Lowercase: true, DNSLabel: true}
+	parser := DockerfileParser{
+		Env:                    &environment.Environment{ProjectName: "myproject"},
+		DockerfileParserConfig: DockerfileParserYamlConfig{Naming: naming, Registry: "quay.io/myorg"},
+	}
+	dockerfilePath := writeDockerfile(t, "FROM nginx\nEXPOSE 8080\n")
+	ir, err := parser.GetIRFromDockerfile(dockerfilePath, "", "myimage:1.0", "MySvc")
+	if err != nil {
+		t.Fatalf("failed to get the IR from the Dockerfile at path %s . Error: %q", dockerfilePath, err)
+	}
+	if ir.Name != "team-myproject-v2" {
+		t.Fatalf("expected the naming to be applied to the project name. Actual: %s", ir.Name)
+	}
+	service, ok := ir.Services["team-mysvc-v2"]
+	if !ok {
+		t.Fatalf("expected the naming to be applied to the service name. Actual: %+v", ir.Services)
+	}
+	if image := service.Containers[0].Image; image != "quay.io/myorg/team-myimage-v2:1.0" {
+		t.Fatalf("expected the naming to be applied to the image name. Actual: %s", image)
+	}
+	if _, ok := ir.ContainerImages["quay.io/myorg/team-myimage-v2:1.0"]; !ok {
+		t.Fatalf("expected the container image to use the new name. Actual: %+v", ir.ContainerImages)
+	}
+	longName := NamingConfig{DNSLabel: true}.apply(strings.Repeat("a", 100))
+	if len(longName) != 63 {
+		t.Fatalf("expected the name to be truncated to 63 characters. Actual: %d characters", len(longName))
+	}
+	if name := (NamingConfig{}).apply("MySvc"); name != "MySvc" {
+		t.Fatalf("expected the name to not change by default. Actual: %s", name)
+	}
+}
+
+func TestBaseImages(t *testing.T) {
+	dockerfile := `FROM golang:1.16@sha256:abcd AS build
+FROM localhost:5000/org/tools AS tools
+FROM scratch
+FROM build AS final
+`
+	dockerfilePath := writeDockerfile(t, dockerfile)
+	_, dfMetadata, err := parseDockerfile(getDockerfileLogger(dockerfilePath, "myimage", "mysvc"), dockerfilePath, "", "myproject", "myimage", "mysvc", NamingConfig{}, nil, nil, false, false)
+	if err != nil {
+		t.Fatalf("failed to parse the Dockerfile at path %s . Error: %q", dockerfilePath, err)
+	}
+	want := []artifacts.DockerfileBaseImage{
+		{Reference: "golang:1.16@sha256:abcd", Repository: "golang", Tag: "1.16", Digest: "sha256:abcd", Stage: "build"},
+		{Reference: "localhost:5000/org/tools", Repository: "localhost:5000/org/tools", Stage: "tools"},
+		{Reference: "scratch", Repository: "scratch"},
+		{Reference: "build", Repository: "build", Stage: "final", FromStage: true},
+	}
+	if !cmp.Equal(dfMetadata.BaseImages, want) {
+		t.Fatalf("failed to get the base images. Differences:\n%s", cmp.Diff(want, dfMetadata.BaseImages))
+	}
+}
+
+func TestEnvFile(t *testing.T) {
+	dockerfilePath := writeDockerfile(t, "FROM nginx\nEXPOSE 8080\n")
+	envFile := `# database settings
+DB_HOST=db.example.com
+export DB_PORT=5432 # the default port
+GREETING="hello \"world\"\nbye"
+RAW='a # not a comment \n'
+EMPTY=
+NOT_A_VARIABLE
+`
+	if err := ioutil.WriteFile(filepath.Join(filepath.Dir(dockerfilePath), ".env"), []byte(envFile), common.DefaultFilePermission); err != nil {
+		t.Fatalf("failed to write the env file. Error: %q", err)
+	}
+	parser := DockerfileParser{
+		Env:                    &environment.Environment{ProjectName: "myproject"},
+		DockerfileParserConfig: DockerfileParserYamlConfig{EnvFile: true},
+	}
+	ir, err := parser.GetIRFromDockerfile(dockerfilePath, "", "myimage", "mysvc")
+	if err != nil {
+		t.Fatalf("failed to get the IR from the Dockerfile at path %s . Error: %q", dockerfilePath, err)
+	}
+	if len(ir.Storages) != 1 || ir.Storages[0].Name != "mysvc-env" || ir.Storages[0].StorageType != irtypes.ConfigMapKind {
+		t.Fatalf("expected a ConfigMap for the env file. Actual: %+v", ir.Storages)
+	}
+	want := map[string]string{
+		"DB_HOST":  "db.example.com",
+		"DB_PORT":  "5432",
+		"GREETING": "hello \"world\"\nbye",
+		"RAW":      `a # not a comment \n`,
+		"EMPTY":    "",
+	}
+	actual := map[string]string{}
+	for key, value := range ir.Storages[0].Content {
+		actual[key] = string(value)
+	}
+	if !reflect.DeepEqual(actual, want) {
+		t.Fatalf("failed to parse the env file. Expected: %+v Actual: %+v", want, actual)
+	}
+	envFrom := ir.Services["mysvc"].Containers[0].EnvFrom
+	if len(envFrom) != 1 || envFrom[0].ConfigMapRef == nil || envFrom[0].ConfigMapRef.Name != "mysvc-env" {
+		t.Fatalf("expected the container to get its environment from the ConfigMap. Actual: %+v", envFrom)
+	}
+
+	parser.DockerfileParserConfig.EnvFile = false
+	ir, err = parser.GetIRFromDockerfile(dockerfilePath, "", "myimage", "mysvc")
+	if err != nil {
+		t.Fatalf("failed to get the IR from the Dockerfile at path %s . Error: %q", dockerfilePath, err)
+	}
+	if len(ir.Storages) != 0 || len(ir.Services["mysvc"].Containers[0].EnvFrom) != 0 {
+		t.Fatalf("expected the env file to be ignored by default. Actual: %+v", ir.Storages)
+	}
+}
+
+func TestSourceAnnotations(t *testing.T) {
+	dockerfilePath := writeDockerfile(t, "FROM nginx\nEXPOSE 8080\n")
+	sourceDir := filepath.Dir(filepath.Dir(dockerfilePath))
+	parser := DockerfileParser{Env: &environment.Environment{ProjectName: "myproject", Source: sourceDir}}
+	ir, err := parser.GetIRFromDockerfile(dockerfilePath, "", "myimage", "mysvc")
+	if err != nil {
+		t.Fatalf("failed to get the IR from the Dockerfile at path %s . Error: %q", dockerfilePath, err)
+	}
+	annotations := ir.Services["mysvc"].Annotations
+	wantPath := filepath.Base(filepath.Dir(dockerfilePath)) + "/" + common.DefaultDockerfileName
+	if annotations[common.SourceDockerfileAnnotation] != wantPath {
+		t.Fatalf("expected the source dockerfile annotation to be %s . Actual: %+v", wantPath, annotations)
+	}
+	if annotations[common.TransformerAnnotation] != "DockerfileParser" {
+		t.Fatalf("expected the transformer annotation to be DockerfileParser. Actual: %+v", annotations)
+	}
+}
+
+func TestHeredocs(t *testing.T) {
+	dockerfile := `# syntax=docker/dockerfile:1.4
+FROM alpine
+RUN <<EOF
+apk add curl
+EXPOSE 9999
+EOF
+COPY <<-CONF <<INDEX /etc/app/
+	EXPOSE 7777
+	CONF
+EXPOSE 6666
+INDEX
+RUN echo "a<<b" > /tmp/c
+EXPOSE 8080
+`
+	dockerfilePath := writeDockerfile(t, dockerfile)
+	ir, dfMetadata, err := parseDockerfile(getDockerfileLogger(dockerfilePath, "myimage", "mysvc"), dockerfilePath, "", "myproject", "myimage", "mysvc", NamingConfig{}, nil, nil, false, false)
+	if err != nil {
+		t.Fatalf("failed to parse the Dockerfile at path %s . Error: %q", dockerfilePath, err)
+	}
+	if ports := ir.ContainerImages["myimage"].ExposedPorts; !cmp.Equal(ports, []int{8080}) {
+		t.Fatalf("expected only the port exposed after the heredocs. Actual: %+v", ports)
+	}
+	if len(dfMetadata.CopySources) != 0 {
+		t.Fatalf("expected the heredocs to not be copy sources. Actual: %+v", dfMetadata.CopySources)
+	}
+	if syntax := getSyntaxDirective(dockerfile); syntax != "docker/dockerfile:1.4" {
+		t.Fatalf("failed to get the syntax directive. Actual: %s", syntax)
+	}
+}
+
+func TestDefaultTCPProbe(t *testing.T) {
+	parser := DockerfileParser{
+		Env:                    &environment.Environment{ProjectName: "myproject"},
+		DockerfileParserConfig: DockerfileParserYamlConfig{DefaultTCPProbe: true},
+	}
+	testcases := []struct {
+		name       string
+		dockerfile string
+		wantPort   int
+	}{
+		{name: "probe on the first TCP port", dockerfile: "FROM nginx\nEXPOSE 53/udp 8080 9090\n", wantPort: 8080},
+		{name: "no probe with a healthcheck", dockerfile: "FROM nginx\nEXPOSE 8080\nHEALTHCHECK CMD curl -f http://localhost:8080/\n"},
+		{name: "probe when the healthcheck is disabled", dockerfile: "FROM nginx\nEXPOSE 8080\nHEALTHCHECK NONE\n", wantPort: 8080},
+		{name: "probe when the healthcheck is in an earlier stage", dockerfile: "FROM nginx AS build\nHEALTHCHECK CMD true\nFROM nginx\nEXPOSE 8080\n", wantPort: 8080},
+	}
+	for _, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			dockerfilePath := writeDockerfile(t, testcase.dockerfile)
+			ir, err := parser.GetIRFromDockerfile(dockerfilePath, "", "myimage", "mysvc")
+			if err != nil {
+				t.Fatalf("failed to get the IR from the Dockerfile at path %s . Error: %q", dockerfilePath, err)
+			}
+			probe := ir.Services["mysvc"].Containers[0].ReadinessProbe
+			if testcase.wantPort == 0 {
+				if probe != nil {
+					t.Fatalf("expected no readiness probe. Actual: %+v", probe)
+				}
+				return
+			}
+			if probe == nil || probe.TCPSocket == nil || probe.TCPSocket.Port.IntValue() != testcase.wantPort {
+				t.Fatalf("expected a TCP readiness probe on the port %d . Actual: %+v", testcase.wantPort, probe)
+			}
+		})
+	}
+	parser.DockerfileParserConfig.DefaultTCPProbe = false
+	dockerfilePath := writeDockerfile(t, "FROM nginx\nEXPOSE 8080\n")
+	ir, err := parser.GetIRFromDockerfile(dockerfilePath, "", "myimage", "mysvc")
+	if err != nil {
+		t.Fatalf("failed to get the IR from the Dockerfile at path %s . Error: %q", dockerfilePath, err)
+	}
+	if probe := ir.Services["mysvc"].Containers[0].ReadinessProbe; probe != nil {
+		t.Fatalf("expected no readiness probe by default. Actual: %+v", probe)
+	}
+}
+
+func TestPartialParse(t *testing.T) {
+	dockerfilePath := writeDockerfile(t, "FROM nginx\nENV a\nEXPOSE 8080\nENV b\n")
+	ir, _, err := parseDockerfile(getDockerfileLogger(dockerfilePath, "myimage", "mysvc"), dockerfilePath, "", "myproject", "myimage", "mysvc", NamingConfig{}, nil, nil, false, false)
+	if err != nil {
+		t.Fatalf("expected the instructions that parsed to be used. Error: %q", err)
+	}
+	if ports := ir.ContainerImages["myimage"].ExposedPorts; !cmp.Equal(ports, []int{8080}) {
+		t.Fatalf("expected the port exposed after the invalid instruction. Actual: %+v", ports)
+	}
+	for _, dockerfile := range []string{"", "# only a comment\n", "ENV a\n"} {
+		dockerfilePath := writeDockerfile(t, dockerfile)
+		if _, err := getDockerFileAST(getDockerfileLogger(dockerfilePath, "myimage", "mysvc"), dockerfilePath); err == nil {
+			t.Fatalf("expected an error for the dockerfile %q", dockerfile)
+		}
+	}
+}
+
+func TestGlobalBuildArgs(t *testing.T) {
+	dockerfile := "ARG REGISTRY=mcr.microsoft.com\nARG BASE=${REGISTRY}/windows/nanoserver:1809\nFROM ${BASE}\nARG PORT=8080\nCMD app.exe\n"
+	dockerfilePath := writeDockerfile(t, dockerfile)
+	ir, dfMetadata, err := parseDockerfile(getDockerfileLogger(dockerfilePath, "myimage", "mysvc"), dockerfilePath, "", "myproject", "myimage", "mysvc", NamingConfig{}, nil, nil, false, false)
+	if err != nil {
+		t.Fatalf("failed to parse the Dockerfile at path %s . Error: %q", dockerfilePath, err)
+	}
+	if len(dfMetadata.BaseImages) != 1 || dfMetadata.BaseImages[0].Reference != "mcr.microsoft.com/windows/nanoserver:1809" || dfMetadata.BaseImages[0].Tag != "1809" {
+		t.Fatalf("expected the base image to be resolved using the global build args. Actual: %+v", dfMetadata.BaseImages)
+	}
+	if want := []string{"cmd", "/S", "/C", "app.exe"}; !reflect.DeepEqual(ir.Services["mysvc"].Containers[0].Args, want) {
+		t.Fatalf("expected the Windows shell to be used. Expected: %v Actual: %v", want, ir.Services["mysvc"].Containers[0].Args)
+	}
+	if len(dfMetadata.BuildArgs) != 3 || !dfMetadata.BuildArgs[0].Global || !dfMetadata.BuildArgs[1].Global || dfMetadata.BuildArgs[2].Global {
+		t.Fatalf("expected only the build args before the first FROM to be global. Actual: %+v", dfMetadata.BuildArgs)
+	}
+}
+
+func TestDetectPorts(t *testing.T) {
+	testcases := []struct {
+		name       string
+		dockerfile string
+		relPath    string
+		contents   string
+		wantPorts  []int32
+	}{
+		{name: "package.json start script", dockerfile: "FROM node\n", relPath: "package.json", contents: `{"scripts": {"start": "PORT=4000 node server.js"}}`, wantPorts: []int32{4000}},
+		{name: "spring properties", dockerfile: "FROM openjdk\n", relPath: "src/main/resources/application.properties", contents: "spring.application.name=web\nserver.port=9090\n", wantPorts: []int32{9090}},
+		{name: "spring yaml", dockerfile: "FROM openjdk\n", relPath: "src/main/resources/application.yml", contents: "server:\n  servlet:\n    context-path: /api\n  port: 8081\n", wantPorts: []int32{8081}},
+		{name: "the exposed ports take precedence", dockerfile: "FROM node\nEXPOSE 3000\n", relPath: "package.json", contents: `{"scripts": {"start": "node server.js --port 4000"}}`, wantPorts: []int32{3000}},
+		{name: "default port when nothing is declared", dockerfile: "FROM node\n", relPath: "package.json", contents: `{"name": "web"}`, wantPorts: []int32{common.DefaultServicePort}},
+	}
+	parser := DockerfileParser{
+		Env:                    &environment.Environment{ProjectName: "myproject"},
+		DockerfileParserConfig: DockerfileParserYamlConfig{DetectPorts: true},
+	}
+	for _, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			dockerfilePath := writeDockerfile(t, testcase.dockerfile)
+			portFilePath := filepath.Join(filepath.Dir(dockerfilePath), testcase.relPath)
+			if err := os.MkdirAll(filepath.Dir(portFilePath), common.DefaultDirectoryPermission); err != nil {
+				t.Fatalf("failed to create the directory for the file %s . Error: %q", portFilePath, err)
+			}
+			if err := ioutil.WriteFile(portFilePath, []byte(testcase.contents), common.DefaultFilePermission); err != nil {
+				t.Fatalf("failed to write the file %s . Error: %q", portFilePath, err)
+			}
+			ir, err := parser.GetIRFromDockerfile(dockerfilePath, "", "myimage", "mysvc")
+			if err != nil {
+				t.Fatalf("failed to get the IR from the Dockerfile at path %s . Error: %q", dockerfilePath, err)
+			}
+			ports := []int32{}
+			for _, port := range ir.Services["mysvc"].Containers[0].Ports {
+				ports = append(ports, port.ContainerPort)
+			}
+			if !reflect.DeepEqual(ports, testcase.wantPorts) {
+				t.Fatalf("expected the ports %v . Actual: %v", testcase.wantPorts, ports)
+			}
+		})
+	}
+}
+
+func TestPlatformArch(t *testing.T) {
+	testcases := []struct {
+		name       string
+		dockerfile string
+		wantArch   string
+	}{
+		{name: "arm64", dockerfile: "FROM --platform=linux/arm64 alpine\n", wantArch: "arm64"},
+		{name: "arm with variant", dockerfile: "FROM --platform=linux/arm/v7 alpine\n", wantArch: "arm"},
+		{name: "amd64 needs no node selector", dockerfile: "FROM --platform=linux/amd64 alpine\n"},
+		{name: "global build arg", dockerfile: "ARG PLATFORM=linux/aarch64\nFROM --platform=${PLATFORM} alpine\n", wantArch: "arm64"},
+		{name: "unresolved build arg", dockerfile: "FROM --platform=$TARGETPLATFORM alpine\n"},
+		{name: "only the final stage is used", dockerfile: "FROM --platform=linux/arm64 alpine AS build\nFROM alpine\n"},
+	}
+	for _, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			dockerfilePath := writeDockerfile(t, testcase.dockerfile)
+			ir, _, err := parseDockerfile(getDockerfileLogger(dockerfilePath, "myimage", "mysvc"), dockerfilePath, "", "myproject", "myimage", "mysvc", NamingConfig{}, nil, nil, false, false)
+			if err != nil {
+				t.Fatalf("failed to parse the Dockerfile at path %s . Error: %q", dockerfilePath, err)
+			}
+			nodeSelector := ir.Services["mysvc"].NodeSelector
+			if testcase.wantArch == "" {
+				if len(nodeSelector) != 0 {
+					t.Fatalf("expected no node selector. Actual: %+v", nodeSelector)
+				}
+				return
+			}
+			if want := map[string]string{"kubernetes.io/arch": testcase.wantArch}; !reflect.DeepEqual(nodeSelector, want) {
+				t.Fatalf("expected the node selector %+v . Actual: %+v", want, nodeSelector)
+			}
+		})
+	}
+}
+
+func TestInvalidExposedPorts(t *testing.T) {
+	dockerfilePath := writeDockerfile(t, "FROM nginx\nEXPOSE 0 70000 8080/tcp\n")
+	ir, _, err := parseDockerfile(getDockerfileLogger(dockerfilePath, "myimage", "mysvc"), dockerfilePath, "", "myproject", "myimage", "mysvc", NamingConfig{}, nil, nil, false, false)
+	if err != nil {
+		t.Fatalf("failed to parse the Dockerfile at path %s . Error: %q", dockerfilePath, err)
+	}
+	want := []core.ContainerPort{{ContainerPort: 8080}}
+	if ports := ir.Services["mysvc"].Containers[0].Ports; !reflect.DeepEqual(ports, want) {
+		t.Fatalf("expected the ports 0 and 70000 to be skipped. Expected: %+v Actual: %+v", want, ports)
+	}
+	for _, port := range []string{"0", "70000", "-1"} {
+		if _, _, err := parseExposedPort(port); err == nil {
+			t.Fatalf("expected an error for the port %s", port)
+		}
+	}
+}
+
+func TestMakePortNamesUnique(t *testing.T) {
+	ports := []core.ContainerPort{
+		{Name: "http", ContainerPort: 8080},
+		{Name: "http", ContainerPort: 8081},
+		{ContainerPort: 9000},
+		{Name: "http", ContainerPort: 8082},
+		{Name: "very-long-name1", ContainerPort: 9090},
+		{Name: "very-long-name1", ContainerPort: 9091},
+	}
+	makePortNamesUnique(getDockerfileLogger("Dockerfile", "myimage", "mysvc"), ports)
+	want := []string{"http", "http-2", "", "http-3", "very-long-name1", "very-long-nam-2"}
+	for i, port := range ports {
+		if port.Name != want[i] {
+			t.Fatalf("expected the port %d to be named %s . Actual: %s", port.ContainerPort, want[i], port.Name)
+		}
+	}
+}
+
+func TestServiceNameLabel(t *testing.T) {
+	dockerfilePath := writeDockerfile(t, "FROM nginx AS build\nLABEL move2kube.konveyor.io/service-name=builder\nFROM nginx\nLABEL maintainer=\"a b\" move2kube.konveyor.io/service-name=\"My_App\"\nEXPOSE 8080\n")
+	parser := DockerfileParser{Env: &environment.Environment{ProjectName: "myproject"}}
+	ir, err := parser.GetIRFromDockerfile(dockerfilePath, "", "myimage", "mysvc")
+	if err != nil {
+		t.Fatalf("failed to get the IR from the Dockerfile at path %s . Error: %q", dockerfilePath, err)
+	}
+	irService, ok := ir.Services["my-app"]
+	if !ok || len(ir.Services) != 1 {
+		t.Fatalf("expected a single service named after the label. Actual: %+v", ir.Services)
+	}
+	if irService.Name != "my-app" || irService.Containers[0].Name != "my-app" {
+		t.Fatalf("expected the service and the container to be named after the label. Actual: %+v", irService)
+	}
+	if irService.Annotations[common.SourceDockerfileAnnotation] == "" {
+		t.Fatalf("expected the config of the transformer to be applied to the renamed service. Actual: %+v", irService.Annotations)
+	}
+}
+
+func TestPortNamesLabel(t *testing.T) {
+	dockerfilePath := writeDockerfile(t, "FROM nginx\nLABEL move2kube.konveyor.io/port-names=\"8080=http, 9090=metrics,53=dns,7070=Not_Valid,abc=x\"\nEXPOSE 8080 9090 7070 53/tcp 53/udp\n")
+	parser := DockerfileParser{Env: &environment.Environment{ProjectName: "myproject"}}
+	ir, err := parser.GetIRFromDockerfile(dockerfilePath, "", "myimage", "mysvc")
+	if err != nil {
+		t.Fatalf("failed to get the IR from the Dockerfile at path %s . Error: %q", dockerfilePath, err)
+	}
+	irService := ir.Services["mysvc"]
+	want := map[string]string{"8080/": "http", "9090/": "metrics", "7070/": "", "53/": "dns", "53/UDP": ""}
+	actual := map[string]string{}
+	for _, port := range irService.Containers[0].Ports {
+		actual[fmt.Sprintf("%d/%s", port.ContainerPort, port.Protocol)] = port.Name
+	}
+	if !cmp.Equal(actual, want) {
+		t.Fatalf("the container port names are wrong. Difference:\n%s", cmp.Diff(want, actual))
+	}
+	actual = map[string]string{}
+	for _, forwarding := range irService.ServiceToPodPortForwardings {
+		actual[fmt.Sprintf("%d/%s", forwarding.ServicePort.Number, forwarding.Protocol)] = forwarding.ServicePort.Name
+	}
+	if !cmp.Equal(actual, want) {
+		t.Fatalf("the service port names are wrong. Difference:\n%s", cmp.Diff(want, actual))
+	}
+}
+
+func getDockerfileArtifacts(tb testing.TB, count int) []transformertypes.Artifact {
+	tb.Helper()
+	dir := tb.TempDir()
+	dockerfileArtifacts := []transformertypes.Artifact{}
+	for i := 0; i < count; i++ {
+		serviceName := fmt.Sprintf("svc%d", i)
+		dockerfilePath := filepath.Join(dir, serviceName, common.DefaultDockerfileName)
+		if err := os.MkdirAll(filepath.Dir(dockerfilePath), common.DefaultDirectoryPermission); err != nil {
+			tb.Fatal(err)
+		}
+		contents := fmt.Sprintf("FROM node:14\nWORKDIR /app\nCOPY . .\nRUN npm install\nEXPOSE %d\nCMD [\"npm\", \"start\"]\n", 8000+i)
+		if err := ioutil.WriteFile(dockerfilePath, []byte(contents), common.DefaultFilePermission); err != nil {
+			tb.Fatal(err)
+		}
+		dockerfileArtifacts = append(dockerfileArtifacts, transformertypes.Artifact{
+			Name:     serviceName,
+			Artifact: artifacts.DockerfileForServiceArtifactType,
+			Paths:    map[transformertypes.PathType][]string{artifacts.DockerfilePathType: {dockerfilePath}},
+			Configs: map[transformertypes.ConfigType]interface{}{
+				artifacts.ServiceConfigType:   artifacts.ServiceConfig{ServiceName: serviceName},
+				artifacts.ImageNameConfigType: artifacts.ImageName{ImageName: serviceName + "-image"},
+			},
+		})
+	}
+	return dockerfileArtifacts
+}
+
+func TestTransformConcurrently(t *testing.T) {
+	dockerfileArtifacts := getDockerfileArtifacts(t, 20)
+	parser := DockerfileParser{Env: &environment.Environment{ProjectName: "myproject"}, DockerfileParserConfig: DockerfileParserYamlConfig{Concurrency: 4}}
+	_, nartifacts, err := parser.Transform(dockerfileArtifacts, nil)
+	if err != nil {
+		t.Fatalf("failed to transform the artifacts. Error: %q", err)
+	}
+	if len(nartifacts) != len(dockerfileArtifacts) {
+		t.Fatalf("expected %d artifacts. Actual: %d", len(dockerfileArtifacts), len(nartifacts))
+	}
+	for i, na := range nartifacts {
+		ir := irtypes.IR{}
+		if err := na.GetConfig(irtypes.IRConfigType, &ir); err != nil {
+			t.Fatalf("failed to get the IR from the artifact. Error: %q", err)
+		}
+		serviceName := fmt.Sprintf("svc%d", i)
+		if _, ok := ir.Services[serviceName]; !ok || len(ir.Services) != 1 {
+			t.Fatalf("expected the artifact %d to contain the service %s . Actual: %+v", i, serviceName, ir.Services)
+		}
+	}
+}
+
+func BenchmarkTransform(b *testing.B) {
+	logrus.SetLevel(logrus.ErrorLevel)
+	dockerfileArtifacts := getDockerfileArtifacts(b, 50)
+	for _, concurrency := range []int{1, 4, 8} {
+		b.Run(fmt.Sprintf("concurrency-%d", concurrency), func(b *testing.B) {
+			parser := DockerfileParser{Env: &environment.Environment{ProjectName: "myproject"}, DockerfileParserConfig: DockerfileParserYamlConfig{Concurrency: concurrency}}
+			for i := 0; i < b.N; i++ {
+				if _, _, err := parser.Transform(dockerfileArtifacts, nil); err != nil {
+					b.Fatalf("failed to transform the artifacts. Error: %q", err)
+				}
+			}
+		})
+	}
+}
+
+func TestPodDisruptionBudget(t *testing.T) {
+	parser := DockerfileParser{Env: &environment.Environment{ProjectName: "myproject"}, DockerfileParserConfig: DockerfileParserYamlConfig{PodDisruptionBudget: true}}
+	for dockerfile, wantMinAvailable := range map[string]bool{
+		"FROM nginx\nEXPOSE 8080\n": true,
+		"FROM redis\nEXPOSE 6379\n": false,
+	} {
+		dockerfilePath := writeDockerfile(t, dockerfile)
+		ir, err := parser.GetIRFromDockerfile(dockerfilePath, "", "myimage", "mysvc")
+		if err != nil {
+			t.Fatalf("failed to get the IR from the Dockerfile at path %s . Error: %q", dockerfilePath, err)
+		}
+		minAvailable := ir.Services["mysvc"].MinAvailable
+		if (minAvailable != nil) != wantMinAvailable {
+			t.Fatalf("expected the minAvailable to be set: %t for the Dockerfile %q . Actual: %v", wantMinAvailable, dockerfile, minAvailable)
+		}
+		if minAvailable != nil && minAvailable.IntValue() != defaultMinAvailable {
+			t.Fatalf("expected the minAvailable to be %d . Actual: %s", defaultMinAvailable, minAvailable.String())
+		}
+	}
+}
+
+func TestDetectCommandPorts(t *testing.T) {
+	testcases := []struct {
+		name               string
+		dockerfile         string
+		detectCommandPorts bool
+		wantPorts          []int32
+	}{
+		{name: "exec form", dockerfile: "FROM node\nCMD [\"node\", \"server.js\", \"--port\", \"3000\"]\n", detectCommandPorts: true, wantPorts: []int32{3000}},
+		{name: "shell form with equals", dockerfile: "FROM python\nCMD gunicorn app:app --port=5001\n", detectCommandPorts: true, wantPorts: []int32{5001}},
+		{name: "short flag in the entrypoint", dockerfile: "FROM node\nENTRYPOINT [\"serve\", \"-p\", \"4000\"]\nCMD [\"--port\", \"4001\"]\n", detectCommandPorts: true, wantPorts: []int32{4000, 4001}},
+		{name: "invalid port", dockerfile: "FROM node\nCMD [\"serve\", \"-p\", \"99999\"]\n", detectCommandPorts: true, wantPorts: []int32{common.DefaultServicePort}},
+		{name: "the exposed ports take precedence", dockerfile: "FROM node\nEXPOSE 8080\nCMD [\"node\", \"server.js\", \"--port\", \"3000\"]\n", detectCommandPorts: true, wantPorts: []int32{8080}},
+		{name: "disabled", dockerfile: "FROM node\nCMD [\"node\", \"server.js\", \"--port\", \"3000\"]\n", wantPorts: []int32{common.DefaultServicePort}},
+	}
+	for _, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			parser := DockerfileParser{
+				Env:                    &environment.Environment{ProjectName: "myproject"},
+				DockerfileParserConfig: DockerfileParserYamlConfig{DetectCommandPorts: testcase.detectCommandPorts},
+			}
+			dockerfilePath := writeDockerfile(t, testcase.dockerfile)
+			ir, err := parser.GetIRFromDockerfile(dockerfilePath, "", "myimage", "mysvc")
+			if err != nil {
+				t.Fatalf("failed to get the IR from the Dockerfile at path %s . Error: %q", dockerfilePath, err)
+			}
+			ports := []int32{}
+			for _, port := range ir.Services["mysvc"].Containers[0].Ports {
+				ports = append(ports, port.ContainerPort)
+			}
+			if !reflect.DeepEqual(ports, testcase.wantPorts) {
+				t.Fatalf("expected the ports %v . Actual: %v", testcase.wantPorts, ports)
+			}
+		})
+	}
+}
+
+func TestParseDockerfileInstructions(t *testing.T) {
+	testcases := []struct {
+		name       string
+		dockerfile string
+		want       DockerfileInfo
+		wantErr    bool
+	}{
+		{
+			name:       "ports and command",
+			dockerfile: "FROM node\nEXPOSE 8080 53/udp 53/tcp\nENTRYPOINT [\"node\"]\nCMD [\"server.js\"]\n",
+			want: DockerfileInfo{
+				Ports:      []core.ContainerPort{{ContainerPort: 8080}, {ContainerPort: 53, Protocol: core.ProtocolUDP}, {ContainerPort: 53}},
+				Entrypoint: []string{"node"},
+				Cmd:        []string{"server.js"},
+			},
+		},
+		{
+			name:       "env volumes workdir and user",
+			dockerfile: "FROM node\nENV A=1 B=\"two words\"\nENV A 3\nVOLUME /data /logs\nVOLUME [\"/data\", \"/cache\"]\nWORKDIR /app\nWORKDIR src\nUSER node:node\n",
+			want: DockerfileInfo{
+				EnvVars:    []core.EnvVar{{Name: "A", Value: "3"}, {Name: "B", Value: "two words"}},
+				Volumes:    []string{"/data", "/logs", "/cache"},
+				WorkingDir: "/app/src",
+				User:       "node:node",
+			},
+		},
+		{
+			name:       "only the final stage is used except for the ports",
+			dockerfile: "FROM --platform=linux/arm64 golang AS build\nEXPOSE 9000\nENV A=1\nWORKDIR /src\nUSER builder\nLABEL stage=build\nFROM --platform=linux/arm64 alpine\nLABEL app=web\nCMD /app\n",
+			want: DockerfileInfo{
+				Ports:        []core.ContainerPort{{ContainerPort: 9000}},
+				Cmd:          []string{"/bin/sh", "-c", "/app"},
+				Labels:       map[string]string{"app": "web"},
+				PlatformArch: "arm64",
+			},
+		},
+		{
+			name:       "shell form entrypoint ignores the cmd on windows",
+			dockerfile: "FROM mcr.microsoft.com/windows/servercore:ltsc2019\nENTRYPOINT app.exe\nCMD [\"--help\"]\n",
+			want: DockerfileInfo{
+				Entrypoint: []string{"cmd", "/S", "/C", "app.exe"},
+				IsWindows:  true,
+			},
+		},
+		{
+			name:       "no FROM instruction",
+			dockerfile: "EXPOSE 8080\n",
+			wantErr:    true,
+		},
+	}
+	for _, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			ast, err := dockerparser.Parse(strings.NewReader(testcase.dockerfile))
+			if err != nil {
+				t.Fatalf("failed to parse the Dockerfile. Error: %q", err)
+			}
+			dfInfo, err := ParseDockerfileInstructions(ast)
+			if testcase.wantErr {
+				if err == nil {
+					t.Fatalf("expected an error. Actual: %+v", dfInfo)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("failed to parse the Dockerfile instructions. Error: %q", err)
+			}
+			if !cmp.Equal(dfInfo, testcase.want, cmpopts.EquateEmpty()) {
+				t.Fatalf("the parsed Dockerfile info is wrong. Difference:\n%s", cmp.Diff(testcase.want, dfInfo, cmpopts.EquateEmpty()))
+			}
+		})
+	}
+}
+
+func TestGetImageName(t *testing.T) {
+	answersPath := filepath.Join(t.TempDir(), "answers.json")
+	answers := `{
+		"move2kube.services.\"custom-svc\".imagename": "my-image",
+		"move2kube.services.\"empty-svc\".imagename": "  "
+	}`
+	if err := ioutil.WriteFile(answersPath, []byte(answers), common.DefaultFilePermission); err != nil {
+		t.Fatalf("failed to write the answers file. Error: %q", err)
+	}
+	qaengine.AddEngine(qaengine.NewDefaultEngine())
+	if err := qaengine.AddAnswersFile(answersPath); err != nil {
+		t.Fatalf("failed to add the answers file. Error: %q", err)
+	}
+	parser := DockerfileParser{}
+	testcases := []struct {
+		serviceName string
+		want        string
+	}{
+		{serviceName: "Default_Svc", want: common.MakeStringContainerImageNameCompliant("Default_Svc")},
+		{serviceName: "custom-svc", want: "my-image"},
+		{serviceName: "empty-svc", want: "empty-svc"},
+	}
+	for _, testcase := range testcases {
+		if imageName := parser.getImageName(testcase.serviceName); imageName != testcase.want {
+			t.Fatalf("wrong image name for the service %s . Expected: %s Actual: %s", testcase.serviceName, testcase.want, imageName)
+		}
+	}
+}
+
+func TestGeneratedAtAnnotation(t *testing.T) {
+	oldValue, wasSet := os.LookupEnv("SOURCE_DATE_EPOCH")
+	oldReproducible := common.Reproducible
+	t.Cleanup(func() {
+		common.Reproducible = oldReproducible
+		if wasSet {
+			os.Setenv("SOURCE_DATE_EPOCH", oldValue)
+			return
+		}
+		os.Unsetenv("SOURCE_DATE_EPOCH")
+	})
+	dockerfilePath := writeDockerfile(t, "FROM nginx\nEXPOSE 8080\n")
+	parser := DockerfileParser{Env: &environment.Environment{ProjectName: "myproject"}}
+	getAnnotations := func() map[string]string {
+		ir, err := parser.GetIRFromDockerfile(dockerfilePath, "", "myimage", "mysvc")
+		if err != nil {
+			t.Fatalf("failed to get the IR from the Dockerfile at path %s . Error: %q", dockerfilePath, err)
+		}
+		return ir.Services["mysvc"].Annotations
+	}
+	os.Unsetenv("SOURCE_DATE_EPOCH")
+	common.Reproducible = false
+	if annotations := getAnnotations(); annotations[common.GeneratedAtAnnotation] == "" {
+		t.Fatalf("expected the service to be annotated with the time it was generated at. Actual: %+v", annotations)
+	}
+	common.Reproducible = true
+	if annotations := getAnnotations(); annotations[common.GeneratedAtAnnotation] != "" {
+		t.Fatalf("expected the timestamp to be omitted for reproducible output. Actual: %+v", annotations)
+	}
+	os.Setenv("SOURCE_DATE_EPOCH", "1600000000")
+	if annotations := getAnnotations(); annotations[common.GeneratedAtAnnotation] != "2020-09-13T12:26:40Z" {
+		t.Fatalf("expected the timestamp from SOURCE_DATE_EPOCH. Actual: %+v", annotations)
+	}
+}
+
+func TestServiceNameLabelWithNaming(t *testing.T) {
+	dockerfilePath := writeDockerfile(t, "FROM nginx\nLABEL move2kube.konveyor.io/service-name=\"My_App\"\nEXPOSE 8080\n")
+	parser := DockerfileParser{
+		Env:                    &environment.Environment{ProjectName: "myproject"},
+		DockerfileParserConfig: DockerfileParserYamlConfig{Naming: NamingConfig{Prefix: "Team-", Suffix: "-v2", Lowercase: true}},
+	}
+	ir, err := parser.GetIRFromDockerfile(dockerfilePath, "", "myimage", "mysvc")
+	if err != nil {
+		t.Fatalf("failed to get the IR from the Dockerfile at path %s . Error: %q", dockerfilePath, err)
+	}
+	irService, ok := ir.Services["team-my-app-v2"]
+	if !ok || len(ir.Services) != 1 {
+		t.Fatalf("expected the naming to be applied to the service name from the label. Actual: %+v", ir.Services)
+	}
+	if irService.Containers[0].Name != "team-my-app-v2" {
+		t.Fatalf("expected the container to be named after the label. Actual: %+v", irService.Containers[0])
+	}
+}
+
+func TestDockerfileInfoIsAddedToTheContainer(t *testing.T) {
+	dockerfilePath := writeDockerfile(t, "FROM node\nENV A=1 PATH=/app/bin:$PATH\nVOLUME /data\nWORKDIR /app\nUSER 1001:1002\nEXPOSE 8080\n")
+	ir, err := ParseDockerfileToIR(dockerfilePath, "myproject", "myimage", "mysvc")
+	if err != nil {
+		t.Fatalf("failed to get the IR from the Dockerfile at path %s . Error: %q", dockerfilePath, err)
+	}
+	irService := ir.Services["mysvc"]
+	container := irService.Containers[0]
+	if !cmp.Equal(container.Env, []core.EnvVar{{Name: "A", Value: "1"}}) {
+		t.Fatalf("expected only the environment variables without references to be added. Actual: %+v", container.Env)
+	}
+	if container.WorkingDir != "/app" {
+		t.Fatalf("expected the working directory to be set. Actual: %s", container.WorkingDir)
+	}
+	if container.SecurityContext == nil || *container.SecurityContext.RunAsUser != 1001 || *container.SecurityContext.RunAsGroup != 1002 {
+		t.Fatalf("expected the user and the group to be set. Actual: %+v", container.SecurityContext)
+	}
+	if len(container.VolumeMounts) != 1 || container.VolumeMounts[0].MountPath != "/data" || len(irService.Volumes) != 1 || irService.Volumes[0].Name != container.VolumeMounts[0].Name || irService.Volumes[0].EmptyDir == nil {
+		t.Fatalf("expected the volume to be backed by an emptyDir volume. Actual: %+v %+v", container.VolumeMounts, irService.Volumes)
+	}
+
+	dockerfilePath = writeDockerfile(t, "FROM node\nUSER node\n")
+	ir, err = ParseDockerfileToIR(dockerfilePath, "myproject", "myimage", "mysvc")
+	if err != nil {
+		t.Fatalf("failed to get the IR from the Dockerfile at path %s . Error: %q", dockerfilePath, err)
+	}
+	if securityContext := ir.Services["mysvc"].Containers[0].SecurityContext; securityContext != nil {
+		t.Fatalf("expected a non numeric user to be ignored. Actual: %+v", securityContext)
+	}
+
+	dockerfilePath = writeDockerfile(t, "EXPOSE 8080\n")
+	if _, err := ParseDockerfileToIR(dockerfilePath, "myproject", "myimage", "mysvc"); err == nil || !strings.Contains(err.Error(), "does not have a FROM instruction") {
+		t.Fatalf("expected the error about the missing FROM instruction. Actual: %v", err)
+	}
+}
@@ -0,0 +1,119 @@
+/*
+ *  Copyright IBM Corporation 2021
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package analysers
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/konveyor/move2kube/internal/common"
+	"github.com/sirupsen/logrus"
+)
+
+// frameworkPortFile is a well known file that can declare the port the service listens on
+type frameworkPortFile struct {
+	// relPath is the path of the file relative to the service directory
+	relPath string
+	// portRegex has the port as its first sub match
+	portRegex *regexp.Regexp
+}
+
+// frameworkPortFiles are checked in order and the ports from the first file that declares any are used
+var frameworkPortFiles = []frameworkPortFile{
+	{relPath: "src/main/resources/application.properties", portRegex: regexp.MustCompile(`(?m)^\s*server\.port\s*[=:]\s*(\d+)\s*$`)},
+	{relPath: "src/main/resources/application.yaml", portRegex: regexp.MustCompile(`(?m)^server:\s*\n(?:[ \t]+.*\n)*?[ \t]+port:\s*(\d+)\s*$`)},
+	{relPath: "src/main/resources/application.yml", portRegex: regexp.MustCompile(`(?m)^server:\s*\n(?:[ \t]+.*\n)*?[ \t]+port:\s*(\d+)\s*$`)},
+	{relPath: "application.properties", portRegex: regexp.MustCompile(`(?m)^\s*server\.port\s*[=:]\s*(\d+)\s*$`)},
+	{relPath: "package.json", portRegex: regexp.MustCompile(`(?:\bPORT=|--port[= ]|"port"\s*:\s*)(\d+)`)},
+	{relPath: ".env", portRegex: regexp.MustCompile(`(?m)^\s*(?:export\s+)?PORT\s*=\s*["']?(\d+)["']?\s*$`)},
+	{relPath: "app.py", portRegex: regexp.MustCompile(`\bport\s*=\s*(\d+)`)},
+	{relPath: "main.py", portRegex: regexp.MustCompile(`\bport\s*=\s*(\d+)`)},
+}
+
+// detectFrameworkPorts returns the ports declared in the well known framework files in the service directory.
+// It returns nil if none of the files declare a port.
+func detectFrameworkPorts(logger *logrus.Entry, serviceDir string) []int {
+	for _, portFile := range frameworkPortFiles {
+		portFilePath := filepath.Join(serviceDir, portFile.relPath)
+		contents, err := ioutil.ReadFile(portFilePath)
+		if err != nil {
+			if !os.IsNotExist(err) {
+				logger.Debugf("Unable to read the file at path %s to detect the ports : %s", portFilePath, err)
+			}
+			continue
+		}
+		ports := []int{}
+		for _, match := range portFile.portRegex.FindAllStringSubmatch(string(contents), -1) {
+			port, err := strconv.Atoi(match[1])
+			if err == nil {
+				err = validatePort(port)
+			}
+			if err != nil {
+				logger.Debugf("Ignoring the invalid port %s in the file at path %s : %s", match[1], portFilePath, err)
+				continue
+			}
+			if !common.IsIntPresent(ports, port) {
+				ports = append(ports, port)
+			}
+		}
+		if len(ports) > 0 {
+			logger.Infof("Detected the ports %v from the file at path %s", ports, portFilePath)
+			return ports
+		}
+	}
+	return nil
+}
+
+// detectPortsFromCommand returns the ports passed using the --port and -p flags in the command.
+// Both the "--port 3000" and the "--port=3000" forms are detected. Shell form commands are split on whitespace.
+func detectPortsFromCommand(logger *logrus.Entry, command []string) []int {
+	tokens := []string{}
+	for _, arg := range command {
+		tokens = append(tokens, strings.Fields(arg)...)
+	}
+	ports := []int{}
+	for i, token := range tokens {
+		portStr := ""
+		if (token == "--port" || token == "-p") && i+1 < len(tokens) {
+			portStr = tokens[i+1]
+		} else if strings.HasPrefix(token, "--port=") {
+			portStr = strings.TrimPrefix(token, "--port=")
+		} else {
+			continue
+		}
+		portStr = common.StripQuotes(portStr)
+		port, err := strconv.Atoi(portStr)
+		if err == nil {
+			err = validatePort(port)
+		}
+		if err != nil {
+			logger.Debugf("Ignoring the invalid port %s in the command %v : %s", portStr, command, err)
+			continue
+		}
+		if !common.IsIntPresent(ports, port) {
+			ports = append(ports, port)
+		}
+	}
+	if len(ports) > 0 {
+		logger.Infof("Inferred the ports %v from the port flags in the command %v since the dockerfile doesn't expose any ports", ports, command)
+	}
+	return ports
+}
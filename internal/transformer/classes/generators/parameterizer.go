@@ -17,6 +17,7 @@
 package generators
 
 import (
+	"context"
 	"path/filepath"
 
 	"github.com/konveyor/move2kube/environment"
@@ -59,7 +60,7 @@ func (t *Parameterizer) DirectoryDetect(dir string) (namedServices map[string]pl
 // Transform transforms artifacts
 func (t *Parameterizer) Transform(newArtifacts []transformertypes.Artifact, oldArtifacts []transformertypes.Artifact) (pathMappings []transformertypes.PathMapping, createdArtifacts []transformertypes.Artifact, err error) {
 	pathMappings = []transformertypes.PathMapping{}
-	psmap, err := parameterizer.CollectParamsFromPath(t.Env.Context)
+	psmap, err := parameterizer.CollectParamsFromPath(logrus.StandardLogger(), t.Env.Context)
 	if err != nil {
 		logrus.Errorf("Error while parsing for params : %s", err)
 		return nil, nil, err
@@ -74,7 +75,7 @@ func (t *Parameterizer) Transform(newArtifacts []transformertypes.Artifact, oldA
 		}
 		yamlsPath := a.Paths[artifacts.KubernetesYamlsPathType][0]
 		destPath := yamlsPath + "-parameterized"
-		filesWritten, err := parameterizer.Parameterize(yamlsPath, destPath, parameterizertypes.PackagingSpecPathT{}, ps)
+		filesWritten, err := parameterizer.Parameterize(context.Background(), parameterizer.ParameterizeOptions{SrcDirs: []string{yamlsPath}, OutDir: destPath, Parameterizers: ps})
 		if err != nil {
 			logrus.Errorf("Unable to parameterize : %s", err)
 		}
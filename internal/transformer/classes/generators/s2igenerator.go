@@ -96,7 +96,7 @@ func (t *S2IGenerator) Transform(newArtifacts []transformertypes.Artifact, oldAr
 			// Forward the port on the k8s service to the k8s pod.
 			podPort := irtypes.Port{Number: int32(port)}
 			servicePort := podPort
-			irService.AddPortForwarding(servicePort, podPort)
+			irService.AddPortForwarding(servicePort, podPort, "")
 		}
 		serviceContainer.Ports = serviceContainerPorts
 		irService.Containers = []core.Container{serviceContainer}
@@ -0,0 +1,57 @@
+/*
+ *  Copyright IBM Corporation 2020, 2021
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package lib
+
+import (
+	"github.com/konveyor/move2kube/environment"
+	"github.com/konveyor/move2kube/internal/transformer/classes/analysers"
+	irtypes "github.com/konveyor/move2kube/types/ir"
+	transformertypes "github.com/konveyor/move2kube/types/transformer"
+)
+
+// DockerfileParserConfig is the config of the DockerfileParser transformer
+type DockerfileParserConfig = analysers.DockerfileParserYamlConfig
+
+// DockerfileInstructionHandler is called by the DockerfileParser for each instruction in the Dockerfile.
+// The node is a top level node of the Dockerfile AST. node.Value is the lower case instruction name (like "expose"),
+// node.Next is the linked list of its arguments and node.Original is the line as written in the Dockerfile.
+// The handler is called after the default handling, so it can augment or override the container of the service.
+// Dockerfiles are parsed concurrently, so the handler must be safe for concurrent use.
+type DockerfileInstructionHandler = analysers.DockerfileInstructionHandler
+
+// RegisterDockerfileInstructionHandler registers a handler that can be selected using the
+// instructionHandler field in the config of the DockerfileParser transformer
+func RegisterDockerfileInstructionHandler(name string, handler DockerfileInstructionHandler) {
+	analysers.RegisterDockerfileInstructionHandler(name, handler)
+}
+
+// ParseDockerfileToIR creates an IR containing a single service from the Dockerfile
+func ParseDockerfileToIR(dockerfilePath, projectName, imageName, serviceName string) (irtypes.IR, error) {
+	return analysers.ParseDockerfileToIR(dockerfilePath, projectName, imageName, serviceName)
+}
+
+// GetIRFromDockerfile returns the IR for the Dockerfile after applying the config of the DockerfileParser transformer.
+// If the context path is empty, the directory containing the Dockerfile is used as the build context.
+func GetIRFromDockerfile(config DockerfileParserConfig, dockerfilePath, contextPath, projectName, imageName, serviceName string) (irtypes.IR, error) {
+	t := &analysers.DockerfileParser{}
+	tc := transformertypes.Transformer{}
+	tc.Spec.Config = config
+	if err := t.Init(tc, &environment.Environment{ProjectName: projectName}); err != nil {
+		return irtypes.IR{}, err
+	}
+	return t.GetIRFromDockerfile(dockerfilePath, contextPath, imageName, serviceName)
+}
@@ -0,0 +1,67 @@
+/*
+ *  Copyright IBM Corporation 2021
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package lib_test
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/konveyor/move2kube/lib"
+	dockerparser "github.com/moby/buildkit/frontend/dockerfile/parser"
+	core "k8s.io/kubernetes/pkg/apis/core"
+)
+
+type labelToEnvHandler struct{}
+
+func (labelToEnvHandler) HandleInstruction(node *dockerparser.Node, container *core.Container) error {
+	if node.Value != "label" {
+		return nil
+	}
+	for n := node.Next; n != nil && n.Next != nil; n = n.Next.Next {
+		container.Env = append(container.Env, core.EnvVar{Name: n.Value, Value: n.Next.Value})
+	}
+	return nil
+}
+
+func TestGetIRFromDockerfile(t *testing.T) {
+	lib.RegisterDockerfileInstructionHandler("lib-labeltoenv", labelToEnvHandler{})
+	dockerfilePath := filepath.Join(t.TempDir(), "Dockerfile")
+	if err := ioutil.WriteFile(dockerfilePath, []byte("FROM nginx\nLABEL APP_VERSION=1.0\nEXPOSE 8080\n"), 0644); err != nil {
+		t.Fatalf("Failed to write the Dockerfile. Error: %q", err)
+	}
+	config := lib.DockerfileParserConfig{Registry: "quay.io/myorg", InstructionHandler: "lib-labeltoenv"}
+	ir, err := lib.GetIRFromDockerfile(config, dockerfilePath, "", "myproject", "myimage", "mysvc")
+	if err != nil {
+		t.Fatalf("Failed to get the IR from the Dockerfile. Error: %q", err)
+	}
+	service, ok := ir.Services["mysvc"]
+	if !ok || len(service.Containers) != 1 {
+		t.Fatalf("Expected the IR to have the service mysvc with a single container. Actual: %+v", ir.Services)
+	}
+	container := service.Containers[0]
+	if container.Image != "quay.io/myorg/myimage" {
+		t.Fatalf("Expected the registry in the config to be applied to the image. Actual: %s", container.Image)
+	}
+	if want := []core.EnvVar{{Name: "APP_VERSION", Value: "1.0"}}; !cmp.Equal(container.Env, want) {
+		t.Fatalf("Expected the registered handler to add the env vars %+v . Actual: %+v", want, container.Env)
+	}
+	if _, err := lib.GetIRFromDockerfile(lib.DockerfileParserConfig{InstructionHandler: "missing"}, dockerfilePath, "", "myproject", "myimage", "mysvc"); err == nil {
+		t.Fatalf("Expected an error for a handler that is not registered")
+	}
+}
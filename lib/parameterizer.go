@@ -17,6 +17,8 @@
 package lib
 
 import (
+	"context"
+	"io"
 	"path/filepath"
 
 	"github.com/konveyor/move2kube/internal/common"
@@ -25,17 +27,50 @@ import (
 	"github.com/sirupsen/logrus"
 )
 
-// Parameterize does the parameterization
-func Parameterize(srcDir string, packDir string, outDir string) ([]string, error) {
-	cleanPackDir, err := filepath.Abs(packDir)
+// ParameterizeOptions are the inputs to Parameterize
+type ParameterizeOptions struct {
+	// SrcDirs are the directories containing the k8s resources. They are parameterized together into the output directory.
+	SrcDirs []string
+	// PackDir is the directory containing the packagings and the parameterizers
+	PackDir string
+	// OutDir is the directory where the outputs are written
+	OutDir string
+	// Kinds are the kinds of k8s resources to parameterize. If empty, all the kinds are parameterized.
+	Kinds []string
+	// Targets are the outputs (Helm, Kustomize, Kubernetes) to write. If empty, the default outputs are written.
+	Targets []parameterizertypes.ParamTargetT
+	// OverwriteValues overwrites the existing Helm values files instead of merging the new values into them
+	OverwriteValues bool
+	// HelmChartName overrides the name of the Helm chart in the packagings
+	HelmChartName string
+	// HelmChartVersion overrides the version of the Helm chart in the packagings
+	HelmChartVersion string
+	// DiffOut receives a unified diff between each source file and its parameterized output. If nil, no diff is generated.
+	DiffOut io.Writer
+	// Header starts each k8s resource file with a comment noting the source file and the time of generation
+	Header bool
+	// Reproducible omits the timestamp from the headers unless it is pinned using the SOURCE_DATE_EPOCH environment variable
+	Reproducible bool
+	// Logger is used for the logs. If nil, the standard logger of logrus is used.
+	Logger *logrus.Logger
+}
+
+// Parameterize does the parameterization.
+// Cancelling the context stops the parameterization and returns the files written so far.
+func Parameterize(ctx context.Context, opts ParameterizeOptions) ([]string, error) {
+	logger := opts.Logger
+	if logger == nil {
+		logger = logrus.StandardLogger()
+	}
+	cleanPackDir, err := filepath.Abs(opts.PackDir)
 	if err != nil {
 		return nil, err
 	}
-	packs, err := collectPacksFromPath(cleanPackDir)
+	packs, err := collectPacksFromPath(logger, cleanPackDir)
 	if err != nil {
 		return nil, err
 	}
-	namedPs, err := parameterizer.CollectParamsFromPath(cleanPackDir)
+	namedPs, err := parameterizer.CollectParamsFromPath(logger, cleanPackDir)
 	if err != nil {
 		return nil, err
 	}
@@ -47,13 +82,34 @@ func Parameterize(srcDir string, packDir string, outDir string) ([]string, error
 				ps = append(ps, currPs...)
 				continue
 			}
-			logrus.Errorf("failed to find the paramterizers with the name %s referred to by the packaging with the name %s , in the folder %s", name, pack.ObjectMeta.Name, cleanPackDir)
+			logger.Errorf("failed to find the paramterizers with the name %s referred to by the packaging with the name %s , in the folder %s", name, pack.ObjectMeta.Name, cleanPackDir)
 		}
 		ps = append(ps, pack.Spec.Parameterizers...)
 		for _, path := range pack.Spec.Paths {
-			fw, err := parameterizer.Parameterize(srcDir, outDir, path, ps)
+			path.OverwriteValues = path.OverwriteValues || opts.OverwriteValues
+			if opts.HelmChartName != "" {
+				path.HelmChartName = opts.HelmChartName
+			}
+			if opts.HelmChartVersion != "" {
+				path.HelmChartVersion = opts.HelmChartVersion
+			}
+			fw, err := parameterizer.Parameterize(ctx, parameterizer.ParameterizeOptions{
+				SrcDirs:        opts.SrcDirs,
+				OutDir:         opts.OutDir,
+				PackSpecPath:   path,
+				Parameterizers: ps,
+				Kinds:          opts.Kinds,
+				Targets:        opts.Targets,
+				DiffOut:        opts.DiffOut,
+				Header:         opts.Header,
+				Reproducible:   opts.Reproducible,
+				Logger:         logger,
+			})
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				return append(filesWritten, fw...), ctxErr
+			}
 			if err != nil {
-				logrus.Errorf("Unable to process path %s : %s", path.Src, err)
+				logger.Errorf("Unable to process path %s : %s", path.Src, err)
 				continue
 			}
 			filesWritten = append(filesWritten, fw...)
@@ -62,7 +118,22 @@ func Parameterize(srcDir string, packDir string, outDir string) ([]string, error
 	return filesWritten, nil
 }
 
-func collectPacksFromPath(packDir string) ([]parameterizertypes.PackagingFileT, error) {
+// WriteFilesToStream writes the parameterized files as a multi document YAML stream.
+func WriteFilesToStream(w io.Writer, outDir string, filesWritten []string) error {
+	return parameterizer.WriteFilesToStream(w, outDir, filesWritten)
+}
+
+// ValidatePack checks the syntax of all the key expressions in the pack directory without reading the source.
+// If the logger is nil, the standard logger of logrus is used.
+func ValidatePack(logger *logrus.Logger, packDir string) ([]parameterizer.PackKeyError, error) {
+	cleanPackDir, err := filepath.Abs(packDir)
+	if err != nil {
+		return nil, err
+	}
+	return parameterizer.ValidatePack(logger, cleanPackDir)
+}
+
+func collectPacksFromPath(logger *logrus.Logger, packDir string) ([]parameterizertypes.PackagingFileT, error) {
 	yamlPaths, err := common.GetFilesByExt(packDir, []string{".yaml", ".yml"})
 	if err != nil {
 		return nil, err
@@ -75,7 +146,7 @@ func collectPacksFromPath(packDir string) ([]parameterizertypes.PackagingFileT,
 			},
 		}
 		if err := common.ReadMove2KubeYamlStrict(yamlPath, &pack, parameterizertypes.PackagingKind); err == nil {
-			logrus.Debugf("found packing yaml at path %s", yamlPath)
+			logger.Debugf("found packing yaml at path %s", yamlPath)
 			packs = append(packs, pack)
 			continue
 		}
@@ -17,13 +17,17 @@
 package lib_test
 
 import (
+	"bytes"
+	"context"
 	"io/ioutil"
+	"os"
 	"path/filepath"
 	"strings"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/konveyor/move2kube/lib"
+	parameterizertypes "github.com/konveyor/move2kube/types/parameterizer"
 	log "github.com/sirupsen/logrus"
 )
 
@@ -39,12 +43,12 @@ func TestGettingAndParameterizingResources(t *testing.T) {
 	k8sResourcesPath := filepath.Join(baseDir, "k8s-resources")
 	outputPath := t.TempDir()
 
-	filesWritten, err := lib.Parameterize(k8sResourcesPath, parameterizersPath, outputPath)
+	filesWritten, err := lib.Parameterize(context.Background(), lib.ParameterizeOptions{SrcDirs: []string{k8sResourcesPath}, PackDir: parameterizersPath, OutDir: outputPath})
 	if err != nil {
 		t.Fatalf("Failed to apply all the parameterizations. Error: %q", err)
 	}
-	if len(filesWritten) != 26 {
-		t.Fatalf("Expected %d files to be written. Actual: %d", 26, len(filesWritten))
+	if len(filesWritten) != 27 {
+		t.Fatalf("Expected %d files to be written. Actual: %d", 27, len(filesWritten))
 	}
 	wantDataDir := filepath.Join(baseDir, "want")
 	for _, fileWritten := range filesWritten {
@@ -69,3 +73,227 @@ func TestGettingAndParameterizingResources(t *testing.T) {
 		}
 	}
 }
+
+func TestParameterizeCancelled(t *testing.T) {
+	relBaseDir := "testdata"
+	baseDir, err := filepath.Abs(relBaseDir)
+	if err != nil {
+		t.Fatalf("Failed to make the base directory %s absolute path. Error: %q", relBaseDir, err)
+	}
+	parameterizersPath := filepath.Join(baseDir, "parameterizers")
+	k8sResourcesPath := filepath.Join(baseDir, "k8s-resources")
+	outputPath := t.TempDir()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := lib.Parameterize(ctx, lib.ParameterizeOptions{SrcDirs: []string{k8sResourcesPath}, PackDir: parameterizersPath, OutDir: outputPath}); err != context.Canceled {
+		t.Fatalf("Expected the parameterization to be cancelled. Actual error: %+v", err)
+	}
+}
+
+func TestParameterizeIsReproducible(t *testing.T) {
+	relBaseDir := "testdata"
+	baseDir, err := filepath.Abs(relBaseDir)
+	if err != nil {
+		t.Fatalf("Failed to make the base directory %s absolute path. Error: %q", relBaseDir, err)
+	}
+	parameterizersPath := filepath.Join(baseDir, "parameterizers")
+	k8sResourcesPath := filepath.Join(baseDir, "k8s-resources")
+	outputPath1, outputPath2 := t.TempDir(), t.TempDir()
+
+	filesWritten1, err := lib.Parameterize(context.Background(), lib.ParameterizeOptions{SrcDirs: []string{k8sResourcesPath}, PackDir: parameterizersPath, OutDir: outputPath1})
+	if err != nil {
+		t.Fatalf("Failed to apply all the parameterizations. Error: %q", err)
+	}
+	if _, err := lib.Parameterize(context.Background(), lib.ParameterizeOptions{SrcDirs: []string{k8sResourcesPath}, PackDir: parameterizersPath, OutDir: outputPath2}); err != nil {
+		t.Fatalf("Failed to apply all the parameterizations. Error: %q", err)
+	}
+	for _, fileWritten := range filesWritten1 {
+		relFilePath, err := filepath.Rel(outputPath1, fileWritten)
+		if err != nil {
+			t.Fatalf("failed to make the file path %s relative to the output path %s . Error: %q", fileWritten, outputPath1, err)
+		}
+		bytes1, err := ioutil.ReadFile(fileWritten)
+		if err != nil {
+			t.Fatalf("Failed to read the output data at path %s . Error: %q", fileWritten, err)
+		}
+		bytes2, err := ioutil.ReadFile(filepath.Join(outputPath2, relFilePath))
+		if err != nil {
+			t.Fatalf("Failed to read the output data at path %s . Error: %q", filepath.Join(outputPath2, relFilePath), err)
+		}
+		if !cmp.Equal(string(bytes1), string(bytes2)) {
+			t.Fatalf("The file %s is different between runs. Differences:\n%s", relFilePath, cmp.Diff(string(bytes1), string(bytes2)))
+		}
+	}
+}
+
+func TestParameterizeSelectedTargets(t *testing.T) {
+	relBaseDir := "testdata"
+	baseDir, err := filepath.Abs(relBaseDir)
+	if err != nil {
+		t.Fatalf("Failed to make the base directory %s absolute path. Error: %q", relBaseDir, err)
+	}
+	parameterizersPath := filepath.Join(baseDir, "parameterizers")
+	k8sResourcesPath := filepath.Join(baseDir, "k8s-resources")
+	outputPath := t.TempDir()
+
+	targets := []parameterizertypes.ParamTargetT{parameterizertypes.TargetHelm}
+	filesWritten, err := lib.Parameterize(context.Background(), lib.ParameterizeOptions{SrcDirs: []string{k8sResourcesPath}, PackDir: parameterizersPath, OutDir: outputPath, Targets: targets})
+	if err != nil {
+		t.Fatalf("Failed to apply all the parameterizations. Error: %q", err)
+	}
+	if len(filesWritten) == 0 {
+		t.Fatalf("Expected the Helm chart files to be written")
+	}
+	for _, fileWritten := range filesWritten {
+		relFilePath, err := filepath.Rel(outputPath, fileWritten)
+		if err != nil {
+			t.Fatalf("failed to make the file path %s relative to the output path %s . Error: %q", fileWritten, outputPath, err)
+		}
+		if !strings.HasPrefix(relFilePath, "helm-chart/") {
+			t.Fatalf("Expected only the Helm chart files to be written. Actual: %s", relFilePath)
+		}
+	}
+}
+
+func TestParameterizeMultipleSources(t *testing.T) {
+	relBaseDir := "testdata"
+	baseDir, err := filepath.Abs(relBaseDir)
+	if err != nil {
+		t.Fatalf("Failed to make the base directory %s absolute path. Error: %q", relBaseDir, err)
+	}
+	parameterizersPath := filepath.Join(baseDir, "parameterizers")
+	k8sResourcesPath := filepath.Join(baseDir, "k8s-resources")
+	otherK8sResourcesPath := filepath.Join(t.TempDir(), "other")
+	if err := os.MkdirAll(otherK8sResourcesPath, 0755); err != nil {
+		t.Fatalf("Failed to create the directory %s . Error: %q", otherK8sResourcesPath, err)
+	}
+	depBytes, err := ioutil.ReadFile(filepath.Join(k8sResourcesPath, "dep-v1.yaml"))
+	if err != nil {
+		t.Fatalf("Failed to read the k8s resource. Error: %q", err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(otherK8sResourcesPath, "dep-v1.yaml"), depBytes, 0644); err != nil {
+		t.Fatalf("Failed to write the k8s resource. Error: %q", err)
+	}
+	outputPath := t.TempDir()
+
+	targets := []parameterizertypes.ParamTargetT{parameterizertypes.TargetHelm}
+	if _, err := lib.Parameterize(context.Background(), lib.ParameterizeOptions{SrcDirs: []string{k8sResourcesPath, otherK8sResourcesPath}, PackDir: parameterizersPath, OutDir: outputPath, Targets: targets}); err != nil {
+		t.Fatalf("Failed to apply all the parameterizations. Error: %q", err)
+	}
+	templatesDir := filepath.Join(outputPath, "helm-chart", "myproject", "templates")
+	for _, name := range []string{"dep-v1.yaml", "dep-v1beta1.yaml", "namespace-v1.yaml", "other-dep-v1.yaml"} {
+		if _, err := os.Stat(filepath.Join(templatesDir, name)); err != nil {
+			t.Fatalf("Expected the file %s to be written. Error: %q", name, err)
+		}
+	}
+}
+
+func TestParameterizeMergesExistingValues(t *testing.T) {
+	relBaseDir := "testdata"
+	baseDir, err := filepath.Abs(relBaseDir)
+	if err != nil {
+		t.Fatalf("Failed to make the base directory %s absolute path. Error: %q", relBaseDir, err)
+	}
+	parameterizersPath := filepath.Join(baseDir, "parameterizers")
+	k8sResourcesPath := filepath.Join(baseDir, "k8s-resources")
+	targets := []parameterizertypes.ParamTargetT{parameterizertypes.TargetHelm}
+	runWithExistingValues := func(t *testing.T, overwriteValues bool) string {
+		outputPath := t.TempDir()
+		valuesPath := filepath.Join(outputPath, "helm-chart", "myproject", "values-dev.yaml")
+		if err := os.MkdirAll(filepath.Dir(valuesPath), 0755); err != nil {
+			t.Fatalf("failed to create the helm chart directory. Error: %q", err)
+		}
+		userValues := "common:\n  replicas: 3\nuserKey: userValue\n"
+		if err := ioutil.WriteFile(valuesPath, []byte(userValues), 0644); err != nil {
+			t.Fatalf("failed to write the existing values file. Error: %q", err)
+		}
+		if _, err := lib.Parameterize(context.Background(), lib.ParameterizeOptions{SrcDirs: []string{k8sResourcesPath}, PackDir: parameterizersPath, OutDir: outputPath, Targets: targets, OverwriteValues: overwriteValues}); err != nil {
+			t.Fatalf("Failed to apply all the parameterizations. Error: %q", err)
+		}
+		valuesBytes, err := ioutil.ReadFile(valuesPath)
+		if err != nil {
+			t.Fatalf("failed to read the values file at path %s . Error: %q", valuesPath, err)
+		}
+		return string(valuesBytes)
+	}
+	t.Run("existing values are preserved", func(t *testing.T) {
+		values := runWithExistingValues(t, false)
+		for _, want := range []string{"replicas: 3", "userKey: userValue", "url: us.icr.io"} {
+			if !strings.Contains(values, want) {
+				t.Fatalf("expected the merged values to contain %q . Actual:\n%s", want, values)
+			}
+		}
+	})
+	t.Run("existing values are overwritten", func(t *testing.T) {
+		values := runWithExistingValues(t, true)
+		if strings.Contains(values, "userKey") || !strings.Contains(values, "replicas: 10") {
+			t.Fatalf("expected the values to be overwritten. Actual:\n%s", values)
+		}
+	})
+}
+
+func TestParameterizeUsesLogger(t *testing.T) {
+	relBaseDir := "testdata"
+	baseDir, err := filepath.Abs(relBaseDir)
+	if err != nil {
+		t.Fatalf("Failed to make the base directory %s absolute path. Error: %q", relBaseDir, err)
+	}
+	parameterizersPath := filepath.Join(baseDir, "parameterizers")
+	k8sResourcesPath := filepath.Join(baseDir, "k8s-resources")
+	outputPath := t.TempDir()
+
+	logs := &bytes.Buffer{}
+	logger := log.New()
+	logger.SetOutput(logs)
+	logger.SetLevel(log.DebugLevel)
+	if _, err := lib.Parameterize(context.Background(), lib.ParameterizeOptions{SrcDirs: []string{k8sResourcesPath}, PackDir: parameterizersPath, OutDir: outputPath, Logger: logger}); err != nil {
+		t.Fatalf("Failed to apply all the parameterizations. Error: %q", err)
+	}
+	for _, want := range []string{"found packing yaml at path", "found paramterizer yaml at path"} {
+		if !strings.Contains(logs.String(), want) {
+			t.Fatalf("expected the logger to receive the log %q . Actual:\n%s", want, logs.String())
+		}
+	}
+}
+
+func TestParameterizeSourcesWithTheSameName(t *testing.T) {
+	relBaseDir := "testdata"
+	baseDir, err := filepath.Abs(relBaseDir)
+	if err != nil {
+		t.Fatalf("Failed to make the base directory %s absolute path. Error: %q", relBaseDir, err)
+	}
+	parameterizersPath := filepath.Join(baseDir, "parameterizers")
+	k8sResourcesPath := filepath.Join(baseDir, "k8s-resources")
+	depBytes, err := ioutil.ReadFile(filepath.Join(k8sResourcesPath, "dep-v1.yaml"))
+	if err != nil {
+		t.Fatalf("Failed to read the k8s resource. Error: %q", err)
+	}
+	srcDirs := []string{k8sResourcesPath}
+	for _, parentDir := range []string{"a", "b"} {
+		srcDir := filepath.Join(t.TempDir(), parentDir, "k8s-resources")
+		if err := os.MkdirAll(srcDir, 0755); err != nil {
+			t.Fatalf("Failed to create the directory %s . Error: %q", srcDir, err)
+		}
+		if err := ioutil.WriteFile(filepath.Join(srcDir, "dep-v1.yaml"), depBytes, 0644); err != nil {
+			t.Fatalf("Failed to write the k8s resource. Error: %q", err)
+		}
+		srcDirs = append(srcDirs, srcDir)
+	}
+	outputPath := t.TempDir()
+
+	targets := []parameterizertypes.ParamTargetT{parameterizertypes.TargetHelm}
+	if _, err := lib.Parameterize(context.Background(), lib.ParameterizeOptions{SrcDirs: srcDirs, PackDir: parameterizersPath, OutDir: outputPath, Targets: targets}); err != nil {
+		t.Fatalf("Failed to apply all the parameterizations. Error: %q", err)
+	}
+	templatesDir := filepath.Join(outputPath, "helm-chart", "myproject", "templates")
+	for _, name := range []string{"dep-v1.yaml", "k8s-resources-dep-v1.yaml", "k8s-resources-dep-v1-2.yaml"} {
+		fileBytes, err := ioutil.ReadFile(filepath.Join(templatesDir, name))
+		if err != nil {
+			t.Fatalf("Expected the file %s to be written. Error: %q", name, err)
+		}
+		if count := strings.Count(string(fileBytes), "kind: Deployment"); count != 1 {
+			t.Fatalf("Expected the file %s to have a single Deployment. Actual: %d", name, count)
+		}
+	}
+}
@@ -0,0 +1,219 @@
+/*
+ *  Copyright IBM Corporation 2021
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package parameterizer
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	// diffContextLines is the number of unchanged lines shown around each change in a unified diff
+	diffContextLines = 3
+)
+
+type diffOpT struct {
+	kind byte // ' ', '-' or '+'
+	line string
+}
+
+// getUnifiedDiff returns the unified diff between the from and to texts.
+// It returns an empty string if the texts are the same.
+func getUnifiedDiff(fromName, toName, from, to string) string {
+	if from == to {
+		return ""
+	}
+	ops := getDiffOps(splitLines(from), splitLines(to))
+	out := strings.Builder{}
+	out.WriteString("--- " + fromName + "\n")
+	out.WriteString("+++ " + toName + "\n")
+	fromLine, toLine := 1, 1
+	for i := 0; i < len(ops); {
+		if ops[i].kind == ' ' {
+			fromLine++
+			toLine++
+			i++
+			continue
+		}
+		// found a change, include the context before it
+		start := i
+		for start > 0 && i-start < diffContextLines && ops[start-1].kind == ' ' {
+			start--
+		}
+		hunkFromLine, hunkToLine := fromLine-(i-start), toLine-(i-start)
+		// extend the hunk until there are more than twice the context lines without changes
+		end := i
+		for end < len(ops) {
+			if ops[end].kind != ' ' {
+				end++
+				continue
+			}
+			next := end
+			for next < len(ops) && ops[next].kind == ' ' {
+				next++
+			}
+			if next == len(ops) || next-end > 2*diffContextLines {
+				end += min(next-end, diffContextLines)
+				break
+			}
+			end = next
+		}
+		fromCount, toCount := 0, 0
+		hunk := strings.Builder{}
+		for _, op := range ops[start:end] {
+			hunk.WriteString(string(op.kind) + op.line + "\n")
+			if op.kind != '+' {
+				fromCount++
+			}
+			if op.kind != '-' {
+				toCount++
+			}
+		}
+		out.WriteString(fmt.Sprintf("@@ -%s +%s @@\n", getHunkRange(hunkFromLine, fromCount), getHunkRange(hunkToLine, toCount)))
+		out.WriteString(hunk.String())
+		for _, op := range ops[i:end] {
+			if op.kind != '+' {
+				fromLine++
+			}
+			if op.kind != '-' {
+				toLine++
+			}
+		}
+		i = end
+	}
+	return out.String()
+}
+
+// getDiffOps returns the edit script between the lines using the longest common subsequence.
+// It uses Hirschberg's algorithm so that the memory used is linear in the number of lines.
+func getDiffOps(from, to []string) []diffOpT {
+	return appendDiffOps([]diffOpT{}, from, to)
+}
+
+// appendDiffOps appends the edit script between the lines to the ops
+func appendDiffOps(ops []diffOpT, from, to []string) []diffOpT {
+	// the common prefix and suffix are unchanged
+	prefix := 0
+	for prefix < len(from) && prefix < len(to) && from[prefix] == to[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(from)-prefix && suffix < len(to)-prefix && from[len(from)-1-suffix] == to[len(to)-1-suffix] {
+		suffix++
+	}
+	for _, line := range from[:prefix] {
+		ops = append(ops, diffOpT{kind: ' ', line: line})
+	}
+	middleFrom, middleTo := from[prefix:len(from)-suffix], to[prefix:len(to)-suffix]
+	switch {
+	case len(middleFrom) == 0:
+		for _, line := range middleTo {
+			ops = append(ops, diffOpT{kind: '+', line: line})
+		}
+	case len(middleTo) == 0:
+		for _, line := range middleFrom {
+			ops = append(ops, diffOpT{kind: '-', line: line})
+		}
+	case len(middleFrom) == 1:
+		idx := -1
+		for j, line := range middleTo {
+			if line == middleFrom[0] {
+				idx = j
+				break
+			}
+		}
+		if idx == -1 {
+			ops = append(ops, diffOpT{kind: '-', line: middleFrom[0]})
+		}
+		for j, line := range middleTo {
+			kind := byte('+')
+			if j == idx {
+				kind = ' '
+			}
+			ops = append(ops, diffOpT{kind: kind, line: line})
+		}
+	default:
+		// split the from lines in half and find the split of the to lines that keeps the common subsequence the longest
+		mid := len(middleFrom) / 2
+		forward := getLCSLengths(middleFrom[:mid], middleTo, false)
+		backward := getLCSLengths(middleFrom[mid:], middleTo, true)
+		split, best := 0, -1
+		for j := range forward {
+			if total := forward[j] + backward[len(middleTo)-j]; total > best {
+				split, best = j, total
+			}
+		}
+		ops = appendDiffOps(ops, middleFrom[:mid], middleTo[:split])
+		ops = appendDiffOps(ops, middleFrom[mid:], middleTo[split:])
+	}
+	for _, line := range from[len(from)-suffix:] {
+		ops = append(ops, diffOpT{kind: ' ', line: line})
+	}
+	return ops
+}
+
+// getLCSLengths returns the lengths of the longest common subsequences between the from lines and each prefix of the to lines.
+// If reverse is true, the lines are compared from the end and the lengths are for each suffix of the to lines instead.
+func getLCSLengths(from, to []string, reverse bool) []int {
+	prev, curr := make([]int, len(to)+1), make([]int, len(to)+1)
+	for i := range from {
+		fromLine := from[i]
+		if reverse {
+			fromLine = from[len(from)-1-i]
+		}
+		for j := range to {
+			toLine := to[j]
+			if reverse {
+				toLine = to[len(to)-1-j]
+			}
+			if fromLine == toLine {
+				curr[j+1] = prev[j] + 1
+			} else if prev[j+1] >= curr[j] {
+				curr[j+1] = prev[j+1]
+			} else {
+				curr[j+1] = curr[j]
+			}
+		}
+		prev, curr = curr, prev
+	}
+	return prev
+}
+
+// getHunkRange returns the range of lines in the unified diff hunk header format
+func getHunkRange(start, count int) string {
+	if count == 0 {
+		return fmt.Sprintf("%d,0", start-1)
+	}
+	if count == 1 {
+		return fmt.Sprintf("%d", start)
+	}
+	return fmt.Sprintf("%d,%d", start, count)
+}
+
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
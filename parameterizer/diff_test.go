@@ -0,0 +1,93 @@
+/*
+ *  Copyright IBM Corporation 2021
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package parameterizer
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// applyDiffOps returns the from and to lines described by the edit script along with the number of changed lines
+func applyDiffOps(ops []diffOpT) (from, to []string, changes int) {
+	for _, op := range ops {
+		if op.kind != '+' {
+			from = append(from, op.line)
+		}
+		if op.kind != '-' {
+			to = append(to, op.line)
+		}
+		if op.kind != ' ' {
+			changes++
+		}
+	}
+	return from, to, changes
+}
+
+func TestGetDiffOps(t *testing.T) {
+	testcases := []struct {
+		name        string
+		from        string
+		to          string
+		wantChanges int
+	}{
+		{name: "same", from: "a\nb\nc", to: "a\nb\nc", wantChanges: 0},
+		{name: "empty from", from: "", to: "a\nb", wantChanges: 2},
+		{name: "empty to", from: "a\nb", to: "", wantChanges: 2},
+		{name: "changed line", from: "a\nb\nc", to: "a\nx\nc", wantChanges: 2},
+		{name: "single line kept", from: "x", to: "a\nx\nb", wantChanges: 2},
+		{name: "scattered changes", from: "a\nb\nc\nd\ne\nf\ng", to: "a\nB\nc\nd\nE\nf\ng\nh", wantChanges: 5},
+		{name: "moved line", from: "a\nb\nc\nd", to: "b\nc\nd\na", wantChanges: 2},
+	}
+	for _, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			from, to := splitLines(testcase.from), splitLines(testcase.to)
+			actualFrom, actualTo, changes := applyDiffOps(getDiffOps(from, to))
+			if !cmp.Equal(actualFrom, from) || !cmp.Equal(actualTo, to) {
+				t.Fatalf("the edit script doesn't describe the lines. From:\n%s\nTo:\n%s", cmp.Diff(from, actualFrom), cmp.Diff(to, actualTo))
+			}
+			if changes != testcase.wantChanges {
+				t.Fatalf("expected %d changed lines. Actual: %d", testcase.wantChanges, changes)
+			}
+		})
+	}
+}
+
+func TestGetDiffOpsLargeInput(t *testing.T) {
+	fromLines, toLines := []string{}, []string{}
+	for i := 0; i < 5000; i++ {
+		line := fmt.Sprintf("  key%d: value%d", i, i)
+		fromLines = append(fromLines, line)
+		if i%25 == 0 {
+			line = fmt.Sprintf("  key%d: {{ .Values.key%d }}", i, i)
+		}
+		toLines = append(toLines, line)
+	}
+	from, to := strings.Join(fromLines, "\n"), strings.Join(toLines, "\n")
+	actualFrom, actualTo, changes := applyDiffOps(getDiffOps(splitLines(from), splitLines(to)))
+	if !cmp.Equal(actualFrom, fromLines) || !cmp.Equal(actualTo, toLines) {
+		t.Fatalf("the edit script doesn't describe the lines")
+	}
+	if changes != 400 {
+		t.Fatalf("expected %d changed lines. Actual: %d", 400, changes)
+	}
+	if diff := getUnifiedDiff("a", "b", from, to); strings.Count(diff, "@@ -") != 200 {
+		t.Fatalf("expected a hunk for each changed line. Actual: %d hunks", strings.Count(diff, "@@ -"))
+	}
+}
@@ -0,0 +1,75 @@
+/*
+ *  Copyright IBM Corporation 2021
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package parameterizer
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/konveyor/move2kube/internal/k8sschema"
+	parameterizertypes "github.com/konveyor/move2kube/types/parameterizer"
+)
+
+const (
+	// imagesValuesKey is the key in the Helm values under which the images are stored
+	imagesValuesKey = "images"
+)
+
+var (
+	// podSpecKeys are the keys of the pod specs in pods, workloads like deployments and cron jobs
+	podSpecKeys = []string{"spec", "spec.template.spec", "spec.jobTemplate.spec.template.spec"}
+	// containerListKeys are the keys of the container lists in a pod spec
+	containerListKeys = []string{"containers", "initContainers"}
+)
+
+// ParameterizeImages replaces the images of all the containers and init containers in the k8s resource with Helm templates.
+// It returns the Helm values containing the original images, keyed by the name of the resource and the container.
+// Example: the image of the container nginx in the deployment web is replaced by {{ index .Values "images" "web" "nginx" }}
+// It also returns the keys that were replaced so that they can be written unquoted using WriteOptions.HelmTemplateKeys
+func ParameterizeImages(k parameterizertypes.K8sResourceT) (parameterizertypes.HelmValuesT, []string, error) {
+	_, _, metadataName, err := k8sschema.GetInfoFromK8sResource(k)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get the name of the k8s resource: %+v\nError: %q", k, err)
+	}
+	values := parameterizertypes.HelmValuesT{}
+	helmTemplateKeys := []string{}
+	for _, podSpecKey := range podSpecKeys {
+		for _, containerListKey := range containerListKeys {
+			results, _, err := GetAllLenient(podSpecKey+"."+containerListKey+".[name].image", k)
+			if err != nil {
+				return values, helmTemplateKeys, err
+			}
+			for _, result := range results {
+				image, ok := result.Value.(string)
+				if !ok {
+					return values, helmTemplateKeys, fmt.Errorf("the image at the key %s is not a string. Actual value is %+v of type %T", getKeyFromSubKeys(result.Key), result.Value, result.Value)
+				}
+				valueSubKeys := []string{imagesValuesKey, metadataName, result.Matches["name"]}
+				if err := setCreatingNew(getKeyFromSubKeys(valueSubKeys), image, values); err != nil {
+					return values, helmTemplateKeys, fmt.Errorf("failed to set the image %s in the Helm values. Error: %q", image, err)
+				}
+				helmTemplate := fmt.Sprintf(`{{ index .Values "%s" }}`, strings.Join(valueSubKeys, `" "`))
+				key := getKeyFromSubKeys(result.Key)
+				if _, err := SetAll(key, helmTemplate, k); err != nil {
+					return values, helmTemplateKeys, err
+				}
+				helmTemplateKeys = append(helmTemplateKeys, key)
+			}
+		}
+	}
+	return values, helmTemplateKeys, nil
+}
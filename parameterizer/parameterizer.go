@@ -17,13 +17,18 @@
 package parameterizer
 
 import (
+	"context"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"os"
 	"path/filepath"
 	"regexp"
+	"sort"
 	"strings"
+	"time"
 
+	semver "github.com/Masterminds/semver/v3"
 	"github.com/konveyor/move2kube/internal/common"
 	"github.com/konveyor/move2kube/internal/common/deepcopy"
 	"github.com/konveyor/move2kube/internal/k8sschema"
@@ -32,9 +37,15 @@ import (
 	qatypes "github.com/konveyor/move2kube/types/qaengine"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cast"
+	"gopkg.in/yaml.v3"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
+const (
+	// defaultHelmChartVersion is the version of the Helm chart when the version is not specified
+	defaultHelmChartVersion = "0.1.0"
+)
+
 var (
 	stringInterpRegex            = regexp.MustCompile(`\${([^}]+)}`)
 	invalidOCTemplateChars       = regexp.MustCompile(`[^a-zA-Z0-9_]+`)
@@ -42,13 +53,45 @@ var (
 	templateInnerParametersRegex = regexp.MustCompile(`\$\([^)]+\)`)
 )
 
-// Parameterize does the parameterization based on a spec
-func Parameterize(srcDir, outDir string, packSpecPath parameterizertypes.PackagingSpecPathT, ps []parameterizertypes.ParameterizerT) ([]string, error) {
-	filesWritten := []string{}
-	cleanSrcDir, err := filepath.Abs(srcDir)
-	if err != nil {
-		return nil, err
+// ParameterizeOptions are the inputs to Parameterize
+type ParameterizeOptions struct {
+	// SrcDirs are the directories containing the k8s resources to parameterize
+	SrcDirs []string
+	// OutDir is the directory where the outputs are written
+	OutDir string
+	// PackSpecPath contains the paths and the settings of the outputs
+	PackSpecPath parameterizertypes.PackagingSpecPathT
+	// Parameterizers are the parameterizations to apply
+	Parameterizers []parameterizertypes.ParameterizerT
+	// Kinds are the kinds of k8s resources to parameterize. If empty, all the kinds are parameterized.
+	Kinds []string
+	// Targets are the outputs (Helm, Kustomize, Openshift Templates, Kubernetes) to write.
+	// If empty, all the outputs except Kubernetes are written.
+	Targets []parameterizertypes.ParamTargetT
+	// DiffOut receives a unified diff between each source file and its Helm template. If nil, no diff is generated.
+	DiffOut io.Writer
+	// Logger is used for the logs. If nil, the standard logger of logrus is used.
+	Logger *log.Logger
+	// Header starts each k8s resource file with a comment noting the source file and the time of generation
+	Header bool
+	// Reproducible omits the timestamp from the headers unless it is pinned using the SOURCE_DATE_EPOCH environment variable
+	Reproducible bool
+}
+
+// Parameterize does the parameterization based on a spec.
+// The k8s resources from all the source directories are merged into a single output.
+// If two source directories have files with the same relative path, the later file is prefixed with the name of its source directory.
+// If kinds is not empty, only the k8s resources of those kinds are parameterized.
+// If targets is not empty, only the outputs for those targets (Helm, Kustomize, Openshift Templates, Kubernetes) are written.
+// The Kubernetes output has the concrete k8s resources and a json patch file for each env.
+// If the context is cancelled, it stops and returns the files written so far along with the context error.
+func Parameterize(ctx context.Context, opts ParameterizeOptions) ([]string, error) {
+	srcDirs, outDir, packSpecPath, ps, kinds, targets, diffOut := opts.SrcDirs, opts.OutDir, opts.PackSpecPath, opts.Parameterizers, opts.Kinds, opts.Targets, opts.DiffOut
+	logger := opts.Logger
+	if logger == nil {
+		logger = log.StandardLogger()
 	}
+	filesWritten := []string{}
 	cleanOutDir, err := filepath.Abs(outDir)
 	if err != nil {
 		return nil, err
@@ -62,49 +105,140 @@ func Parameterize(srcDir, outDir string, packSpecPath parameterizertypes.Packagi
 	if packSpecPath.OCTemplates == "" {
 		packSpecPath.OCTemplates = filepath.Join(packSpecPath.Out, "openshift-template")
 	}
+	if packSpecPath.Kubernetes == "" && isTargetSelected(targets, parameterizertypes.TargetKubernetes) {
+		packSpecPath.Kubernetes = filepath.Join(packSpecPath.Out, "kubernetes")
+	}
+	if len(targets) > 0 {
+		if !isTargetSelected(targets, parameterizertypes.TargetHelm) {
+			packSpecPath.Helm = ""
+		}
+		if !isTargetSelected(targets, parameterizertypes.TargetKustomize) {
+			packSpecPath.Kustomize = ""
+		}
+		if !isTargetSelected(targets, parameterizertypes.TargetOCTemplates) {
+			packSpecPath.OCTemplates = ""
+		}
+		if !isTargetSelected(targets, parameterizertypes.TargetKubernetes) {
+			packSpecPath.Kubernetes = ""
+		}
+	}
 	if len(packSpecPath.Envs) == 0 {
 		packSpecPath.Envs = []string{"dev", "staging", "prod"}
 	}
-	pathedKs, err := k8sschema.GetK8sResourcesWithPaths(filepath.Join(cleanSrcDir, packSpecPath.Src))
+	pathedKs, err := getK8sResourcesFromSrcDirs(logger, srcDirs, packSpecPath.Src)
 	if err != nil {
 		return filesWritten, err
 	}
+	timestamp := time.Time{}
+	if opts.Header {
+		if timestamp, err = GetTimestamp(opts.Reproducible); err != nil {
+			return filesWritten, err
+		}
+	}
+	getKHeader := func(kPath string) string {
+		if !opts.Header {
+			return ""
+		}
+		return GetHeader(filepath.ToSlash(filepath.Join(packSpecPath.Src, kPath)), timestamp)
+	}
+	// iterate over the paths in sorted order so that the output is the same on every run
+	sortedKPaths := []string{}
+	for kPath := range pathedKs {
+		sortedKPaths = append(sortedKPaths, kPath)
+	}
+	sort.Strings(sortedKPaths)
+	if diffOut != nil && packSpecPath.Helm == "" {
+		logger.Warnf("The diff is only generated for the Helm output. Skipping the diff since Helm output is not selected.")
+	}
 	if packSpecPath.Helm != "" {
 		// helm chart with multiple values.yaml
 		helmChartName := packSpecPath.HelmChartName
 		if helmChartName == "" {
 			helmChartName = common.DefaultProjectName
 		}
+		helmChartVersion := packSpecPath.HelmChartVersion
+		if helmChartVersion == "" {
+			helmChartVersion = defaultHelmChartVersion
+		}
+		if _, err := semver.NewVersion(helmChartVersion); err != nil {
+			return filesWritten, fmt.Errorf("the Helm chart version %s is not a valid semantic version. Error: %q", helmChartVersion, err)
+		}
 		namedValues := map[string]parameterizertypes.HelmValuesT{}
+		helmDescriptions := map[string]string{}
 		helmChartDir := filepath.Join(cleanOutDir, packSpecPath.Helm, helmChartName)
 		helmTemplatesDir := filepath.Join(helmChartDir, "templates")
 		if err := os.MkdirAll(helmTemplatesDir, common.DefaultDirectoryPermission); err != nil {
 			return filesWritten, err
 		}
-		for kPath, ks := range pathedKs {
-			for _, k := range ks {
-				k = deepcopy.DeepCopy(k).(parameterizertypes.K8sResourceT)
-				if err := parameterize(parameterizertypes.TargetHelm, packSpecPath.Envs, k, ps, namedValues, nil, nil); err != nil {
+		for _, kPath := range sortedKPaths {
+			origYamls, paramYamls := []string{}, []string{}
+			for _, origK := range pathedKs[kPath] {
+				if err := ctx.Err(); err != nil {
+					return filesWritten, err
+				}
+				k := deepcopy.DeepCopy(origK).(parameterizertypes.K8sResourceT)
+				selected, err := isKindSelected(kinds, k)
+				if err != nil {
 					return filesWritten, err
 				}
+				helmTemplateKeys := map[string]bool{}
+				if selected {
+					if err := parameterize(ctx, logger, parameterizertypes.TargetHelm, packSpecPath.Envs, k, ps, namedValues, helmDescriptions, helmTemplateKeys, nil, nil); err != nil {
+						return filesWritten, err
+					}
+				}
 				finalKPath := filepath.Join(helmTemplatesDir, kPath)
-				if err := writeResourceStripQuotesAndAppendToFile(k, finalKPath); err != nil {
+				sortedHelmTemplateKeys := []string{}
+				for key := range helmTemplateKeys {
+					sortedHelmTemplateKeys = append(sortedHelmTemplateKeys, key)
+				}
+				sort.Strings(sortedHelmTemplateKeys)
+				if _, err := WriteResource(k, finalKPath, WriteOptions{HelmTemplateKeys: sortedHelmTemplateKeys, Header: getKHeader(kPath), IfExists: getIfExistsPolicy(filesWritten, finalKPath), Logger: logger}); err != nil {
 					return filesWritten, err
 				}
 				filesWritten = append(filesWritten, finalKPath)
+				if diffOut != nil {
+					origYaml, err := marshalResource(origK, false, nil)
+					if err != nil {
+						return filesWritten, err
+					}
+					paramYaml, err := marshalResource(k, false, sortedHelmTemplateKeys)
+					if err != nil {
+						return filesWritten, err
+					}
+					origYamls = append(origYamls, string(origYaml))
+					paramYamls = append(paramYamls, string(paramYaml))
+				}
+			}
+			if diffOut != nil {
+				helmKPath := filepath.Join(packSpecPath.Helm, helmChartName, "templates", kPath)
+				diff := getUnifiedDiff(filepath.Join(packSpecPath.Src, kPath), helmKPath, strings.Join(origYamls, "---\n"), strings.Join(paramYamls, "---\n"))
+				if _, err := io.WriteString(diffOut, diff); err != nil {
+					return filesWritten, fmt.Errorf("failed to write the diff for the file %s . Error: %q", kPath, err)
+				}
 			}
 		}
 		for env, values := range namedValues {
 			finalKPath := filepath.Join(helmChartDir, "values-"+env+".yaml")
-			if err := common.WriteYaml(finalKPath, values); err != nil {
+			if err := writeHelmValues(logger, finalKPath, values, helmDescriptions, packSpecPath.OverwriteValues); err != nil {
 				return filesWritten, err
 			}
 			filesWritten = append(filesWritten, finalKPath)
 		}
+		// the default values.yaml has the values of the first env so that the chart can be installed without specifying a values file
+		defaultValuesPath := filepath.Join(helmChartDir, "values.yaml")
+		defaultValues := namedValues[packSpecPath.Envs[0]]
+		if defaultValues == nil {
+			defaultValues = parameterizertypes.HelmValuesT{}
+		}
+		if err := writeHelmValues(logger, defaultValuesPath, defaultValues, helmDescriptions, packSpecPath.OverwriteValues); err != nil {
+			return filesWritten, err
+		}
+		filesWritten = append(filesWritten, defaultValuesPath)
 		helmChartYaml := map[string]interface{}{
 			"apiVersion":  "v2",
 			"name":        helmChartName,
-			"version":     "0.1.0",
+			"version":     helmChartVersion,
 			"description": "A Helm Chart generated by Move2Kube for " + helmChartName,
 			"keywords":    []string{helmChartName},
 		}
@@ -123,19 +257,28 @@ func Parameterize(srcDir, outDir string, packSpecPath parameterizertypes.Packagi
 		}
 		kustPatches := map[string]map[parameterizertypes.PatchMetadataT][]parameterizertypes.PatchT{}
 		kPaths := []string{}
-		for kPath, ks := range pathedKs {
-			for _, k := range ks {
+		for _, kPath := range sortedKPaths {
+			for _, k := range pathedKs[kPath] {
+				if err := ctx.Err(); err != nil {
+					return filesWritten, err
+				}
 				// base
 				finalKPath := filepath.Join(baseDir, kPath)
-				if err := writeResourceAppendToFile(k, finalKPath); err != nil {
+				if _, err := WriteResource(k, finalKPath, WriteOptions{Header: getKHeader(kPath), IfExists: getIfExistsPolicy(filesWritten, finalKPath), Logger: logger}); err != nil {
 					return filesWritten, err
 				}
 				filesWritten = append(filesWritten, finalKPath)
 				// compute the json patch
 				currKustPatches := map[string]map[string]parameterizertypes.PatchT{} // keyed by env and json pointer/path
-				if err := parameterize(parameterizertypes.TargetKustomize, packSpecPath.Envs, k, ps, nil, currKustPatches, nil); err != nil {
+				selected, err := isKindSelected(kinds, k)
+				if err != nil {
 					return filesWritten, err
 				}
+				if selected {
+					if err := parameterize(ctx, logger, parameterizertypes.TargetKustomize, packSpecPath.Envs, k, ps, nil, nil, nil, currKustPatches, nil); err != nil {
+						return filesWritten, err
+					}
+				}
 				// patch metadata to put in kustomization.yaml
 				group, version, kind, metadataName, err := getGVKNFromK(k)
 				if err != nil {
@@ -154,8 +297,13 @@ func Parameterize(srcDir, outDir string, packSpecPath parameterizertypes.Packagi
 					if _, ok := kustPatches[env]; !ok {
 						kustPatches[env] = map[parameterizertypes.PatchMetadataT][]parameterizertypes.PatchT{}
 					}
-					for _, v := range patches {
-						kustPatches[env][patchMetadata] = append(kustPatches[env][patchMetadata], v)
+					jsonPaths := []string{}
+					for jsonPath := range patches {
+						jsonPaths = append(jsonPaths, jsonPath)
+					}
+					sort.Strings(jsonPaths)
+					for _, jsonPath := range jsonPaths {
+						kustPatches[env][patchMetadata] = append(kustPatches[env][patchMetadata], patches[jsonPath])
 					}
 				}
 				kPaths = append(kPaths, kPath)
@@ -182,6 +330,7 @@ func Parameterize(srcDir, outDir string, packSpecPath parameterizertypes.Packagi
 				metas = append(metas, kMeta)
 				filesWritten = append(filesWritten, finalKPath)
 			}
+			sort.Slice(metas, func(i, j int) bool { return metas[i].Path < metas[j].Path })
 			kustomization := map[string]interface{}{"resources": []string{"../../base"}, "patches": metas}
 			finalKPath := filepath.Join(envDir, "kustomization.yaml")
 			if err := common.WriteYaml(finalKPath, kustomization); err != nil {
@@ -194,22 +343,34 @@ func Parameterize(srcDir, outDir string, packSpecPath parameterizertypes.Packagi
 		// openshift templates for each env
 		newKs := []parameterizertypes.K8sResourceT{}
 		ocParams := map[string]map[string]string{}
-		for _, ks := range pathedKs {
-			for _, k := range ks {
+		for _, kPath := range sortedKPaths {
+			for _, k := range pathedKs[kPath] {
+				if err := ctx.Err(); err != nil {
+					return filesWritten, err
+				}
 				k = deepcopy.DeepCopy(k).(parameterizertypes.K8sResourceT)
-				if err := parameterize(parameterizertypes.TargetOCTemplates, packSpecPath.Envs, k, ps, nil, nil, ocParams); err != nil {
+				selected, err := isKindSelected(kinds, k)
+				if err != nil {
 					return filesWritten, err
 				}
+				if selected {
+					if err := parameterize(ctx, logger, parameterizertypes.TargetOCTemplates, packSpecPath.Envs, k, ps, nil, nil, nil, nil, ocParams); err != nil {
+						return filesWritten, err
+					}
+				}
 				newKs = append(newKs, k)
 			}
 		}
+		envs := []string{}
+		for env := range ocParams {
+			envs = append(envs, env)
+		}
+		sort.Strings(envs)
 		singleSet := []parameterizertypes.OCParamT{}
-		if len(ocParams) > 0 {
-			for _, kvs := range ocParams {
-				for k, v := range kvs {
-					singleSet = append(singleSet, parameterizertypes.OCParamT{Name: k, Value: v})
-				}
-				break
+		if len(envs) > 0 {
+			kvs := ocParams[envs[0]]
+			for _, k := range getSortedKeys(kvs) {
+				singleSet = append(singleSet, parameterizertypes.OCParamT{Name: k, Value: kvs[k]})
 			}
 		}
 		templ := map[string]interface{}{
@@ -231,8 +392,8 @@ func Parameterize(srcDir, outDir string, packSpecPath parameterizertypes.Packagi
 		for env, params := range ocParams {
 			finalKPath := filepath.Join(ocDir, "parameters-"+env+".yaml")
 			finalParams := []string{}
-			for k, v := range params {
-				finalParams = append(finalParams, fmt.Sprintf("%s=%s", k, v))
+			for _, k := range getSortedKeys(params) {
+				finalParams = append(finalParams, fmt.Sprintf("%s=%s", k, params[k]))
 			}
 			if err := ioutil.WriteFile(finalKPath, []byte(strings.Join(finalParams, "\n")), common.DefaultFilePermission); err != nil {
 				return filesWritten, err
@@ -240,12 +401,228 @@ func Parameterize(srcDir, outDir string, packSpecPath parameterizertypes.Packagi
 			filesWritten = append(filesWritten, finalKPath)
 		}
 	}
+	if packSpecPath.Kubernetes != "" {
+		// concrete k8s resources with a separate json patch file for each env
+		kubernetesDir := filepath.Join(cleanOutDir, packSpecPath.Kubernetes)
+		manifestsDir := filepath.Join(kubernetesDir, "manifests")
+		if err := os.MkdirAll(manifestsDir, common.DefaultDirectoryPermission); err != nil {
+			return filesWritten, err
+		}
+		envPatches := map[string][]parameterizertypes.KubernetesPatchT{}
+		for _, kPath := range sortedKPaths {
+			for _, k := range pathedKs[kPath] {
+				if err := ctx.Err(); err != nil {
+					return filesWritten, err
+				}
+				finalKPath := filepath.Join(manifestsDir, kPath)
+				if _, err := WriteResource(k, finalKPath, WriteOptions{Header: getKHeader(kPath), IfExists: getIfExistsPolicy(filesWritten, finalKPath), Logger: logger}); err != nil {
+					return filesWritten, err
+				}
+				filesWritten = append(filesWritten, finalKPath)
+				selected, err := isKindSelected(kinds, k)
+				if err != nil {
+					return filesWritten, err
+				}
+				if !selected {
+					continue
+				}
+				currPatches := map[string]map[string]parameterizertypes.PatchT{} // keyed by env and json pointer/path
+				if err := parameterize(ctx, logger, parameterizertypes.TargetKubernetes, packSpecPath.Envs, k, ps, nil, nil, nil, currPatches, nil); err != nil {
+					return filesWritten, err
+				}
+				group, version, kind, metadataName, err := getGVKNFromK(k)
+				if err != nil {
+					return filesWritten, err
+				}
+				for env, patches := range currPatches {
+					kubernetesPatch := parameterizertypes.KubernetesPatchT{
+						Target: parameterizertypes.PatchMetadataTargetT{Kind: kind, Group: group, Version: version, Name: metadataName},
+					}
+					jsonPaths := []string{}
+					for jsonPath := range patches {
+						jsonPaths = append(jsonPaths, jsonPath)
+					}
+					sort.Strings(jsonPaths)
+					for _, jsonPath := range jsonPaths {
+						kubernetesPatch.Patch = append(kubernetesPatch.Patch, patches[jsonPath])
+					}
+					envPatches[env] = append(envPatches[env], kubernetesPatch)
+				}
+			}
+		}
+		patchesDir := filepath.Join(kubernetesDir, "patches")
+		if err := os.MkdirAll(patchesDir, common.DefaultDirectoryPermission); err != nil {
+			return filesWritten, err
+		}
+		envs := []string{}
+		for env := range envPatches {
+			envs = append(envs, env)
+		}
+		sort.Strings(envs)
+		for _, env := range envs {
+			finalKPath := filepath.Join(patchesDir, env+".yaml")
+			if err := common.WriteYaml(finalKPath, envPatches[env]); err != nil {
+				return filesWritten, err
+			}
+			filesWritten = append(filesWritten, finalKPath)
+		}
+	}
 	return filesWritten, nil
 }
 
 // ------------------------------
 // Utilities
 
+// addHelmDescription records the description of the parameter for the key in the Helm values
+func addHelmDescription(helmDescriptions map[string]string, paramKey string, p parameterizertypes.ParameterizerT, parameter string) {
+	if helmDescriptions == nil {
+		return
+	}
+	description := p.Description
+	for _, param := range p.Parameters {
+		if param.Name == parameter && param.Description != "" {
+			description = param.Description
+			break
+		}
+	}
+	if description != "" {
+		helmDescriptions[paramKey] = description
+	}
+}
+
+// writeHelmValues writes the Helm values to the file with the descriptions as comments above the keys.
+// If the file already exists and overwrite is false, the new values are merged into the existing values.
+// The existing values take precedence so that the values edited by the user are preserved.
+func writeHelmValues(logger *log.Logger, valuesPath string, values parameterizertypes.HelmValuesT, helmDescriptions map[string]string, overwrite bool) error {
+	if !overwrite {
+		if _, err := os.Stat(valuesPath); err == nil {
+			existingValues := map[string]interface{}{}
+			if err := common.ReadYaml(valuesPath, &existingValues); err != nil {
+				return fmt.Errorf("failed to read the existing Helm values file at path %s . Error: %q", valuesPath, err)
+			}
+			logger.Infof("Merging the new values into the existing Helm values file at path %s", valuesPath)
+			values = mergeHelmValues(existingValues, values)
+		}
+	}
+	if len(helmDescriptions) == 0 {
+		return common.WriteYaml(valuesPath, values)
+	}
+	valuesNode := yaml.Node{}
+	if err := valuesNode.Encode(values); err != nil {
+		return fmt.Errorf("failed to encode the Helm values %+v . Error: %q", values, err)
+	}
+	addYamlComments(&valuesNode, nil, helmDescriptions)
+	return common.WriteYaml(valuesPath, &valuesNode)
+}
+
+// addYamlComments adds the descriptions as comments above the keys of the yaml mapping nodes.
+// The descriptions are keyed by the quoted sub keys like "a"."b"
+func addYamlComments(node *yaml.Node, subKeys []string, descriptions map[string]string) {
+	if node.Kind != yaml.MappingNode {
+		return
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		keyNode, valueNode := node.Content[i], node.Content[i+1]
+		currSubKeys := append(append([]string{}, subKeys...), `"`+keyNode.Value+`"`)
+		if description, ok := descriptions[strings.Join(currSubKeys, ".")]; ok {
+			keyNode.HeadComment = description
+		}
+		addYamlComments(valueNode, currSubKeys, descriptions)
+	}
+}
+
+// mergeHelmValues deep merges the new values into the existing values.
+// Keys that are present in both are taken from the existing values unless both values are maps.
+func mergeHelmValues(existingValues, newValues map[string]interface{}) map[string]interface{} {
+	merged := map[string]interface{}{}
+	for k, v := range newValues {
+		merged[k] = v
+	}
+	for k, existingValue := range existingValues {
+		existingMap, ok1 := existingValue.(map[string]interface{})
+		newMap, ok2 := merged[k].(map[string]interface{})
+		if ok1 && ok2 {
+			merged[k] = mergeHelmValues(existingMap, newMap)
+			continue
+		}
+		merged[k] = existingValue
+	}
+	return merged
+}
+
+// getK8sResourcesFromSrcDirs collects the k8s resources from all the source directories keyed by their relative paths.
+// Conflicting paths are prefixed with the name of the source directory, and numbered if the prefixed path is also used.
+func getK8sResourcesFromSrcDirs(logger *log.Logger, srcDirs []string, subPath string) (map[string][]parameterizertypes.K8sResourceT, error) {
+	pathedKs := map[string][]parameterizertypes.K8sResourceT{}
+	for _, srcDir := range srcDirs {
+		cleanSrcDir, err := filepath.Abs(srcDir)
+		if err != nil {
+			return pathedKs, err
+		}
+		currPathedKs, err := k8sschema.GetK8sResourcesWithPaths(filepath.Join(cleanSrcDir, subPath))
+		if err != nil {
+			return pathedKs, err
+		}
+		// the paths are sorted so that the renamed paths don't depend on the order of iterating over the map
+		kPaths := []string{}
+		for kPath := range currPathedKs {
+			kPaths = append(kPaths, kPath)
+		}
+		sort.Strings(kPaths)
+		for _, kPath := range kPaths {
+			ks := currPathedKs[kPath]
+			if _, ok := pathedKs[kPath]; ok {
+				newKPath := getUnusedKPath(pathedKs, kPath, filepath.Base(cleanSrcDir))
+				logger.Warnf("The file %s in the source directory %s conflicts with a file from another source directory. Writing it as %s instead.", kPath, cleanSrcDir, newKPath)
+				kPath = newKPath
+			}
+			pathedKs[kPath] = append(pathedKs[kPath], ks...)
+		}
+	}
+	return pathedKs, nil
+}
+
+// getUnusedKPath prefixes the file name with the name of the source directory.
+// If that path is also used, a number is added to the file name until the path is unused.
+func getUnusedKPath(pathedKs map[string][]parameterizertypes.K8sResourceT, kPath, srcDirName string) string {
+	ext := filepath.Ext(kPath)
+	name := srcDirName + "-" + strings.TrimSuffix(filepath.Base(kPath), ext)
+	newKPath := filepath.Join(filepath.Dir(kPath), name+ext)
+	for i := 2; ; i++ {
+		if _, ok := pathedKs[newKPath]; !ok {
+			return newKPath
+		}
+		newKPath = filepath.Join(filepath.Dir(kPath), name+"-"+cast.ToString(i)+ext)
+	}
+}
+
+// getIfExistsPolicy overwrites the files left over from a previous run and appends to the files already written in this run,
+// so that running again into the same output directory doesn't duplicate the k8s resources.
+func getIfExistsPolicy(filesWritten []string, path string) ExistingFilePolicyT {
+	if common.IsStringPresent(filesWritten, path) {
+		return AppendToExistingFile
+	}
+	return OverwriteExistingFile
+}
+
+func isTargetSelected(targets []parameterizertypes.ParamTargetT, target parameterizertypes.ParamTargetT) bool {
+	for _, t := range targets {
+		if t == target {
+			return true
+		}
+	}
+	return false
+}
+
+func getSortedKeys(m map[string]string) []string {
+	keys := []string{}
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
 func getGVKNFromK(k parameterizertypes.K8sResourceT) (group string, version string, kind string, metadataName string, err error) {
 	var apiVersion string
 	kind, apiVersion, metadataName, err = k8sschema.GetInfoFromK8sResource(k)
@@ -265,6 +642,24 @@ func getGVKNFromK(k parameterizertypes.K8sResourceT) (group string, version stri
 	return group, version, kind, metadataName, nil
 }
 
+// isKindSelected returns true if the k8s resource is one of the given kinds.
+// An empty list of kinds selects all the k8s resources.
+func isKindSelected(kinds []string, k parameterizertypes.K8sResourceT) (bool, error) {
+	if len(kinds) == 0 {
+		return true, nil
+	}
+	kind, _, _, err := k8sschema.GetInfoFromK8sResource(k)
+	if err != nil {
+		return false, err
+	}
+	for _, selectedKind := range kinds {
+		if strings.EqualFold(selectedKind, kind) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
 func getParameters(templ string) ([]string, error) {
 	matches := stringInterpRegex.FindAllStringSubmatch(templ, -1)
 	if len(matches) == 0 {
@@ -398,9 +793,16 @@ func fillCustomTemplate(templ, kind, apiVersion, metadataName string, matches ma
 // ------------------------------
 // Parameterization
 
-func parameterize(target parameterizertypes.ParamTargetT, envs []string, k parameterizertypes.K8sResourceT, ps []parameterizertypes.ParameterizerT, namedValues map[string]parameterizertypes.HelmValuesT, namedKustPatches map[string]map[string]parameterizertypes.PatchT, namedOCParams map[string]map[string]string) error {
-	for _, p := range ps {
-		ok, err := parameterizeFilter(envs, k, p)
+func parameterize(ctx context.Context, logger *log.Logger, target parameterizertypes.ParamTargetT, envs []string, k parameterizertypes.K8sResourceT, ps []parameterizertypes.ParameterizerT, namedValues map[string]parameterizertypes.HelmValuesT, helmDescriptions map[string]string, helmTemplateKeys map[string]bool, namedKustPatches map[string]map[string]parameterizertypes.PatchT, namedOCParams map[string]map[string]string) error {
+	kind, apiVersion, _, err := k8sschema.GetInfoFromK8sResource(k)
+	if err != nil {
+		return err
+	}
+	for _, p := range getParameterizersForKind(ps, kind, apiVersion) {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		ok, err := parameterizeFilter(logger, envs, k, p)
 		if err != nil {
 			return err
 		}
@@ -409,15 +811,15 @@ func parameterize(target parameterizertypes.ParamTargetT, envs []string, k param
 		}
 		switch target {
 		case parameterizertypes.TargetHelm:
-			if err := parameterizeHelperHelm(envs, k, p, namedValues, namedKustPatches, namedOCParams); err != nil {
+			if err := parameterizeHelperHelm(logger, envs, k, p, namedValues, helmDescriptions, helmTemplateKeys, namedKustPatches, namedOCParams); err != nil {
 				return err
 			}
-		case parameterizertypes.TargetKustomize:
-			if err := parameterizeHelperKustomize(envs, k, p, namedValues, namedKustPatches, namedOCParams); err != nil {
+		case parameterizertypes.TargetKustomize, parameterizertypes.TargetKubernetes:
+			if err := parameterizeHelperKustomize(logger, envs, k, p, namedValues, namedKustPatches, namedOCParams); err != nil {
 				return err
 			}
 		case parameterizertypes.TargetOCTemplates:
-			if err := parameterizeHelperOCTemplates(envs, k, p, namedValues, namedKustPatches, namedOCParams); err != nil {
+			if err := parameterizeHelperOCTemplates(logger, envs, k, p, namedValues, namedKustPatches, namedOCParams); err != nil {
 				return err
 			}
 		default:
@@ -427,10 +829,21 @@ func parameterize(target parameterizertypes.ParamTargetT, envs []string, k param
 	return nil
 }
 
+// getParameterizersForKind returns the parameterizers that apply to k8s resources of the given kind and apiVersion, in the same order
+func getParameterizersForKind(ps []parameterizertypes.ParameterizerT, kind, apiVersion string) []parameterizertypes.ParameterizerT {
+	selected := []parameterizertypes.ParameterizerT{}
+	for _, p := range ps {
+		if (p.Kind == "" || p.Kind == kind) && (p.APIVersion == "" || p.APIVersion == apiVersion) {
+			selected = append(selected, p)
+		}
+	}
+	return selected
+}
+
 // parameterizeFilter returns true if this parameterizer can be applied to the given k8s resource
-func parameterizeFilter(envs []string, k parameterizertypes.K8sResourceT, p parameterizertypes.ParameterizerT) (bool, error) {
-	log.Trace("start parameterizeFilter")
-	defer log.Trace("end parameterizeFilter")
+func parameterizeFilter(logger *log.Logger, envs []string, k parameterizertypes.K8sResourceT, p parameterizertypes.ParameterizerT) (bool, error) {
+	logger.Trace("start parameterizeFilter")
+	defer logger.Trace("end parameterizeFilter")
 	kind, apiVersion, metadataName, err := k8sschema.GetInfoFromK8sResource(k)
 	if err != nil {
 		return false, err
@@ -487,9 +900,9 @@ func parameterizeFilter(envs []string, k parameterizertypes.K8sResourceT, p para
 	return false, nil
 }
 
-func parameterizeHelperHelm(envs []string, k parameterizertypes.K8sResourceT, p parameterizertypes.ParameterizerT, namedValues map[string]parameterizertypes.HelmValuesT, namedKustPatches map[string]map[string]parameterizertypes.PatchT, namedOCParams map[string]map[string]string) error {
-	log.Trace("start parameterizeHelperHelm")
-	defer log.Trace("end parameterizeHelperHelm")
+func parameterizeHelperHelm(logger *log.Logger, envs []string, k parameterizertypes.K8sResourceT, p parameterizertypes.ParameterizerT, namedValues map[string]parameterizertypes.HelmValuesT, helmDescriptions map[string]string, helmTemplateKeys map[string]bool, namedKustPatches map[string]map[string]parameterizertypes.PatchT, namedOCParams map[string]map[string]string) error {
+	logger.Trace("start parameterizeHelperHelm")
+	defer logger.Trace("end parameterizeHelperHelm")
 
 	if len(p.Target) == 0 {
 		return fmt.Errorf("the target is empty")
@@ -498,16 +911,12 @@ func parameterizeHelperHelm(envs []string, k parameterizertypes.K8sResourceT, p
 	if err != nil {
 		return fmt.Errorf("failed to get the kind, apiVersion, and name from the k8s resource: %+v\nError: %q", k, err)
 	}
-	resultKVs, err := GetAll(p.Target, k)
+	resultKVs, err := GetAll(p.Target, k, KeyOptions{Logger: logger})
 	if err != nil {
 		return fmt.Errorf("the key %s does not exist on the k8s resource: %+v Error: %q", p.Target, k, err)
 	}
 	for _, resultKV := range resultKVs {
-		t1 := []string{}
-		for _, k := range resultKV.Key {
-			t1 = append(t1, `"`+k+`"`)
-		}
-		key := strings.Join(t1, ".")
+		key := getKeyFromSubKeys(resultKV.Key)
 		templ := p.Template
 		if templ == "" {
 			templ = fmt.Sprintf(`${"%s"."%s"."%s".%s}`, kind, apiVersion, metadataName, key)
@@ -574,7 +983,12 @@ func parameterizeHelperHelm(envs []string, k parameterizertypes.K8sResourceT, p
 				}
 			}
 			paramKey := strings.Join(subKeys, ".")
+			addHelmDescription(helmDescriptions, paramKey, p, parameter)
 			helmTemplate := fmt.Sprintf(`{{ index .Values %s }}`, strings.Join(subKeys, " "))
+			if isCompositeValue(paramValue) {
+				// lists and maps are replaced as a whole. JSON is valid YAML so the value can be rendered inline.
+				helmTemplate = fmt.Sprintf(`{{ toJson (index .Values %s) }}`, strings.Join(subKeys, " "))
+			}
 			if len(p.Parameters) > 0 {
 				if len(p.Parameters) != 1 {
 					return fmt.Errorf("the template only has a single parameter. Expected a single paramter definition. Actual length: %d Parameters: %+v", len(p.Parameters), p.Parameters)
@@ -593,6 +1007,7 @@ func parameterizeHelperHelm(envs []string, k parameterizertypes.K8sResourceT, p
 			if err := set(key, helmTemplate, k); err != nil {
 				return fmt.Errorf("failed to set the key %s to the value %s in the k8s resource: %+v\nError: %q", key, helmTemplate, k, err)
 			}
+			helmTemplateKeys[key] = true
 			for _, env := range envs {
 				origParamValue := paramValue
 				if len(p.Parameters) > 0 {
@@ -698,10 +1113,12 @@ Actual value is %+v of type %T`,
 		if err := set(key, fullHelmTemplate, k); err != nil {
 			return fmt.Errorf("failed to set the key %s to the value %s in the k8s resource: %+v\nError: %q", key, fullHelmTemplate, k, err)
 		}
+		helmTemplateKeys[key] = true
 		// set all the keys in the values.yaml
 		for i, parameter := range parameters {
 			paramKey := paramKeys[i]
 			paramValue := originalValues[i]
+			addHelmDescription(helmDescriptions, paramKey, p, parameter)
 			for _, env := range envs {
 				origParamValue := paramValue
 				for _, param := range p.Parameters {
@@ -733,9 +1150,9 @@ Actual value is %+v of type %T`,
 	return nil
 }
 
-func parameterizeHelperKustomize(envs []string, k parameterizertypes.K8sResourceT, p parameterizertypes.ParameterizerT, namedValues map[string]parameterizertypes.HelmValuesT, namedKustPatches map[string]map[string]parameterizertypes.PatchT, namedOCParams map[string]map[string]string) error {
-	log.Trace("start parameterizeHelperKustomize")
-	defer log.Trace("end parameterizeHelperKustomize")
+func parameterizeHelperKustomize(logger *log.Logger, envs []string, k parameterizertypes.K8sResourceT, p parameterizertypes.ParameterizerT, namedValues map[string]parameterizertypes.HelmValuesT, namedKustPatches map[string]map[string]parameterizertypes.PatchT, namedOCParams map[string]map[string]string) error {
+	logger.Trace("start parameterizeHelperKustomize")
+	defer logger.Trace("end parameterizeHelperKustomize")
 
 	if len(p.Target) == 0 {
 		return fmt.Errorf("the target is empty")
@@ -744,16 +1161,12 @@ func parameterizeHelperKustomize(envs []string, k parameterizertypes.K8sResource
 	if err != nil {
 		return fmt.Errorf("failed to get the kind, apiVersion, and name from the k8s resource: %+v\nError: %q", k, err)
 	}
-	resultKVs, err := GetAll(p.Target, k)
+	resultKVs, err := GetAll(p.Target, k, KeyOptions{Logger: logger})
 	if err != nil {
 		return fmt.Errorf("the key %s does not exist on the k8s resource: %+v Error: %q", p.Target, k, err)
 	}
 	for _, resultKV := range resultKVs {
-		t1 := []string{}
-		for _, k := range resultKV.Key {
-			t1 = append(t1, `"`+k+`"`)
-		}
-		key := strings.Join(t1, ".")
+		key := getKeyFromSubKeys(resultKV.Key)
 		JSONPointer := subKeysToJSONPointer6901(resultKV.Key)
 		paramValue := p.Default
 		if paramValue == nil {
@@ -791,7 +1204,7 @@ func parameterizeHelperKustomize(envs []string, k parameterizertypes.K8sResource
 			origParamValue := paramValue
 			if len(p.Parameters) > 0 {
 				if len(p.Parameters) > 1 {
-					log.Debugf("more than one parameter specified for kustomize parameterization, ignoring all of them. Actual length: %d Parameters: %+v", len(p.Parameters), p.Parameters)
+					logger.Debugf("more than one parameter specified for kustomize parameterization, ignoring all of them. Actual length: %d Parameters: %+v", len(p.Parameters), p.Parameters)
 				} else {
 					param := p.Parameters[0]
 					// no need to check the parameter name since for kustomize there should be at most one parameter
@@ -814,9 +1227,9 @@ func parameterizeHelperKustomize(envs []string, k parameterizertypes.K8sResource
 	return nil
 }
 
-func parameterizeHelperOCTemplates(envs []string, k parameterizertypes.K8sResourceT, p parameterizertypes.ParameterizerT, namedValues map[string]parameterizertypes.HelmValuesT, namedKustPatches map[string]map[string]parameterizertypes.PatchT, namedOCParams map[string]map[string]string) error {
-	log.Trace("start parameterizeHelperOCTemplates")
-	defer log.Trace("end parameterizeHelperOCTemplates")
+func parameterizeHelperOCTemplates(logger *log.Logger, envs []string, k parameterizertypes.K8sResourceT, p parameterizertypes.ParameterizerT, namedValues map[string]parameterizertypes.HelmValuesT, namedKustPatches map[string]map[string]parameterizertypes.PatchT, namedOCParams map[string]map[string]string) error {
+	logger.Trace("start parameterizeHelperOCTemplates")
+	defer logger.Trace("end parameterizeHelperOCTemplates")
 
 	if len(p.Target) == 0 {
 		return fmt.Errorf("the target is empty")
@@ -825,16 +1238,12 @@ func parameterizeHelperOCTemplates(envs []string, k parameterizertypes.K8sResour
 	if err != nil {
 		return fmt.Errorf("failed to get the kind, apiVersion, and name from the k8s resource: %+v\nError: %q", k, err)
 	}
-	resultKVs, err := GetAll(p.Target, k)
+	resultKVs, err := GetAll(p.Target, k, KeyOptions{Logger: logger})
 	if err != nil {
 		return fmt.Errorf("the key %s does not exist on the k8s resource: %+v Error: %q", p.Target, k, err)
 	}
 	for _, resultKV := range resultKVs {
-		t1 := []string{}
-		for _, k := range resultKV.Key {
-			t1 = append(t1, `"`+k+`"`)
-		}
-		key := strings.Join(t1, ".")
+		key := getKeyFromSubKeys(resultKV.Key)
 		templ := p.Template
 		if templ == "" {
 			templ = fmt.Sprintf(`${"%s"."%s"."%s".%s}`, kind, apiVersion, metadataName, key)
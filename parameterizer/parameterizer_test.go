@@ -0,0 +1,467 @@
+/*
+ *  Copyright IBM Corporation 2021
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package parameterizer_test
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/konveyor/move2kube/internal/common"
+	"github.com/konveyor/move2kube/parameterizer"
+	parameterizertypes "github.com/konveyor/move2kube/types/parameterizer"
+	"github.com/sirupsen/logrus"
+)
+
+func TestParameterizeHelmValuesDescriptions(t *testing.T) {
+	srcDir, outDir := t.TempDir(), t.TempDir()
+	deployment := `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  replicas: 2
+`
+	if err := ioutil.WriteFile(filepath.Join(srcDir, "deployment.yaml"), []byte(deployment), common.DefaultFilePermission); err != nil {
+		t.Fatalf("failed to write the k8s resource. Error: %q", err)
+	}
+	ps := []parameterizertypes.ParameterizerT{
+		{Target: "spec.replicas", Template: "${common.replicas}", Description: "The number of replicas"},
+	}
+	packSpecPath := parameterizertypes.PackagingSpecPathT{Envs: []string{"dev"}}
+	targets := []parameterizertypes.ParamTargetT{parameterizertypes.TargetHelm}
+	if _, err := parameterizer.Parameterize(context.Background(), parameterizer.ParameterizeOptions{SrcDirs: []string{srcDir}, OutDir: outDir, PackSpecPath: packSpecPath, Parameterizers: ps, Targets: targets}); err != nil {
+		t.Fatalf("failed to parameterize. Error: %q", err)
+	}
+	valuesPath := filepath.Join(outDir, "helm-chart", common.DefaultProjectName, "values-dev.yaml")
+	valuesBytes, err := ioutil.ReadFile(valuesPath)
+	if err != nil {
+		t.Fatalf("failed to read the values file at path %s . Error: %q", valuesPath, err)
+	}
+	want := "common:\n  # The number of replicas\n  replicas: 2\n"
+	if !strings.Contains(string(valuesBytes), want) {
+		t.Fatalf("expected the values file to contain the description as a comment. Actual:\n%s", string(valuesBytes))
+	}
+}
+
+func TestParameterizeByKind(t *testing.T) {
+	paramsDir := t.TempDir()
+	paramsYaml := `apiVersion: move2kube.konveyor.io/v1alpha1
+kind: Parameterizer
+metadata:
+  name: deployment-params
+spec:
+  kind: Deployment
+  apiVersion: apps/v1
+  parameterizers:
+    - target: spec.replicas
+      template: ${common.replicas}
+    - target: metadata.name
+      kind: Service
+      apiVersion: v1
+      template: ${common.serviceName}
+`
+	if err := ioutil.WriteFile(filepath.Join(paramsDir, "params.yaml"), []byte(paramsYaml), common.DefaultFilePermission); err != nil {
+		t.Fatalf("failed to write the parameterizer file. Error: %q", err)
+	}
+	namedPs, err := parameterizer.CollectParamsFromPath(nil, paramsDir)
+	if err != nil {
+		t.Fatalf("failed to collect the parameterizers. Error: %q", err)
+	}
+	ps := namedPs["deployment-params"]
+	if len(ps) != 2 || ps[0].Kind != "Deployment" || ps[0].APIVersion != "apps/v1" || ps[1].Kind != "Service" || ps[1].APIVersion != "v1" {
+		t.Fatalf("expected the kind and apiVersion of the file to apply to the parameterizers without their own. Actual: %+v", ps)
+	}
+	srcDir, outDir := t.TempDir(), t.TempDir()
+	resources := map[string]string{
+		"deployment.yaml":  "apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: web\nspec:\n  replicas: 2\n",
+		"statefulset.yaml": "apiVersion: apps/v1\nkind: StatefulSet\nmetadata:\n  name: db\nspec:\n  replicas: 3\n",
+	}
+	for filename, resource := range resources {
+		if err := ioutil.WriteFile(filepath.Join(srcDir, filename), []byte(resource), common.DefaultFilePermission); err != nil {
+			t.Fatalf("failed to write the k8s resource. Error: %q", err)
+		}
+	}
+	packSpecPath := parameterizertypes.PackagingSpecPathT{Envs: []string{"dev"}}
+	targets := []parameterizertypes.ParamTargetT{parameterizertypes.TargetHelm}
+	if _, err := parameterizer.Parameterize(context.Background(), parameterizer.ParameterizeOptions{SrcDirs: []string{srcDir}, OutDir: outDir, PackSpecPath: packSpecPath, Parameterizers: ps, Targets: targets}); err != nil {
+		t.Fatalf("failed to parameterize. Error: %q", err)
+	}
+	templatesDir := filepath.Join(outDir, "helm-chart", common.DefaultProjectName, "templates")
+	templatePaths, err := common.GetFilesByExt(templatesDir, []string{".yaml"})
+	if err != nil {
+		t.Fatalf("failed to get the helm templates. Error: %q", err)
+	}
+	template := ""
+	for _, templatePath := range templatePaths {
+		templateBytes, err := ioutil.ReadFile(templatePath)
+		if err != nil {
+			t.Fatalf("failed to read the helm template at path %s . Error: %q", templatePath, err)
+		}
+		template += string(templateBytes)
+	}
+	if strings.Count(template, `.Values "common" "replicas"`) != 1 || !strings.Contains(template, "replicas: 3") {
+		t.Fatalf("expected only the replicas of the Deployment to be parameterized. Actual:\n%s", template)
+	}
+	if strings.Contains(template, "serviceName") {
+		t.Fatalf("expected the Service parameterizer to not be applied. Actual:\n%s", template)
+	}
+}
+
+func TestParameterizeSelectedKinds(t *testing.T) {
+	srcDir, outDir := t.TempDir(), t.TempDir()
+	resources := map[string]string{
+		"deployment.yaml":  "apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: web\nspec:\n  replicas: 2\n",
+		"statefulset.yaml": "apiVersion: apps/v1\nkind: StatefulSet\nmetadata:\n  name: db\nspec:\n  replicas: 3\n",
+	}
+	for filename, resource := range resources {
+		if err := ioutil.WriteFile(filepath.Join(srcDir, filename), []byte(resource), common.DefaultFilePermission); err != nil {
+			t.Fatalf("failed to write the k8s resource. Error: %q", err)
+		}
+	}
+	ps := []parameterizertypes.ParameterizerT{{Target: "spec.replicas", Template: "${common.replicas}"}}
+	packSpecPath := parameterizertypes.PackagingSpecPathT{Envs: []string{"dev"}}
+	targets := []parameterizertypes.ParamTargetT{parameterizertypes.TargetHelm}
+	kinds := []string{"deployment"}
+	if _, err := parameterizer.Parameterize(context.Background(), parameterizer.ParameterizeOptions{SrcDirs: []string{srcDir}, OutDir: outDir, PackSpecPath: packSpecPath, Parameterizers: ps, Targets: targets, Kinds: kinds}); err != nil {
+		t.Fatalf("failed to parameterize. Error: %q", err)
+	}
+	templatesDir := filepath.Join(outDir, "helm-chart", common.DefaultProjectName, "templates")
+	deploymentBytes, err := ioutil.ReadFile(filepath.Join(templatesDir, "deployment.yaml"))
+	if err != nil {
+		t.Fatalf("failed to read the helm template for the Deployment. Error: %q", err)
+	}
+	if !strings.Contains(string(deploymentBytes), `.Values "common" "replicas"`) {
+		t.Fatalf("expected the replicas of the selected Deployment to be parameterized. Actual:\n%s", string(deploymentBytes))
+	}
+	statefulSetBytes, err := ioutil.ReadFile(filepath.Join(templatesDir, "statefulset.yaml"))
+	if err != nil {
+		t.Fatalf("failed to read the helm template for the StatefulSet. Error: %q", err)
+	}
+	if strings.Contains(string(statefulSetBytes), ".Values") || !strings.Contains(string(statefulSetBytes), "replicas: 3") {
+		t.Fatalf("expected the StatefulSet to stay unparameterized since its kind was not selected. Actual:\n%s", string(statefulSetBytes))
+	}
+}
+
+func TestParameterizeDiff(t *testing.T) {
+	srcDir, outDir := t.TempDir(), t.TempDir()
+	deployment := `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  replicas: 2
+`
+	if err := ioutil.WriteFile(filepath.Join(srcDir, "deployment.yaml"), []byte(deployment), common.DefaultFilePermission); err != nil {
+		t.Fatalf("failed to write the k8s resource. Error: %q", err)
+	}
+	ps := []parameterizertypes.ParameterizerT{
+		{Target: "spec.replicas", Template: "${common.replicas}"},
+	}
+	packSpecPath := parameterizertypes.PackagingSpecPathT{Envs: []string{"dev"}}
+	targets := []parameterizertypes.ParamTargetT{parameterizertypes.TargetHelm}
+	diff := strings.Builder{}
+	if _, err := parameterizer.Parameterize(context.Background(), parameterizer.ParameterizeOptions{SrcDirs: []string{srcDir}, OutDir: outDir, PackSpecPath: packSpecPath, Parameterizers: ps, Targets: targets, DiffOut: &diff}); err != nil {
+		t.Fatalf("failed to parameterize. Error: %q", err)
+	}
+	want := `--- deployment.yaml
++++ helm-chart/` + common.DefaultProjectName + `/templates/deployment.yaml
+@@ -3,4 +3,4 @@
+ metadata:
+     name: web
+ spec:
+-    replicas: 2
++    replicas: {{ index .Values "common" "replicas" }}
+`
+	if diff.String() != want {
+		t.Fatalf("failed to get the correct diff. Expected:\n%s\nActual:\n%s", want, diff.String())
+	}
+}
+
+func TestParameterizeLogger(t *testing.T) {
+	srcDir, outDir := t.TempDir(), t.TempDir()
+	if err := ioutil.WriteFile(filepath.Join(srcDir, "deployment.yaml"), []byte("apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: web\n"), common.DefaultFilePermission); err != nil {
+		t.Fatalf("failed to write the k8s resource. Error: %q", err)
+	}
+	logs := bytes.Buffer{}
+	logger := logrus.New()
+	logger.SetOutput(&logs)
+	opts := parameterizer.ParameterizeOptions{
+		SrcDirs:      []string{srcDir},
+		OutDir:       outDir,
+		PackSpecPath: parameterizertypes.PackagingSpecPathT{Envs: []string{"dev"}},
+		Targets:      []parameterizertypes.ParamTargetT{parameterizertypes.TargetKustomize},
+		DiffOut:      &strings.Builder{},
+		Logger:       logger,
+	}
+	if _, err := parameterizer.Parameterize(context.Background(), opts); err != nil {
+		t.Fatalf("failed to parameterize. Error: %q", err)
+	}
+	if !strings.Contains(logs.String(), "The diff is only generated for the Helm output") {
+		t.Fatalf("expected the logs to be written to the given logger. Actual:\n%s", logs.String())
+	}
+}
+
+func TestParameterizeListValue(t *testing.T) {
+	srcDir, outDir := t.TempDir(), t.TempDir()
+	deployment := `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  template:
+    spec:
+      containers:
+        - name: web
+          args:
+            - --port
+            - "8080"
+`
+	if err := ioutil.WriteFile(filepath.Join(srcDir, "deployment.yaml"), []byte(deployment), common.DefaultFilePermission); err != nil {
+		t.Fatalf("failed to write the k8s resource. Error: %q", err)
+	}
+	ps := []parameterizertypes.ParameterizerT{
+		{Target: "spec.template.spec.containers.[0].args", Template: "${common.args}"},
+	}
+	opts := parameterizer.ParameterizeOptions{
+		SrcDirs:        []string{srcDir},
+		OutDir:         outDir,
+		PackSpecPath:   parameterizertypes.PackagingSpecPathT{Envs: []string{"dev"}},
+		Parameterizers: ps,
+		Targets:        []parameterizertypes.ParamTargetT{parameterizertypes.TargetHelm},
+	}
+	if _, err := parameterizer.Parameterize(context.Background(), opts); err != nil {
+		t.Fatalf("failed to parameterize. Error: %q", err)
+	}
+	helmChartDir := filepath.Join(outDir, "helm-chart", common.DefaultProjectName)
+	templateBytes, err := ioutil.ReadFile(filepath.Join(helmChartDir, "templates", "deployment.yaml"))
+	if err != nil {
+		t.Fatalf("failed to read the Helm template. Error: %q", err)
+	}
+	if want := `args: {{ toJson (index .Values "common" "args") }}`; !strings.Contains(string(templateBytes), want) {
+		t.Fatalf("expected the whole list to be replaced by a single Helm value. Actual:\n%s", string(templateBytes))
+	}
+	valuesBytes, err := ioutil.ReadFile(filepath.Join(helmChartDir, "values-dev.yaml"))
+	if err != nil {
+		t.Fatalf("failed to read the values file. Error: %q", err)
+	}
+	if want := "common:\n  args:\n    - --port\n    - \"8080\"\n"; !strings.Contains(string(valuesBytes), want) {
+		t.Fatalf("expected the list to be in the values file. Actual:\n%s", string(valuesBytes))
+	}
+}
+
+func TestParameterizeKeepsLiteralTemplatesQuoted(t *testing.T) {
+	srcDir, outDir := t.TempDir(), t.TempDir()
+	deployment := `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+  annotations:
+    docs: '{{ not a helm template }}'
+spec:
+  replicas: 2
+`
+	if err := ioutil.WriteFile(filepath.Join(srcDir, "deployment.yaml"), []byte(deployment), common.DefaultFilePermission); err != nil {
+		t.Fatalf("failed to write the k8s resource. Error: %q", err)
+	}
+	ps := []parameterizertypes.ParameterizerT{
+		{Target: "spec.replicas", Template: "${common.replicas}"},
+	}
+	opts := parameterizer.ParameterizeOptions{
+		SrcDirs:        []string{srcDir},
+		OutDir:         outDir,
+		PackSpecPath:   parameterizertypes.PackagingSpecPathT{Envs: []string{"dev"}},
+		Parameterizers: ps,
+		Targets:        []parameterizertypes.ParamTargetT{parameterizertypes.TargetHelm},
+	}
+	if _, err := parameterizer.Parameterize(context.Background(), opts); err != nil {
+		t.Fatalf("failed to parameterize. Error: %q", err)
+	}
+	templateBytes, err := ioutil.ReadFile(filepath.Join(outDir, "helm-chart", common.DefaultProjectName, "templates", "deployment.yaml"))
+	if err != nil {
+		t.Fatalf("failed to read the Helm template. Error: %q", err)
+	}
+	if want := `replicas: {{ index .Values "common" "replicas" }}`; !strings.Contains(string(templateBytes), want) {
+		t.Fatalf("expected the parameterized value to be unquoted. Actual:\n%s", string(templateBytes))
+	}
+	if want := `docs: '{{ not a helm template }}'`; !strings.Contains(string(templateBytes), want) {
+		t.Fatalf("expected the literal value to stay quoted. Actual:\n%s", string(templateBytes))
+	}
+}
+
+func TestParameterizeHelmChartScaffold(t *testing.T) {
+	srcDir, outDir := t.TempDir(), t.TempDir()
+	deployment := "apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: web\nspec:\n  replicas: 2\n"
+	if err := ioutil.WriteFile(filepath.Join(srcDir, "deployment.yaml"), []byte(deployment), common.DefaultFilePermission); err != nil {
+		t.Fatalf("failed to write the k8s resource. Error: %q", err)
+	}
+	opts := parameterizer.ParameterizeOptions{
+		SrcDirs:        []string{srcDir},
+		OutDir:         outDir,
+		PackSpecPath:   parameterizertypes.PackagingSpecPathT{Envs: []string{"dev", "prod"}, HelmChartName: "web", HelmChartVersion: "1.2.3"},
+		Parameterizers: []parameterizertypes.ParameterizerT{{Target: "spec.replicas", Template: "${common.replicas}"}},
+		Targets:        []parameterizertypes.ParamTargetT{parameterizertypes.TargetHelm},
+	}
+	if _, err := parameterizer.Parameterize(context.Background(), opts); err != nil {
+		t.Fatalf("failed to parameterize. Error: %q", err)
+	}
+	helmChartDir := filepath.Join(outDir, "helm-chart", "web")
+	chartBytes, err := ioutil.ReadFile(filepath.Join(helmChartDir, "Chart.yaml"))
+	if err != nil {
+		t.Fatalf("failed to read the Chart.yaml . Error: %q", err)
+	}
+	if !strings.Contains(string(chartBytes), "name: web\n") || !strings.Contains(string(chartBytes), "version: 1.2.3\n") {
+		t.Fatalf("expected the chart name and version to be set. Actual:\n%s", string(chartBytes))
+	}
+	valuesBytes, err := ioutil.ReadFile(filepath.Join(helmChartDir, "values.yaml"))
+	if err != nil {
+		t.Fatalf("failed to read the default values file. Error: %q", err)
+	}
+	if want := "common:\n  replicas: 2\n"; string(valuesBytes) != want {
+		t.Fatalf("expected the default values file to have the values of the first env. Expected:\n%s\nActual:\n%s", want, string(valuesBytes))
+	}
+	opts.OutDir = t.TempDir()
+	opts.PackSpecPath.HelmChartVersion = "latest"
+	if _, err := parameterizer.Parameterize(context.Background(), opts); err == nil {
+		t.Fatalf("expected an error for an invalid chart version")
+	}
+}
+
+func TestParameterizeKubernetesTarget(t *testing.T) {
+	srcDir, outDir := t.TempDir(), t.TempDir()
+	deployment := "apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: web\nspec:\n  replicas: 2\n"
+	if err := ioutil.WriteFile(filepath.Join(srcDir, "deployment.yaml"), []byte(deployment), common.DefaultFilePermission); err != nil {
+		t.Fatalf("failed to write the k8s resource. Error: %q", err)
+	}
+	ps := []parameterizertypes.ParameterizerT{{
+		Target:     "spec.replicas",
+		Template:   "${replicas}",
+		Parameters: []parameterizertypes.ParameterT{{Name: "replicas", Values: []parameterizertypes.ParameterValueT{{Envs: []string{"prod"}, Value: "5"}}}},
+	}}
+	packSpecPath := parameterizertypes.PackagingSpecPathT{Envs: []string{"dev", "prod"}}
+	targets := []parameterizertypes.ParamTargetT{parameterizertypes.TargetKubernetes}
+	if _, err := parameterizer.Parameterize(context.Background(), parameterizer.ParameterizeOptions{SrcDirs: []string{srcDir}, OutDir: outDir, PackSpecPath: packSpecPath, Parameterizers: ps, Targets: targets}); err != nil {
+		t.Fatalf("failed to parameterize. Error: %q", err)
+	}
+	manifestBytes, err := ioutil.ReadFile(filepath.Join(outDir, "kubernetes", "manifests", "deployment.yaml"))
+	if err != nil {
+		t.Fatalf("failed to read the concrete k8s resource. Error: %q", err)
+	}
+	if !strings.Contains(string(manifestBytes), "replicas: 2") {
+		t.Fatalf("expected the k8s resource to be left concrete. Actual:\n%s", string(manifestBytes))
+	}
+	patches := []parameterizertypes.KubernetesPatchT{}
+	if err := common.ReadYaml(filepath.Join(outDir, "kubernetes", "patches", "prod.yaml"), &patches); err != nil {
+		t.Fatalf("failed to read the patch file. Error: %q", err)
+	}
+	want := []parameterizertypes.KubernetesPatchT{{
+		Target: parameterizertypes.PatchMetadataTargetT{Group: "apps", Version: "v1", Kind: "Deployment", Name: "web"},
+		Patch:  []parameterizertypes.PatchT{{Op: parameterizertypes.ReplaceOp, Path: "/spec/replicas", Value: "5"}},
+	}}
+	if !cmp.Equal(patches, want) {
+		t.Fatalf("the patch file is wrong. Difference:\n%s", cmp.Diff(want, patches))
+	}
+	if _, err := os.Stat(filepath.Join(outDir, "helm-chart")); !os.IsNotExist(err) {
+		t.Fatalf("expected only the kubernetes output to be written. Error: %q", err)
+	}
+}
+
+func TestParameterizeHeader(t *testing.T) {
+	oldValue, wasSet := os.LookupEnv("SOURCE_DATE_EPOCH")
+	t.Cleanup(func() {
+		if wasSet {
+			os.Setenv("SOURCE_DATE_EPOCH", oldValue)
+			return
+		}
+		os.Unsetenv("SOURCE_DATE_EPOCH")
+	})
+	srcDir := t.TempDir()
+	deployment := "apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: web\nspec:\n  replicas: 2\n"
+	if err := ioutil.WriteFile(filepath.Join(srcDir, "deployment.yaml"), []byte(deployment), common.DefaultFilePermission); err != nil {
+		t.Fatalf("failed to write the k8s resource. Error: %q", err)
+	}
+	ps := []parameterizertypes.ParameterizerT{{Target: "spec.replicas", Template: "${common.replicas}"}}
+	packSpecPath := parameterizertypes.PackagingSpecPathT{Envs: []string{"dev"}}
+	targets := []parameterizertypes.ParamTargetT{parameterizertypes.TargetHelm}
+	getTemplate := func() string {
+		outDir := t.TempDir()
+		opts := parameterizer.ParameterizeOptions{SrcDirs: []string{srcDir}, OutDir: outDir, PackSpecPath: packSpecPath, Parameterizers: ps, Targets: targets, Header: true, Reproducible: true}
+		if _, err := parameterizer.Parameterize(context.Background(), opts); err != nil {
+			t.Fatalf("failed to parameterize. Error: %q", err)
+		}
+		templateBytes, err := ioutil.ReadFile(filepath.Join(outDir, "helm-chart", common.DefaultProjectName, "templates", "deployment.yaml"))
+		if err != nil {
+			t.Fatalf("failed to read the helm template. Error: %q", err)
+		}
+		return string(templateBytes)
+	}
+	os.Unsetenv("SOURCE_DATE_EPOCH")
+	template := getTemplate()
+	if !strings.HasPrefix(template, "# Generated by move2kube\n# Source: deployment.yaml\n") || strings.Contains(template, "Timestamp") {
+		t.Fatalf("expected the header without the timestamp for reproducible output. Actual:\n%s", template)
+	}
+	if otherTemplate := getTemplate(); otherTemplate != template {
+		t.Fatalf("expected the same output on every run. Difference:\n%s", cmp.Diff(template, otherTemplate))
+	}
+	os.Setenv("SOURCE_DATE_EPOCH", "1600000000")
+	if template := getTemplate(); !strings.Contains(template, "# Timestamp: 2020-09-13T12:26:40Z\n") {
+		t.Fatalf("expected the timestamp from SOURCE_DATE_EPOCH in the header. Actual:\n%s", template)
+	}
+}
+
+func TestParameterizeTwiceIntoSameDir(t *testing.T) {
+	srcDir, outDir := t.TempDir(), t.TempDir()
+	deployment := "apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: web\nspec:\n  replicas: 2\n"
+	if err := ioutil.WriteFile(filepath.Join(srcDir, "web.yaml"), []byte(deployment), common.DefaultFilePermission); err != nil {
+		t.Fatalf("failed to write the k8s resource. Error: %q", err)
+	}
+	ps := []parameterizertypes.ParameterizerT{{Target: "spec.replicas", Template: "${common.replicas}"}}
+	packSpecPath := parameterizertypes.PackagingSpecPathT{Envs: []string{"dev"}}
+	targets := []parameterizertypes.ParamTargetT{parameterizertypes.TargetHelm, parameterizertypes.TargetKustomize, parameterizertypes.TargetKubernetes}
+	paths := []string{
+		filepath.Join(outDir, "helm-chart", common.DefaultProjectName, "templates", "web.yaml"),
+		filepath.Join(outDir, "kustomize", "base", "web.yaml"),
+		filepath.Join(outDir, "kubernetes", "manifests", "web.yaml"),
+	}
+	contents := map[string]string{}
+	for i := 0; i < 2; i++ {
+		if _, err := parameterizer.Parameterize(context.Background(), parameterizer.ParameterizeOptions{SrcDirs: []string{srcDir}, OutDir: outDir, PackSpecPath: packSpecPath, Parameterizers: ps, Targets: targets}); err != nil {
+			t.Fatalf("failed to parameterize. Error: %q", err)
+		}
+		for _, path := range paths {
+			pathBytes, err := ioutil.ReadFile(path)
+			if err != nil {
+				t.Fatalf("failed to read the file at path %s . Error: %q", path, err)
+			}
+			if strings.Count(string(pathBytes), "kind: Deployment") != 1 {
+				t.Fatalf("expected the file at path %s to have the k8s resource only once. Actual:\n%s", path, string(pathBytes))
+			}
+			if i == 0 {
+				contents[path] = string(pathBytes)
+				continue
+			}
+			if string(pathBytes) != contents[path] {
+				t.Fatalf("expected the second run to write the same file at path %s . Difference:\n%s", path, cmp.Diff(contents[path], string(pathBytes)))
+			}
+		}
+	}
+}
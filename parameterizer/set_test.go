@@ -0,0 +1,124 @@
+/*
+ *  Copyright IBM Corporation 2021
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package parameterizer
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestSet(t *testing.T) {
+	getConfig := func() map[string]interface{} {
+		return map[string]interface{}{
+			"spec": map[string]interface{}{
+				"replicas":   1,
+				"containers": []interface{}{map[string]interface{}{"image": "i1"}},
+			},
+		}
+	}
+	t.Run("existing keys are updated", func(t *testing.T) {
+		config := getConfig()
+		if err := set("spec.containers.[0].image", "i2", config); err != nil {
+			t.Fatalf("failed to set the key. Error: %q", err)
+		}
+		want := map[string]interface{}{"spec": map[string]interface{}{"replicas": 1, "containers": []interface{}{map[string]interface{}{"image": "i2"}}}}
+		if !cmp.Equal(config, want) {
+			t.Fatalf("failed to set the key properly. Differences:\n%s", cmp.Diff(want, config))
+		}
+	})
+	t.Run("last element is updated", func(t *testing.T) {
+		config := getConfig()
+		if err := set("spec.containers.[last].image", "i2", config); err != nil {
+			t.Fatalf("failed to set the key. Error: %q", err)
+		}
+		want := map[string]interface{}{"spec": map[string]interface{}{"replicas": 1, "containers": []interface{}{map[string]interface{}{"image": "i2"}}}}
+		if !cmp.Equal(config, want) {
+			t.Fatalf("failed to set the key properly. Differences:\n%s", cmp.Diff(want, config))
+		}
+	})
+	testcases := []struct {
+		name string
+		key  string
+	}{
+		{name: "empty key", key: ""},
+		{name: "missing intermediate key", key: "spec.template.replicas"},
+		{name: "missing last key", key: "spec.paused"},
+		{name: "index out of range", key: "spec.containers.[1].image"},
+		{name: "scalar value in the path", key: "spec.replicas.foo"},
+	}
+	for _, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			config := getConfig()
+			if err := set(testcase.key, "foo", config); err == nil {
+				t.Fatalf("expected an error for the key %s", testcase.key)
+			}
+			if want := getConfig(); !cmp.Equal(config, want) {
+				t.Fatalf("expected the config to be unchanged. Differences:\n%s", cmp.Diff(want, config))
+			}
+		})
+	}
+}
+
+func TestSetCreatingNew(t *testing.T) {
+	testcases := []struct {
+		name   string
+		key    string
+		config map[string]interface{}
+		want   map[string]interface{}
+	}{
+		{
+			name:   "missing keys are created",
+			key:    "aaa.bbb.ccc",
+			config: map[string]interface{}{},
+			want:   map[string]interface{}{"aaa": map[string]interface{}{"bbb": map[string]interface{}{"ccc": "foo"}}},
+		},
+		{
+			name:   "existing keys are updated",
+			key:    "aaa.bbb",
+			config: map[string]interface{}{"aaa": map[string]interface{}{"bbb": "bar", "ccc": "baz"}},
+			want:   map[string]interface{}{"aaa": map[string]interface{}{"bbb": "foo", "ccc": "baz"}},
+		},
+		{
+			name:   "values that are not maps are replaced",
+			key:    "aaa.bbb.ccc",
+			config: map[string]interface{}{"aaa": map[string]interface{}{"bbb": []interface{}{"bar"}}},
+			want:   map[string]interface{}{"aaa": map[string]interface{}{"bbb": map[string]interface{}{"ccc": "foo"}}},
+		},
+		{
+			name:   "quoted sub keys",
+			key:    `aaa."bbb.ccc"`,
+			config: map[string]interface{}{},
+			want:   map[string]interface{}{"aaa": map[string]interface{}{"bbb.ccc": "foo"}},
+		},
+	}
+	for _, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			if err := setCreatingNew(testcase.key, "foo", testcase.config); err != nil {
+				t.Fatalf("failed to set the key %s . Error: %q", testcase.key, err)
+			}
+			if !cmp.Equal(testcase.config, testcase.want) {
+				t.Fatalf("failed to set the key properly. Differences:\n%s", cmp.Diff(testcase.want, testcase.config))
+			}
+		})
+	}
+	t.Run("empty key", func(t *testing.T) {
+		if err := setCreatingNew("", "foo", map[string]interface{}{}); err == nil {
+			t.Fatalf("expected an error for an empty key")
+		}
+	})
+}
@@ -17,103 +17,546 @@
 package parameterizer
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
+	"io"
 	"os"
+	"path/filepath"
 	"regexp"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/konveyor/move2kube/internal/common"
+	"github.com/konveyor/move2kube/internal/common/deepcopy"
+	"github.com/konveyor/move2kube/types"
 	parameterizertypes "github.com/konveyor/move2kube/types/parameterizer"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cast"
 	"gopkg.in/yaml.v3"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+const (
+	// lastIndexSubKey is the sub key for the last element of a slice
+	lastIndexSubKey = "[last]"
+	// mapEntryValueMatchKey is the match key used to match the scalar entries of a map by their values. Example: [label:value=web]
+	mapEntryValueMatchKey = "value"
+	// allIndicesSubKey is the sub key for all the elements of a slice. It is the same as [:]
+	allIndicesSubKey = "[*]"
+	// sourceDateEpochEnvKey is the environment variable used to pin the timestamps for reproducible output
+	sourceDateEpochEnvKey = "SOURCE_DATE_EPOCH"
+	// helmTemplatePlaceholderFormat is the format of the placeholders that stand in for the Helm templates while encoding
+	helmTemplatePlaceholderFormat = "__move2kube_helm_template_%d__"
 )
 
 var (
 	arrayIndexRegex      = regexp.MustCompile(`^\[(\d+)\]$`)
-	complexSubKeyRegex   = regexp.MustCompile(`^\[(\w+:)?(\w+)(=.+)?\]$`)
+	arrayRangeRegex      = regexp.MustCompile(`^\[(-?\d*):(-?\d*)\]$`)
+	complexSubKeyRegex   = regexp.MustCompile(`^\[(\w+:)?(\w+(?:\.\w+)*)(=.+)?\]$`)
 	stripHelmQuotesRegex = regexp.MustCompile(`'({{.+}})'`)
+	// matches lines like "key: |", "key: >-" and "- |2" that start a block scalar
+	blockScalarHeaderRegex = regexp.MustCompile(`(^|:|-)\s*[|>][-+]?[0-9]?\s*$`)
+	helmTemplateRegex      = regexp.MustCompile(`{{.*?}}`)
+	errStopWalk            = errors.New("stop walking")
+	bracketEscaper         = strings.NewReplacer("[", `\[`, "]", `\]`)
+	bracketUnescaper       = strings.NewReplacer(`\[`, "[", `\]`, "]")
+	escapedBracketRemover  = strings.NewReplacer(`\[`, "", `\]`, "")
 )
 
-// RT has Key, Value and Matches
+// RT has Key, Value and Matches.
+// Matches and Indices contain the matched value and the chosen array index for each complex subkey.
+// When the complex subkey is used on a map, Matches contains the matched map key instead and there is no index.
 type RT struct {
 	Key     []string
 	Value   interface{}
 	Matches map[string]string
+	Indices map[string]int
+}
+
+// KeyOptions controls how the sub keys of a key are matched
+type KeyOptions struct {
+	// CaseInsensitive matches map keys ignoring case when there is no exact match
+	CaseInsensitive bool
+	// Logger is used for the logs. If nil, the standard logger of logrus is used.
+	Logger *logrus.Logger
+}
+
+// getLogger returns the logger, or the standard logger of logrus if it is nil
+func getLogger(logger *logrus.Logger) *logrus.Logger {
+	if logger == nil {
+		return logrus.StandardLogger()
+	}
+	return logger
+}
+
+func getKeyOptions(opts []KeyOptions) KeyOptions {
+	if len(opts) == 0 {
+		return KeyOptions{}
+	}
+	return opts[0]
 }
 
 func isNormal(k string) bool {
-	return !strings.Contains(k, "[") || arrayIndexRegex.MatchString(k)
+	return !strings.Contains(removeEscapedBrackets(k), "[") || arrayIndexRegex.MatchString(k) || k == lastIndexSubKey
+}
+
+// escapeBrackets escapes the brackets in a map key so that they are not treated as an index or a selector.
+// Example a[b] -> a\[b\]
+func escapeBrackets(mapKey string) string {
+	return bracketEscaper.Replace(mapKey)
+}
+
+// unescapeBrackets returns the map key for a sub key with escaped brackets.
+// Example a\[b\] -> a[b]
+func unescapeBrackets(subKey string) string {
+	return bracketUnescaper.Replace(subKey)
+}
+
+// removeEscapedBrackets removes the escaped brackets so that the remaining brackets can be checked
+func removeEscapedBrackets(subKey string) string {
+	return escapedBracketRemover.Replace(subKey)
+}
+
+// ValidateKey returns a descriptive error if the key is malformed.
+// It checks for empty sub keys, unterminated or empty quotes and invalid brackets.
+func ValidateKey(key string) error {
+	if key == "" {
+		return fmt.Errorf("the key is an empty string")
+	}
+	subKeys, quote := splitKey(key)
+	if quote != 0 {
+		return fmt.Errorf("the key %s has an unterminated %c quote", key, quote)
+	}
+	for i, subKey := range subKeys {
+		if subKey == "" {
+			return fmt.Errorf("the sub key at position %d in the key %s is empty", i, key)
+		}
+		if unquoted := common.StripQuotes(subKey); unquoted != subKey {
+			if unquoted == "" {
+				return fmt.Errorf("the quoted sub key %s in the key %s is empty", subKey, key)
+			}
+			continue
+		}
+		if arrayIndexRegex.MatchString(subKey) {
+			if _, ok := getIndex(subKey); !ok {
+				return fmt.Errorf("the sub key %s in the key %s is not a valid index. Indices must not have leading zeros or overflow", subKey, key)
+			}
+			continue
+		}
+		if isRange(subKey) {
+			if _, _, err := getSliceRange(subKey, 0); err != nil {
+				return fmt.Errorf("the sub key %s in the key %s is not a valid range. Error: %q", subKey, key, err)
+			}
+			continue
+		}
+		if complexSubKeyRegex.MatchString(subKey) {
+			continue
+		}
+		if strings.ContainsAny(subKey, `"'`) {
+			return fmt.Errorf("the sub key %s in the key %s is only partially quoted", subKey, key)
+		}
+		if strings.ContainsAny(removeEscapedBrackets(subKey), "[]") {
+			return fmt.Errorf("the sub key %s in the key %s has unbalanced or invalid brackets", subKey, key)
+		}
+	}
+	return nil
 }
 
-// GetAll returns all the keys that matched and all corresponding values
-func GetAll(key string, resource interface{}) ([]RT, error) {
+// GetAll returns all the keys that matched and all corresponding values.
+// Map keys are matched case sensitively unless the CaseInsensitive option is set.
+func GetAll(key string, resource interface{}, opts ...KeyOptions) ([]RT, error) {
 	results := []RT{}
-	subKeys := GetSubKeys(key)
-	currentResult := RT{}
-	err := getRecurse(subKeys, 0, resource, currentResult, &results)
+	err := WalkAll(key, resource, func(result RT) error {
+		results = append(results, result)
+		return nil
+	}, opts...)
+	return results, err
+}
+
+// CompiledKey is a key that has already been validated and split into sub keys.
+// It can be reused across many lookups to avoid parsing the key each time.
+type CompiledKey struct {
+	key     string
+	subKeys []string
+}
+
+// CompileKey validates the key and splits it into sub keys
+func CompileKey(key string) (CompiledKey, error) {
+	if err := ValidateKey(key); err != nil {
+		return CompiledKey{}, err
+	}
+	return CompiledKey{key: key, subKeys: GetSubKeys(key)}, nil
+}
+
+// String returns the original key
+func (ck CompiledKey) String() string {
+	return ck.key
+}
+
+// GetCompiled is like GetAll but it uses a key that has already been compiled
+func GetCompiled(ck CompiledKey, config interface{}, opts ...KeyOptions) ([]RT, error) {
+	if len(ck.subKeys) == 0 {
+		return nil, fmt.Errorf("the compiled key is empty. Use CompileKey to create it")
+	}
+	results := []RT{}
+	err := getRecurse(ck.subKeys, 0, config, RT{}, func(result RT) error {
+		results = append(results, result)
+		return nil
+	}, nil, getKeyOptions(opts))
+	return results, err
+}
+
+// GetAllFrom is like GetAll but it takes a key that has already been split into sub keys, for example using GetSubKeys.
+// The resource can be a subtree of a larger config, in which case the keys in the results are relative to the subtree.
+func GetAllFrom(subKeys []string, resource interface{}) ([]RT, error) {
+	if len(subKeys) == 0 {
+		return nil, fmt.Errorf("no sub keys were given")
+	}
+	for i, subKey := range subKeys {
+		if subKey == "" {
+			return nil, fmt.Errorf("the sub key at position %d is empty", i)
+		}
+	}
+	results := []RT{}
+	err := getRecurse(subKeys, 0, resource, RT{}, func(result RT) error {
+		results = append(results, result)
+		return nil
+	}, nil, KeyOptions{})
 	return results, err
 }
 
-// getRecurse recurses on the value and finds all matches for the key
-func getRecurse(subKeys []string, subKeyIdx int, value interface{}, currentResult RT, results *[]RT) error {
+// CommonPrefix returns the longest sequence of leading sub keys shared by the keys of all the matches.
+// It returns an empty slice if there are no matches.
+func CommonPrefix(results []RT) []string {
+	if len(results) == 0 {
+		return []string{}
+	}
+	prefix := results[0].Key
+	for _, result := range results[1:] {
+		i := 0
+		for i < len(prefix) && i < len(result.Key) && prefix[i] == result.Key[i] {
+			i++
+		}
+		prefix = prefix[:i]
+	}
+	commonPrefix := make([]string, len(prefix))
+	copy(commonPrefix, prefix)
+	return commonPrefix
+}
+
+// GetFirst returns the first key that matched and the corresponding value.
+// It stops the traversal as soon as a match is found.
+func GetFirst(key string, resource interface{}) (RT, bool, error) {
+	first, found := RT{}, false
+	err := WalkAll(key, resource, func(result RT) error {
+		first, found = result, true
+		return errStopWalk
+	})
+	if err != nil && err != errStopWalk {
+		return RT{}, false, err
+	}
+	return first, found, nil
+}
+
+// WalkAll calls visit for each key that matched along with the corresponding value, without collecting the matches.
+// The traversal stops at the first error returned by visit and that error is returned.
+func WalkAll(key string, resource interface{}, visit func(RT) error, opts ...KeyOptions) error {
+	if err := ValidateKey(key); err != nil {
+		return err
+	}
+	return getRecurse(GetSubKeys(key), 0, resource, RT{}, visit, nil, getKeyOptions(opts))
+}
+
+// CountAll returns the number of keys that matched without collecting the matches.
+// Branches that don't match are not counted, so a key that matches nothing returns 0 without an error.
+func CountAll(key string, resource interface{}) (int, error) {
+	if err := ValidateKey(key); err != nil {
+		return 0, err
+	}
+	count := 0
+	visit := func(RT) error {
+		count++
+		return nil
+	}
+	skipBranch := func(error) error { return nil }
+	err := getRecurse(GetSubKeys(key), 0, resource, RT{}, visit, skipBranch, KeyOptions{})
+	return count, err
+}
+
+// Exists returns true if the key matches at least one value in the resource.
+// It stops the traversal at the first match. An invalid key matches nothing.
+func Exists(key string, resource interface{}) bool {
+	if err := ValidateKey(key); err != nil {
+		return false
+	}
+	found := false
+	visit := func(RT) error {
+		found = true
+		return errStopWalk
+	}
+	skipBranch := func(error) error { return nil }
+	err := getRecurse(GetSubKeys(key), 0, resource, RT{}, visit, skipBranch, KeyOptions{})
+	return found && err == errStopWalk
+}
+
+// GetAllLenient is like GetAll but it skips the branches that don't match instead of failing.
+// It returns the successful matches along with the errors for each skipped branch.
+// Only an invalid key causes an error to be returned.
+func GetAllLenient(key string, resource interface{}) ([]RT, []error, error) {
+	if err := ValidateKey(key); err != nil {
+		return nil, nil, err
+	}
+	results := []RT{}
+	branchErrs := []error{}
+	visit := func(result RT) error {
+		results = append(results, result)
+		return nil
+	}
+	onBranchErr := func(err error) error {
+		branchErrs = append(branchErrs, err)
+		return nil
+	}
+	if err := getRecurse(GetSubKeys(key), 0, resource, RT{}, visit, onBranchErr, KeyOptions{}); err != nil {
+		return results, branchErrs, err
+	}
+	return results, branchErrs, nil
+}
+
+// SetAll updates the values at all the keys that matched with the new value.
+// The new value can be a list or a map, in which case it replaces the whole value at each key.
+// A nil new value is written as an explicit null. Use Delete to remove the keys instead.
+// It returns the number of values that were updated.
+// Map keys are matched case sensitively unless the CaseInsensitive option is set.
+func SetAll(key string, newValue interface{}, config interface{}, opts ...KeyOptions) (int, error) {
+	results, err := GetAll(key, config, opts...)
+	if err != nil {
+		return 0, err
+	}
+	for i, result := range results {
+		concreteKey := getKeyFromSubKeys(result.Key)
+		value := newValue
+		if isCompositeValue(newValue) {
+			// each match gets its own copy so that updating one of them later doesn't change the others
+			value = deepcopy.DeepCopy(newValue)
+		}
+		if err := set(concreteKey, value, config); err != nil {
+			return i, fmt.Errorf("failed to set the key %s to the value %+v . Error: %q", concreteKey, newValue, err)
+		}
+	}
+	return len(results), nil
+}
+
+// Delete removes all the keys that matched along with their values. Array elements are removed from the array.
+// It returns the number of keys that were removed.
+// Example: Delete("spec.containers.[name=debug]", k) removes the debug container.
+func Delete(key string, config interface{}) (int, error) {
+	results, err := GetAll(key, config)
+	if err != nil {
+		return 0, err
+	}
+	// remove in the reverse order so that removing an array element doesn't change the indices of the remaining matches
+	for i := len(results) - 1; i >= 0; i-- {
+		if err := deleteSubKeys(results[i].Key, config); err != nil {
+			return len(results) - 1 - i, fmt.Errorf("failed to delete the key %s . Error: %q", getKeyFromSubKeys(results[i].Key), err)
+		}
+	}
+	return len(results), nil
+}
+
+// Move moves the value at the source key to the destination key, creating the missing parts of the destination.
+// The source key must match exactly one key. The destination is set after the source is removed, so when both are
+// indices into the same array the destination index refers to the array without the moved element.
+// The config is left unchanged if the move fails.
+// Example: Move("spec.replicas", "metadata.annotations.replicas", k)
+func Move(srcKey, dstKey string, config interface{}) error {
+	results, err := GetAll(srcKey, config)
+	if err != nil {
+		return err
+	}
+	if len(results) == 0 {
+		return fmt.Errorf("the source key %s does not exist", srcKey)
+	}
+	if len(results) > 1 {
+		return fmt.Errorf("the source key %s matches %d keys. Expected it to match exactly one key", srcKey, len(results))
+	}
+	if dstKey == "" {
+		return fmt.Errorf("the destination key is an empty string")
+	}
+	if getKeyFromSubKeys(results[0].Key) == getKeyFromSubKeys(GetSubKeys(dstKey)) {
+		return nil
+	}
+	// try the move on a copy first since the source can't be restored once it is removed from an array
+	if err := moveSubKeys(results[0].Key, dstKey, results[0].Value, deepcopy.DeepCopy(config)); err != nil {
+		return fmt.Errorf("failed to move the key %s to the key %s . Error: %q", srcKey, dstKey, err)
+	}
+	return moveSubKeys(results[0].Key, dstKey, results[0].Value, config)
+}
+
+// moveSubKeys removes the value at the source sub keys and sets it at the destination key
+func moveSubKeys(srcSubKeys []string, dstKey string, value interface{}, config interface{}) error {
+	if err := deleteSubKeys(srcSubKeys, config); err != nil {
+		return err
+	}
+	if _, ok := get(dstKey, config); ok {
+		return set(dstKey, value, config)
+	}
+	// only create the missing maps, the existing arrays and scalars on the way to the destination should not be replaced
+	dstSubKeys := GetSubKeys(dstKey)
+	for i := len(dstSubKeys) - 1; i > 0; i-- {
+		prefix := getKeyFromSubKeys(dstSubKeys[:i])
+		if existing, ok := get(prefix, config); ok {
+			if _, ok := existing.(map[string]interface{}); !ok {
+				return fmt.Errorf("the key %s cannot be created since the value at %s is not a map", dstKey, prefix)
+			}
+			break
+		}
+	}
+	configMap, ok := config.(map[string]interface{})
+	if !ok {
+		return set(dstKey, value, config)
+	}
+	return setCreatingNew(dstKey, value, configMap)
+}
+
+// deleteSubKeys removes the value at the sub keys from its parent map or array
+func deleteSubKeys(subKeys []string, config interface{}) error {
+	if len(subKeys) == 0 {
+		return fmt.Errorf("cannot delete the root of the config")
+	}
+	value, err := getParent(subKeys, config, false)
+	if err != nil {
+		return err
+	}
+	subKey := subKeys[len(subKeys)-1]
+	if valueMap, ok := value.(map[string]interface{}); ok {
+		if _, ok := valueMap[unescapeBrackets(subKey)]; !ok {
+			return fmt.Errorf("the sub key %s is not present in the map %+v", subKey, valueMap)
+		}
+		delete(valueMap, unescapeBrackets(subKey))
+		return nil
+	}
+	if valueArr, ok := value.([]interface{}); ok {
+		idx, err := getSliceIndex(subKey, len(valueArr))
+		if err != nil {
+			return fmt.Errorf("the sub key %s is not a valid index into the array %+v . Error: %q", subKey, valueArr, err)
+		}
+		if len(subKeys) == 1 {
+			return fmt.Errorf("cannot remove the element %s from the root array", subKey)
+		}
+		newValueArr := append(valueArr[:idx:idx], valueArr[idx+1:]...)
+		return set(getKeyFromSubKeys(subKeys[:len(subKeys)-1]), newValueArr, config)
+	}
+	return fmt.Errorf("expected a map or array type. Actual value is %+v of type %T", value, value)
+}
+
+// isCompositeValue returns true if the value is a list or a map
+func isCompositeValue(value interface{}) bool {
+	switch value.(type) {
+	case []interface{}, map[string]interface{}:
+		return true
+	}
+	return false
+}
+
+// TransformAll updates the values at all the keys that matched using the given function.
+// The function is called with each match and its return value replaces the old value.
+func TransformAll(key string, config interface{}, fn func(RT) (interface{}, error)) error {
+	results, err := GetAll(key, config)
+	if err != nil {
+		return err
+	}
+	for _, result := range results {
+		newValue, err := fn(result)
+		if err != nil {
+			return err
+		}
+		concreteKey := getKeyFromSubKeys(result.Key)
+		if err := set(concreteKey, newValue, config); err != nil {
+			return fmt.Errorf("failed to set the key %s to the value %+v . Error: %q", concreteKey, newValue, err)
+		}
+	}
+	return nil
+}
+
+// getRecurse recurses on the value and calls visit for each match of the key.
+// Errors for branches that don't match are passed to onBranchErr. If it is nil or returns an error the traversal stops.
+func getRecurse(subKeys []string, subKeyIdx int, value interface{}, currentResult RT, visit func(RT) error, onBranchErr func(error) error, opts KeyOptions) error {
 	if subKeyIdx >= len(subKeys) {
 		kc := make([]string, len(currentResult.Key))
 		copy(kc, currentResult.Key)
 		currentResult.Key = kc
 		currentResult.Value = value
-		*results = append(*results, currentResult)
-		return nil
+		return visit(currentResult)
+	}
+	branchErr := func(err error) error {
+		if onBranchErr == nil {
+			return err
+		}
+		return onBranchErr(fmt.Errorf("failed to match at the key %s . Error: %w", getKeyFromSubKeys(currentResult.Key), err))
 	}
 	subKey := subKeys[subKeyIdx]
+	if isRange(subKey) {
+		// subkey like [2:5] or [*]
+		valueArr, ok := value.([]interface{})
+		if !ok {
+			return branchErr(fmt.Errorf("expected a slice for the range %s . Actual value %+v is of type %T", subKey, value, value))
+		}
+		start, end, err := getSliceRange(subKey, len(valueArr))
+		if err != nil {
+			return branchErr(err)
+		}
+		origKey := currentResult.Key
+		for idx := start; idx < end; idx++ {
+			currentResult.Key = append(origKey, "["+cast.ToString(idx)+"]")
+			if err := getRecurse(subKeys, subKeyIdx+1, valueArr[idx], currentResult, visit, onBranchErr, opts); err != nil {
+				return err
+			}
+		}
+		currentResult.Key = origKey
+		return nil
+	}
 	if isNormal(subKey) {
 		valueMap, ok := value.(map[string]interface{})
 		if ok {
-			value, ok = valueMap[subKey]
+			mapKey, ok := findMapKey(getLogger(opts.Logger), valueMap, unescapeBrackets(subKey), opts.CaseInsensitive)
 			if ok {
-				currentResult.Key = append(currentResult.Key, subKey)
-				return getRecurse(subKeys, subKeyIdx+1, value, currentResult, results)
+				value = valueMap[mapKey]
+				// use the actual map key so that the key can be used with set
+				currentResult.Key = append(currentResult.Key, mapKey)
+				return getRecurse(subKeys, subKeyIdx+1, value, currentResult, visit, onBranchErr, opts)
 			}
-			return fmt.Errorf("failed to find the subkey %s in the map %+v", subKey, valueMap)
+			return branchErr(fmt.Errorf("failed to find the subkey %s in the map %+v", subKey, valueMap))
 		}
 		valueArr, ok := value.([]interface{})
 		if ok {
-			idx, ok := getIndex(subKey)
-			if !ok {
-				return fmt.Errorf("failed to interpret the subkey %s as an index to the slice %+v", subKey, valueArr)
-			}
-			if idx >= len(valueArr) {
-				return fmt.Errorf("the index %d is out of range for the slice %+v", idx, valueArr)
+			idx, err := getSliceIndex(subKey, len(valueArr))
+			if err != nil {
+				return branchErr(fmt.Errorf("failed to index into the slice %+v . Error: %w", valueArr, err))
 			}
 			value = valueArr[idx]
-			currentResult.Key = append(currentResult.Key, subKey)
-			return getRecurse(subKeys, subKeyIdx+1, value, currentResult, results)
+			// use the concrete index so that the key can be used with set even if the sub key was [last]
+			currentResult.Key = append(currentResult.Key, "["+cast.ToString(idx)+"]")
+			return getRecurse(subKeys, subKeyIdx+1, value, currentResult, visit, onBranchErr, opts)
 		}
-		return fmt.Errorf("the value is not a map or slice. Actual value %+v is of type %T", value, value)
+		return branchErr(fmt.Errorf("the value is not a map or slice. Actual value %+v is of type %T", value, value))
 	}
 	// subkey like [containerName:name=nginx]
 	if !complexSubKeyRegex.MatchString(subKey) {
-		return fmt.Errorf("the subkey %s is invalid", subKey)
-	}
-	subMatches := complexSubKeyRegex.FindAllStringSubmatch(subKey, -1)
-	if len(subMatches) != 1 {
-		return fmt.Errorf("expected there to be 1 match. Actual no. of matches %d matches: %+v", len(subMatches), subMatches)
+		return branchErr(fmt.Errorf("the subkey %s is invalid", subKey))
 	}
-	if len(subMatches[0]) != 4 {
-		return fmt.Errorf("expected there to be 4 submatches. Actual no. of submatches %d submatches: %+v", len(subMatches[0]), subMatches[0])
-	}
-	matchName, matchKey, matchValue := subMatches[0][1], subMatches[0][2], subMatches[0][3]
-	if matchName == "" {
-		matchName = matchKey
-	} else {
-		matchName = strings.TrimSuffix(matchName, ":")
+	matchName, matchKey, matchValue, err := parseSelector(subKey)
+	if err != nil {
+		return branchErr(err)
 	}
-	if matchValue != "" {
-		matchValue = strings.TrimPrefix(matchValue, "=")
+	if valueMap, ok := value.(map[string]interface{}); ok {
+		return getRecurseMapEntries(subKeys, subKeyIdx, valueMap, currentResult, visit, branchErr, onBranchErr, opts, matchName, matchKey, matchValue)
 	}
 	valueArr, ok := value.([]interface{})
 	if !ok {
-		return fmt.Errorf("expected a slice of objects. actual value is %+v of type %T", value, value)
+		return branchErr(fmt.Errorf("expected a slice of objects. actual value is %+v of type %T", value, value))
 	}
 	if len(valueArr) == 0 {
 		return nil
@@ -121,49 +564,148 @@ func getRecurse(subKeys []string, subKeyIdx int, value interface{}, currentResul
 	for arrIdx, valueMapI := range valueArr {
 		valueMap, ok := valueMapI.(map[string]interface{})
 		if !ok {
-			return fmt.Errorf("expected all the elements of the slice to be object. actual value is %+v of %T", valueMapI, valueMapI)
+			if err := branchErr(fmt.Errorf("expected all the elements of the slice to be object. actual value is %+v of %T", valueMapI, valueMapI)); err != nil {
+				return err
+			}
+			continue
 		}
-		actualMatchValueI, ok := valueMap[matchKey]
+		// the match key can be a path to a nested field. Elements without the field are skipped.
+		actualMatchValueI, ok := get(matchKey, valueMap)
 		if !ok {
 			continue
 		}
 		actualMatchValue, ok := actualMatchValueI.(string)
 		if !ok {
-			return fmt.Errorf("expected the value to be a string. Actual value is %+v of type %T", actualMatchValueI, actualMatchValueI)
+			if err := branchErr(fmt.Errorf("expected the value to be a string. Actual value is %+v of type %T", actualMatchValueI, actualMatchValueI)); err != nil {
+				return err
+			}
+			continue
 		}
 		if matchValue != "" && matchValue != actualMatchValue {
 			continue
 		}
-		if currentResult.Matches == nil {
-			currentResult.Matches = map[string]string{}
-		}
-		orig := currentResult.Matches
+		orig, origIndices := currentResult.Matches, currentResult.Indices
 		copy := map[string]string{}
 		for k, v := range orig {
 			copy[k] = v
 		}
 		copy[matchName] = actualMatchValue
 		currentResult.Matches = copy
+		copyIndices := map[string]int{}
+		for k, v := range origIndices {
+			copyIndices[k] = v
+		}
+		copyIndices[matchName] = arrIdx
+		currentResult.Indices = copyIndices
 		origKey := currentResult.Key
 		currentResult.Key = append(origKey, "["+cast.ToString(arrIdx)+"]")
-		if err := getRecurse(subKeys, subKeyIdx+1, valueArr[arrIdx], currentResult, results); err != nil {
+		if err := getRecurse(subKeys, subKeyIdx+1, valueArr[arrIdx], currentResult, visit, onBranchErr, opts); err != nil {
 			return err
 		}
 		currentResult.Matches = orig
+		currentResult.Indices = origIndices
 		currentResult.Key = origKey
 	}
 	return nil
 }
 
+// getRecurseMapEntries is like the complex sub key handling for slices but it matches the entries of a map.
+// Entries whose values are maps are matched using the match key. Scalar entries are matched by their value using the match key "value".
+// The entries are visited in the sorted order of their keys and the matched map key is recorded in the Matches.
+func getRecurseMapEntries(subKeys []string, subKeyIdx int, valueMap map[string]interface{}, currentResult RT, visit func(RT) error, branchErr func(error) error, onBranchErr func(error) error, opts KeyOptions, matchName, matchKey, matchValue string) error {
+	mapKeys := []string{}
+	for mapKey := range valueMap {
+		mapKeys = append(mapKeys, mapKey)
+	}
+	sort.Strings(mapKeys)
+	for _, mapKey := range mapKeys {
+		entryValue := valueMap[mapKey]
+		var actualMatchValueI interface{}
+		if entryMap, ok := entryValue.(map[string]interface{}); ok {
+			if actualMatchValueI, ok = get(matchKey, entryMap); !ok {
+				continue
+			}
+		} else if matchKey == mapEntryValueMatchKey {
+			actualMatchValueI = entryValue
+		} else {
+			continue
+		}
+		actualMatchValue, ok := actualMatchValueI.(string)
+		if !ok {
+			if err := branchErr(fmt.Errorf("expected the value to be a string. Actual value is %+v of type %T", actualMatchValueI, actualMatchValueI)); err != nil {
+				return err
+			}
+			continue
+		}
+		if matchValue != "" && matchValue != actualMatchValue {
+			continue
+		}
+		entryResult := currentResult
+		entryResult.Matches = map[string]string{}
+		for k, v := range currentResult.Matches {
+			entryResult.Matches[k] = v
+		}
+		entryResult.Matches[matchName] = mapKey
+		entryResult.Key = append(currentResult.Key[:len(currentResult.Key):len(currentResult.Key)], mapKey)
+		if err := getRecurse(subKeys, subKeyIdx+1, entryValue, entryResult, visit, onBranchErr, opts); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parseSelector returns the name, the key and the value of a complex sub key like [containerName:name=nginx].
+// The name defaults to the key and the value is empty if the sub key doesn't have one.
+func parseSelector(subKey string) (matchName, matchKey, matchValue string, err error) {
+	subMatches := complexSubKeyRegex.FindAllStringSubmatch(subKey, -1)
+	if len(subMatches) != 1 {
+		return "", "", "", fmt.Errorf("expected there to be 1 match. Actual no. of matches %d matches: %+v", len(subMatches), subMatches)
+	}
+	if len(subMatches[0]) != 4 {
+		return "", "", "", fmt.Errorf("expected there to be 4 submatches. Actual no. of submatches %d submatches: %+v", len(subMatches[0]), subMatches[0])
+	}
+	matchName, matchKey, matchValue = subMatches[0][1], subMatches[0][2], subMatches[0][3]
+	if matchName == "" {
+		matchName = matchKey
+	} else {
+		matchName = strings.TrimSuffix(matchName, ":")
+	}
+	if matchValue != "" {
+		matchValue = common.StripQuotes(strings.TrimPrefix(matchValue, "="))
+	}
+	return matchName, matchKey, matchValue, nil
+}
+
+// findMapKey returns the key in the map that matches the sub key.
+// If caseInsensitive is true and there is no exact match, the keys are compared ignoring case.
+func findMapKey(logger *logrus.Logger, valueMap map[string]interface{}, subKey string, caseInsensitive bool) (string, bool) {
+	if _, ok := valueMap[subKey]; ok || !caseInsensitive {
+		return subKey, ok
+	}
+	matches := []string{}
+	for k := range valueMap {
+		if strings.EqualFold(k, subKey) {
+			matches = append(matches, k)
+		}
+	}
+	if len(matches) == 0 {
+		return "", false
+	}
+	sort.Strings(matches)
+	if len(matches) > 1 {
+		logger.Warnf("the sub key %s matches multiple keys %+v ignoring case. Using %s", subKey, matches, matches[0])
+	}
+	return matches[0], true
+}
+
 // get returns the value at the key in the config
-/*
 func get(key string, config interface{}) (value interface{}, ok bool) {
 	subKeys := GetSubKeys(key)
 	value = config
 	for _, subKey := range subKeys {
 		valueMap, ok := value.(map[string]interface{})
 		if ok {
-			value, ok = valueMap[subKey]
+			value, ok = valueMap[unescapeBrackets(subKey)]
 			if ok {
 				continue
 			}
@@ -171,8 +713,7 @@ func get(key string, config interface{}) (value interface{}, ok bool) {
 		}
 		valueArr, ok := value.([]interface{})
 		if ok {
-			idx, ok := getIndex(subKey)
-			if ok && idx < len(valueArr) {
+			if idx, err := getSliceIndex(subKey, len(valueArr)); err == nil {
 				value = valueArr[idx]
 				continue
 			}
@@ -180,58 +721,90 @@ func get(key string, config interface{}) (value interface{}, ok bool) {
 		return value, false
 	}
 	return value, true
-}*/
+}
 
-// set updates the value at the key in the config with the new value
-func set(key string, newValue, config interface{}) error {
-	if key == "" {
-		return fmt.Errorf("the key is an empty string")
-	}
+// GetParent returns the map or array containing the key along with the last sub key of the key.
+// The parent can be used to inspect or modify the siblings of the key.
+func GetParent(key string, config interface{}, opts ...KeyOptions) (parent interface{}, lastKey string, ok bool) {
 	subKeys := GetSubKeys(key)
-	if len(subKeys) == 0 {
-		return fmt.Errorf("no sub keys found for the key %s", key)
+	if key == "" || len(subKeys) == 0 {
+		return nil, "", false
+	}
+	parent, err := getParent(subKeys, config, false)
+	if err != nil {
+		getLogger(getKeyOptions(opts).Logger).Debugf("failed to get the parent of the key %s . Error: %q", key, err)
+		return nil, "", false
 	}
+	return parent, subKeys[len(subKeys)-1], true
+}
+
+// getParent returns the value found by following all the sub keys except the last one.
+// If createMissing is true, missing sub keys and sub keys whose values are not maps are replaced with new maps.
+func getParent(subKeys []string, config interface{}, createMissing bool) (interface{}, error) {
 	value := config
 	for _, subKey := range subKeys[:len(subKeys)-1] {
 		valueMap, ok := value.(map[string]interface{})
 		if ok {
-			value, ok = valueMap[subKey]
-			if ok {
-				continue
+			nextValue, ok := valueMap[unescapeBrackets(subKey)]
+			if createMissing {
+				if _, isMap := nextValue.(map[string]interface{}); !ok || !isMap {
+					nextValue = map[string]interface{}{}
+					valueMap[unescapeBrackets(subKey)] = nextValue
+				}
+			} else if !ok {
+				return nil, fmt.Errorf("the sub key %s is not present in the map %+v", subKey, valueMap)
 			}
-			return fmt.Errorf("the sub key %s is not present in the map %+v", subKey, valueMap)
+			value = nextValue
+			continue
 		}
 		valueArr, ok := value.([]interface{})
 		if ok {
-			idx, ok := getIndex(subKey)
-			if ok && idx < len(valueArr) {
-				value = valueArr[idx]
-				continue
+			idx, err := getSliceIndex(subKey, len(valueArr))
+			if err != nil {
+				return nil, fmt.Errorf("the sub key %s is not a valid index into the array %+v . Error: %q", subKey, valueArr, err)
 			}
-			return fmt.Errorf("the sub key %s is not a valid index into the array %+v", subKey, valueArr)
+			value = valueArr[idx]
+			continue
 		}
-		return fmt.Errorf("the sub key %s cannot be matched because we reached a scalar value %+v", subKey, value)
+		return nil, fmt.Errorf("the sub key %s cannot be matched because we reached a scalar value %+v", subKey, value)
 	}
-	subKey := subKeys[len(subKeys)-1]
-	if valueMap, ok := value.(map[string]interface{}); ok {
-		if _, ok := valueMap[subKey]; ok {
-			valueMap[subKey] = newValue
-			return nil
+	return value, nil
+}
+
+// set updates the value at the key in the config with the new value
+func set(key string, newValue, config interface{}) error {
+	if key == "" {
+		return fmt.Errorf("the key is an empty string")
+	}
+	subKeys := GetSubKeys(key)
+	if len(subKeys) == 0 {
+		return fmt.Errorf("no sub keys found for the key %s", key)
+	}
+	value, err := getParent(subKeys, config, false)
+	if err != nil {
+		return err
+	}
+	subKey := subKeys[len(subKeys)-1]
+	if valueMap, ok := value.(map[string]interface{}); ok {
+		if _, ok := valueMap[unescapeBrackets(subKey)]; ok {
+			valueMap[unescapeBrackets(subKey)] = newValue
+			return nil
 		}
 		return fmt.Errorf("the sub key %s is not present in the map %+v", subKey, valueMap)
 	}
 	if valueArr, ok := value.([]interface{}); ok {
-		idx, ok := getIndex(subKey)
-		if ok && idx < len(valueArr) {
-			valueArr[idx] = newValue
-			return nil
+		idx, err := getSliceIndex(subKey, len(valueArr))
+		if err != nil {
+			return fmt.Errorf("the sub key %s is not a valid index into the array %+v . Error: %q", subKey, valueArr, err)
 		}
-		return fmt.Errorf("the sub key %s is not a valid index into the array %+v", subKey, valueArr)
+		valueArr[idx] = newValue
+		return nil
 	}
 	return fmt.Errorf("expected a map or array type. Actual value is %+v of type %T", value, value)
 }
 
-// setCreatingNew updates the value at the key in the config with the new value
+// setCreatingNew updates the value at the key in the config with the new value.
+// Unlike set, it creates the missing sub keys and replaces the values that are not maps along the way.
 func setCreatingNew(key string, newValue interface{}, config map[string]interface{}) error {
 	if key == "" {
 		return fmt.Errorf("the key is an empty string")
@@ -240,101 +813,937 @@ func setCreatingNew(key string, newValue interface{}, config map[string]interfac
 	if len(subKeys) == 0 {
 		return fmt.Errorf("no sub keys found for the key %s", key)
 	}
-	lastIdx := len(subKeys) - 1
-	var value interface{}
-	var ok bool
-	for _, subKey := range subKeys[:lastIdx] {
-		value, ok = config[subKey]
-		if !ok {
-			// sub key doesn't exist
-			newMap := map[string]interface{}{}
-			config[subKey] = newMap
-			config = newMap
-			continue
+	value, err := getParent(subKeys, config, true)
+	if err != nil {
+		return err
+	}
+	valueMap, ok := value.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("expected a map type. Actual value is %+v of type %T", value, value)
+	}
+	valueMap[unescapeBrackets(subKeys[len(subKeys)-1])] = newValue
+	return nil
+}
+
+// Flatten returns all the leaf values in the config keyed by their dotted paths.
+// Array elements use the [n] index notation so the keys can be used with Get and Set.
+// Empty maps and slices are kept as leaf values. Structs should be converted to maps first, for example using common.GetMapInterfaceFromObj
+// Example {"a": {"b.c": [1, 2]}} -> {`a."b.c".[0]`: 1, `a."b.c".[1]`: 2}
+func Flatten(config interface{}) map[string]interface{} {
+	flattened := map[string]interface{}{}
+	Walk(config, func(path []string, value interface{}) {
+		flattened[strings.Join(path, ".")] = value
+	})
+	return flattened
+}
+
+// Walk calls visit for every leaf value in the config along with its path, in the sorted order of the map keys.
+// The path contains the sub keys, quoted when necessary, so strings.Join(path, ".") is a key that can be used with Get and Set.
+// Array elements use the [n] index notation. Empty maps and slices are visited as leaf values.
+// Example {"a": {"b.c": [1]}} -> visit([]string{"a", `"b.c"`, "[0]"}, 1)
+func Walk(config interface{}, visit func(path []string, value interface{})) {
+	walkRecurse(nil, config, visit)
+}
+
+func walkRecurse(subKeys []string, value interface{}, visit func(path []string, value interface{})) {
+	switch actualValue := value.(type) {
+	case map[string]interface{}:
+		if len(actualValue) > 0 {
+			keys := make([]string, 0, len(actualValue))
+			for k := range actualValue {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			for _, k := range keys {
+				walkRecurse(append(subKeys[:len(subKeys):len(subKeys)], quoteSubKey(k)), actualValue[k], visit)
+			}
+			return
 		}
-		valueMap, ok := value.(map[string]interface{})
-		if ok {
-			config = valueMap
+	case []interface{}:
+		if len(actualValue) > 0 {
+			for i, v := range actualValue {
+				walkRecurse(append(subKeys[:len(subKeys):len(subKeys)], "["+cast.ToString(i)+"]"), v, visit)
+			}
+			return
+		}
+	}
+	visit(subKeys, value)
+}
+
+// quoteSubKey quotes the map key if it would otherwise be interpreted as multiple sub keys and escapes its brackets
+func quoteSubKey(subKey string) string {
+	if !arrayIndexRegex.MatchString(subKey) {
+		subKey = escapeBrackets(subKey)
+	}
+	if subKey != "" && !strings.ContainsAny(removeEscapedBrackets(subKey), `.[]"'`) {
+		return subKey
+	}
+	if strings.Contains(subKey, `"`) {
+		return `'` + subKey + `'`
+	}
+	return `"` + subKey + `"`
+}
+
+// Unflatten rebuilds the nested config from the dotted key/value pairs. It is the inverse of Flatten.
+func Unflatten(flattened map[string]interface{}) (interface{}, error) {
+	if value, ok := flattened[""]; ok {
+		if len(flattened) > 1 {
+			return nil, fmt.Errorf("the empty key cannot be combined with other keys")
+		}
+		return value, nil
+	}
+	keys := []string{}
+	for key := range flattened {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var config interface{}
+	for _, key := range keys {
+		if err := ValidateKey(key); err != nil {
+			return nil, err
+		}
+		var err error
+		config, err = unflattenRecurse(GetSubKeys(key), config, flattened[key])
+		if err != nil {
+			return nil, fmt.Errorf("failed to set the key %s . Error: %q", key, err)
+		}
+	}
+	return config, nil
+}
+
+func unflattenRecurse(subKeys []string, node interface{}, value interface{}) (interface{}, error) {
+	if len(subKeys) == 0 {
+		if node != nil {
+			return nil, fmt.Errorf("the value %+v conflicts with the existing value %+v", value, node)
+		}
+		return value, nil
+	}
+	if idx, ok := getIndex(subKeys[0]); ok {
+		valueArr, ok := node.([]interface{})
+		if !ok && node != nil {
+			return nil, fmt.Errorf("expected a slice for the sub key %s . Actual value is %+v of type %T", subKeys[0], node, node)
+		}
+		for len(valueArr) <= idx {
+			valueArr = append(valueArr, nil)
+		}
+		newValue, err := unflattenRecurse(subKeys[1:], valueArr[idx], value)
+		if err != nil {
+			return nil, err
+		}
+		valueArr[idx] = newValue
+		return valueArr, nil
+	}
+	valueMap, ok := node.(map[string]interface{})
+	if !ok {
+		if node != nil {
+			return nil, fmt.Errorf("expected a map for the sub key %s . Actual value is %+v of type %T", subKeys[0], node, node)
+		}
+		valueMap = map[string]interface{}{}
+	}
+	mapKey := unescapeBrackets(subKeys[0])
+	newValue, err := unflattenRecurse(subKeys[1:], valueMap[mapKey], value)
+	if err != nil {
+		return nil, err
+	}
+	valueMap[mapKey] = newValue
+	return valueMap, nil
+}
+
+// GetByPointer returns the value at the RFC 6901 JSON Pointer in the config.
+// Example: /spec/template/spec/containers/0/image is the same as the key spec.template.spec.containers.[0].image
+func GetByPointer(pointer string, config interface{}) (interface{}, error) {
+	key, err := jsonPointerToKey(pointer)
+	if err != nil {
+		return nil, err
+	}
+	result, ok, err := GetFirst(key, config)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, fmt.Errorf("the JSON pointer %s did not match anything", pointer)
+	}
+	return result.Value, nil
+}
+
+// SetByPointer updates the value at the RFC 6901 JSON Pointer in the config with the new value
+func SetByPointer(pointer string, newValue interface{}, config interface{}) error {
+	key, err := jsonPointerToKey(pointer)
+	if err != nil {
+		return err
+	}
+	return set(key, newValue, config)
+}
+
+// jsonPointerToKey converts an RFC 6901 JSON Pointer into a key. Numeric segments become array indices.
+// Example: /metadata/annotations/a.b~1c -> metadata.annotations."a.b/c"
+func jsonPointerToKey(pointer string) (string, error) {
+	if !strings.HasPrefix(pointer, "/") {
+		return "", fmt.Errorf("the JSON pointer %s must start with a /", pointer)
+	}
+	subKeys := []string{}
+	for _, segment := range strings.Split(pointer[1:], "/") {
+		if segment == "-" {
+			return "", fmt.Errorf("the JSON pointer %s refers to the end of an array which is not supported", pointer)
+		}
+		if _, err := strconv.ParseUint(segment, 10, 0); err == nil {
+			subKeys = append(subKeys, "["+segment+"]")
 			continue
 		}
-		// sub key exists but corresponding value is not a map
-		newMap := map[string]interface{}{}
-		config[subKey] = newMap
-		config = newMap
+		segment = strings.ReplaceAll(strings.ReplaceAll(segment, "~1", "/"), "~0", "~")
+		subKeys = append(subKeys, quoteSubKey(segment))
 	}
-	lastSubKey := subKeys[lastIdx]
-	config[lastSubKey] = newValue
-	return nil
+	return strings.Join(subKeys, "."), nil
+}
+
+// KeySegmentType is the type of a segment of a key
+type KeySegmentType string
+
+const (
+	// MapKeySegment selects the value of a key in a map. Example: metadata
+	MapKeySegment KeySegmentType = "MapKey"
+	// ArrayIndexSegment selects an element of a slice. Example: [2] and [last]
+	ArrayIndexSegment KeySegmentType = "ArrayIndex"
+	// ArrayRangeSegment selects a range of elements of a slice. Example: [2:5] and [*]
+	ArrayRangeSegment KeySegmentType = "ArrayRange"
+	// SelectorSegment selects the elements of a slice or the entries of a map that match. Example: [containerName:name=nginx]
+	SelectorSegment KeySegmentType = "Selector"
+)
+
+// KeySegment is a parsed segment of a key. Only the fields for its type are set.
+type KeySegment struct {
+	Type KeySegmentType
+	// Raw is the sub key as returned by GetSubKeys
+	Raw string
+	// MapKey is the key for map key segments
+	MapKey string
+	// Index is the index for array index segments. It is not set if Last is true.
+	Index int
+	// Last is true for the [last] array index segment
+	Last bool
+	// Start and End are the bounds of array range segments. A nil bound is open.
+	Start *int
+	End   *int
+	// MatchName, MatchKey and MatchValue are the parts of selector segments. MatchValue is empty if any value matches.
+	MatchName  string
+	MatchKey   string
+	MatchValue string
+}
+
+// ParseKey validates the key and returns its segments
+func ParseKey(key string) ([]KeySegment, error) {
+	if err := ValidateKey(key); err != nil {
+		return nil, err
+	}
+	segments := []KeySegment{}
+	for _, subKey := range GetSubKeys(key) {
+		segment, err := parseKeySegment(subKey)
+		if err != nil {
+			return segments, fmt.Errorf("failed to parse the sub key %s of the key %s . Error: %q", subKey, key, err)
+		}
+		segments = append(segments, segment)
+	}
+	return segments, nil
+}
+
+// parseKeySegment returns the segment for a sub key returned by GetSubKeys
+func parseKeySegment(subKey string) (KeySegment, error) {
+	segment := KeySegment{Raw: subKey}
+	switch {
+	case subKey == lastIndexSubKey:
+		segment.Type, segment.Last = ArrayIndexSegment, true
+	case arrayIndexRegex.MatchString(subKey):
+		idx, ok := getIndex(subKey)
+		if !ok {
+			return segment, fmt.Errorf("the sub key %s is not a valid index", subKey)
+		}
+		segment.Type, segment.Index = ArrayIndexSegment, idx
+	case subKey == allIndicesSubKey:
+		segment.Type = ArrayRangeSegment
+	case arrayRangeRegex.MatchString(subKey):
+		segment.Type = ArrayRangeSegment
+		matches := arrayRangeRegex.FindStringSubmatch(subKey)
+		for i, bound := range []**int{&segment.Start, &segment.End} {
+			if matches[i+1] == "" {
+				continue
+			}
+			idx, err := strconv.Atoi(matches[i+1])
+			if err != nil {
+				return segment, fmt.Errorf("the bound %s is not a valid integer. Error: %q", matches[i+1], err)
+			}
+			*bound = &idx
+		}
+	case complexSubKeyRegex.MatchString(subKey):
+		matchName, matchKey, matchValue, err := parseSelector(subKey)
+		if err != nil {
+			return segment, err
+		}
+		segment.Type, segment.MatchName, segment.MatchKey, segment.MatchValue = SelectorSegment, matchName, matchKey, matchValue
+	default:
+		segment.Type, segment.MapKey = MapKeySegment, unescapeBrackets(subKey)
+	}
+	return segment, nil
 }
 
 // GetSubKeys returns the parts of a key.
+// Brackets are used for indices and selectors. To match a map key containing brackets, escape them with a backslash.
+// The escapes are kept in the sub keys and removed when the sub key is used as a map key.
 // Example aaa.bbb."ccc ddd".eee.fff -> {"aaa", "bbb", "ccc ddd", "eee", "fff"}
+// Example aaa.[metadata.name=web].bbb -> {"aaa", "[metadata.name=web]", "bbb"}
+// Example aaa.bbb\[ccc\] -> {"aaa", `bbb\[ccc\]`} which matches the map key bbb[ccc]
 func GetSubKeys(key string) []string {
-	unStrippedSubKeys := common.SplitOnDotExpectInsideQuotes(key) // assuming delimiter is dot
+	unStrippedSubKeys, _ := splitKey(key)
 	subKeys := []string{}
 	for _, unStrippedSubKey := range unStrippedSubKeys {
+		if unStrippedSubKey == "" {
+			continue
+		}
 		subKeys = append(subKeys, common.StripQuotes(unStrippedSubKey))
 	}
 	return subKeys
 }
 
+// splitKey splits the key on dots that are not inside quotes or brackets.
+// It also returns the quote character if the last quote is unterminated.
+func splitKey(key string) ([]string, rune) {
+	parts := []string{}
+	part := ""
+	var quote rune
+	bracketDepth := 0
+	escaped := false
+	for _, c := range key {
+		wasEscaped := escaped
+		escaped = c == '\\' && !wasEscaped && quote == 0
+		switch {
+		case wasEscaped && (c == '[' || c == ']'):
+			// an escaped bracket is part of the map key
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '[':
+			bracketDepth++
+		case c == ']':
+			if bracketDepth > 0 {
+				bracketDepth--
+			}
+		case c == '.' && bracketDepth == 0:
+			parts = append(parts, part)
+			part = ""
+			continue
+		}
+		part += string(c)
+	}
+	return append(parts, part), quote
+}
+
+// getKeyFromSubKeys joins the matched map keys and indices into a key.
+// The brackets in the map keys are escaped so that they are not treated as indices or selectors.
+// Example {"aaa", "[0]", "ccc ddd", "e[f]"} -> "aaa"."[0]"."ccc ddd"."e\[f\]"
+func getKeyFromSubKeys(subKeys []string) string {
+	quotedSubKeys := []string{}
+	for _, subKey := range subKeys {
+		if !arrayIndexRegex.MatchString(subKey) {
+			subKey = escapeBrackets(subKey)
+		}
+		quotedSubKeys = append(quotedSubKeys, `"`+subKey+`"`)
+	}
+	return strings.Join(quotedSubKeys, ".")
+}
+
+// getSliceIndex returns the index into a slice of the given length for sub keys like [2] and [last]
+func getSliceIndex(subKey string, length int) (int, error) {
+	if subKey == lastIndexSubKey {
+		if length == 0 {
+			return 0, fmt.Errorf("the sub key %s cannot be used with an empty slice", subKey)
+		}
+		return length - 1, nil
+	}
+	idx, ok := getIndex(subKey)
+	if !ok {
+		return 0, fmt.Errorf("failed to interpret the sub key %s as an index", subKey)
+	}
+	if idx >= length {
+		return 0, fmt.Errorf("the index %d is out of range for a slice of length %d", idx, length)
+	}
+	return idx, nil
+}
+
+// isRange returns true for sub keys like [2:5], [:3], [-2:] and [*]
+func isRange(subKey string) bool {
+	return subKey == allIndicesSubKey || arrayRangeRegex.MatchString(subKey)
+}
+
+// getSliceRange returns the start (inclusive) and end (exclusive) indices for sub keys like [2:5] and [*].
+// Like Python, the bounds are optional, negative bounds count from the end and the bounds are clamped to the slice.
+func getSliceRange(subKey string, length int) (int, int, error) {
+	if subKey == allIndicesSubKey {
+		return 0, length, nil
+	}
+	matches := arrayRangeRegex.FindStringSubmatch(subKey)
+	if matches == nil {
+		return 0, 0, fmt.Errorf("failed to interpret the sub key %s as a range", subKey)
+	}
+	start, err := getRangeBound(matches[1], 0, length)
+	if err != nil {
+		return 0, 0, err
+	}
+	end, err := getRangeBound(matches[2], length, length)
+	if err != nil {
+		return 0, 0, err
+	}
+	if end < start {
+		end = start
+	}
+	return start, end, nil
+}
+
+// getRangeBound returns the bound clamped to [0, length]. An empty bound returns the default.
+func getRangeBound(bound string, defaultBound, length int) (int, error) {
+	if bound == "" {
+		return defaultBound, nil
+	}
+	digits := strings.TrimPrefix(bound, "-")
+	if len(digits) > 1 && digits[0] == '0' {
+		return 0, fmt.Errorf("the bound %s has leading zeros", bound)
+	}
+	idx, err := strconv.Atoi(bound)
+	if err != nil {
+		return 0, fmt.Errorf("the bound %s is not a valid integer. Error: %q", bound, err)
+	}
+	if idx < 0 {
+		idx += length
+	}
+	if idx < 0 {
+		return 0, nil
+	}
+	if idx > length {
+		return length, nil
+	}
+	return idx, nil
+}
+
+// getIndex returns the index for sub keys like [2].
+// Indices with leading zeros like [00] and indices that overflow int are rejected.
 func getIndex(key string) (int, bool) {
-	matches := arrayIndexRegex.FindSubmatch([]byte(key))
+	matches := arrayIndexRegex.FindStringSubmatch(key)
 	if matches == nil {
 		return 0, false
 	}
-	idx, err := cast.ToIntE(string(matches[1]))
+	digits := matches[1]
+	if len(digits) > 1 && digits[0] == '0' {
+		return 0, false
+	}
+	idx, err := strconv.Atoi(digits)
 	if err != nil || idx < 0 {
 		return 0, false
 	}
 	return idx, true
 }
 
-// writeResourceAppendToFile is like WriteResource but appends to the file
-func writeResourceAppendToFile(k8sResource parameterizertypes.K8sResourceT, outputPath string) error {
-	logrus.Trace("start WriteResourceAppendToFile")
-	defer logrus.Trace("end WriteResourceAppendToFile")
+// ExistingFilePolicyT decides what happens when writing to a file that already exists
+type ExistingFilePolicyT string
+
+const (
+	// AppendToExistingFile appends to the existing file
+	AppendToExistingFile ExistingFilePolicyT = ""
+	// ErrorOnExistingFile returns an error instead of writing to the existing file
+	ErrorOnExistingFile ExistingFilePolicyT = "error"
+	// GeneratedFileOnExistingFile leaves the existing file untouched and writes to <name>.generated.yaml instead
+	GeneratedFileOnExistingFile ExistingFilePolicyT = "generated"
+	// OverwriteExistingFile replaces the contents of the existing file
+	OverwriteExistingFile ExistingFilePolicyT = "overwrite"
+)
+
+// WriteOptions are the options used while writing k8s resources to a file
+type WriteOptions struct {
+	// StripHelmQuotes strips the quotes around anything that looks like a Helm template
+	StripHelmQuotes bool
+	// HelmTemplateKeys are the keys whose values are Helm templates. Only these values are written without quotes.
+	HelmTemplateKeys []string
+	// Header is written as a comment block at the start of the file
+	Header string
+	// IfExists decides what happens when the file already exists. By default the resources are appended to it.
+	IfExists ExistingFilePolicyT
+	// IndexPath is the path of an index file that maps each resource to the file it was written to.
+	// If it is empty, no index is written.
+	IndexPath string
+	// OmitDocumentStart omits the --- marker before the first document of a new file.
+	// The marker is always written between documents so that the file remains a valid multi document stream.
+	OmitDocumentStart bool
+	// OmitDocumentEnd omits the ... marker after each document
+	OmitDocumentEnd bool
+	// Verify re-reads the file after writing and checks that every document is a k8s resource with an apiVersion and kind
+	Verify bool
+	// Logger is used for the logs. If nil, the standard logger of logrus is used.
+	Logger *logrus.Logger
+}
+
+// IndexEntry maps a k8s resource to the file it was written to
+type IndexEntry struct {
+	Kind      string `yaml:"kind"`
+	Namespace string `yaml:"namespace,omitempty"`
+	Name      string `yaml:"name"`
+	// Path is relative to the directory containing the index file
+	Path string `yaml:"path"`
+}
+
+// GetHeader returns a header noting that the file was generated by move2kube from the source path.
+// The timestamp is omitted if it is the zero time so that the output can be reproducible.
+func GetHeader(srcPath string, timestamp time.Time) string {
+	header := fmt.Sprintf("Generated by %s\nSource: %s", types.AppName, srcPath)
+	if timestamp.IsZero() {
+		return header
+	}
+	return header + "\nTimestamp: " + timestamp.UTC().Format(time.RFC3339)
+}
+
+// GetTimestamp returns the timestamp to use in the generated output.
+// If the SOURCE_DATE_EPOCH environment variable is set to a unix timestamp, that time is used so that the output is reproducible.
+// If reproducible is true and SOURCE_DATE_EPOCH is not set, the zero time is returned which omits the timestamp from the header.
+func GetTimestamp(reproducible bool) (time.Time, error) {
+	if sourceDateEpoch, ok := os.LookupEnv(sourceDateEpochEnvKey); ok && sourceDateEpoch != "" {
+		seconds, err := strconv.ParseInt(sourceDateEpoch, 10, 64)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("the environment variable %s has an invalid unix timestamp %s . Error: %q", sourceDateEpochEnvKey, sourceDateEpoch, err)
+		}
+		return time.Unix(seconds, 0).UTC(), nil
+	}
+	if reproducible {
+		return time.Time{}, nil
+	}
+	return time.Now(), nil
+}
+
+// getHeaderComment converts the header into YAML comment lines
+func getHeaderComment(header string) string {
+	if header == "" {
+		return ""
+	}
+	lines := strings.Split(strings.TrimRight(header, "\n"), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight("# "+line, " ")
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// StripHelmTemplateQuotes strips the single quotes that the yaml encoder adds around Helm templates
+// Example: image: '{{ .Values.image }}' -> image: {{ .Values.image }}
+// The contents of block scalars (| and >) are left untouched since the yaml encoder never quotes templates
+// inside them and any quotes found there are part of the value. For example in a ConfigMap like
+// data: {config.sh: "echo '{{ .Values.greeting }}'\n..."} the quotes belong to the shell script.
+func StripHelmTemplateQuotes(yamlBytes []byte) []byte {
+	lines := strings.Split(string(yamlBytes), "\n")
+	blockIndent := -1
+	for i, line := range lines {
+		trimmedLine := strings.TrimLeft(line, " ")
+		indent := len(line) - len(trimmedLine)
+		if blockIndent >= 0 {
+			if trimmedLine == "" || indent > blockIndent {
+				continue
+			}
+			blockIndent = -1
+		}
+		lines[i] = stripHelmQuotesRegex.ReplaceAllString(line, "$1")
+		if blockScalarHeaderRegex.MatchString(line) {
+			blockIndent = indent
+		}
+	}
+	return []byte(strings.Join(lines, "\n"))
+}
+
+// MarshalResources returns the YAML for the k8s resources keyed by the name of the file each resource would be written to.
+// The file name is <name>-<kind>.yaml and resources with the same file name are combined as separate YAML documents.
+// Nothing is written to disk.
+func MarshalResources(k8sResources []parameterizertypes.K8sResourceT, stripQuotes bool) (map[string][]byte, error) {
+	marshalled := map[string][]byte{}
+	for _, k8sResource := range k8sResources {
+		yamlBytes, err := marshalResource(k8sResource, stripQuotes, nil)
+		if err != nil {
+			return marshalled, err
+		}
+		filename := getResourceFilename(k8sResource)
+		if existing, ok := marshalled[filename]; ok {
+			yamlBytes = append(append(existing, []byte("---\n")...), yamlBytes...)
+		}
+		marshalled[filename] = yamlBytes
+	}
+	return marshalled, nil
+}
+
+// marshalResource returns the YAML for the k8s resource.
+// The values at the Helm template keys are written as is, without the quotes the yaml encoder would add.
+func marshalResource(k8sResource parameterizertypes.K8sResourceT, stripQuotes bool, helmTemplateKeys []string) ([]byte, error) {
+	helmTemplates := map[string]string{}
+	if len(helmTemplateKeys) > 0 {
+		k8sResource = deepcopy.DeepCopy(k8sResource).(parameterizertypes.K8sResourceT)
+		for i, key := range helmTemplateKeys {
+			value, ok := get(key, k8sResource)
+			if !ok {
+				return nil, fmt.Errorf("the Helm template key %s does not exist on the k8s resource: %+v", key, k8sResource)
+			}
+			helmTemplate, ok := value.(string)
+			if !ok {
+				return nil, fmt.Errorf("the value at the Helm template key %s is not a string. Actual value is %+v of type %T", key, value, value)
+			}
+			placeholder := fmt.Sprintf(helmTemplatePlaceholderFormat, i)
+			if err := set(key, placeholder, k8sResource); err != nil {
+				return nil, fmt.Errorf("failed to set the key %s to the value %s in the k8s resource: %+v\nError: %q", key, placeholder, k8sResource, err)
+			}
+			helmTemplates[placeholder] = helmTemplate
+		}
+	}
+	// the yaml encoder sorts the map keys so the output is stable across runs
 	yamlBytes, err := yaml.Marshal(k8sResource)
 	if err != nil {
-		logrus.Error("Error while Encoding object")
-		return err
+		return nil, fmt.Errorf("failed to marshal the k8s resource to yaml. Error: %q", err)
+	}
+	if stripQuotes {
+		yamlBytes = StripHelmTemplateQuotes(yamlBytes)
+	}
+	for placeholder, helmTemplate := range helmTemplates {
+		yamlBytes = bytes.Replace(yamlBytes, []byte(placeholder), []byte(helmTemplate), 1)
+	}
+	return yamlBytes, nil
+}
+
+// getResourceFilename returns a file name like <name>-<kind>.yaml for the k8s resource.
+// The name and kind are sanitized so that the file name can't contain path separators.
+func getResourceFilename(k8sResource parameterizertypes.K8sResourceT) string {
+	name, kind := "unnamed", "unknown"
+	if value, ok := get("metadata.name", k8sResource); ok && cast.ToString(value) != "" {
+		name = cast.ToString(value)
+	}
+	if value, ok := get("kind", k8sResource); ok && cast.ToString(value) != "" {
+		kind = cast.ToString(value)
+	}
+	return strings.ToLower(common.SanitizeFilenamePart(name) + "-" + common.SanitizeFilenamePart(kind) + ".yaml")
+}
+
+// WriteFilesToStream writes the files as a multi document YAML stream.
+// Each file is preceded by a comment with its path relative to the base directory.
+// Files that appear more than once are only written the first time.
+func WriteFilesToStream(w io.Writer, baseDir string, paths []string) error {
+	seen := map[string]bool{}
+	for _, path := range paths {
+		if seen[path] {
+			continue
+		}
+		seen[path] = true
+		relPath, err := filepath.Rel(baseDir, path)
+		if err != nil {
+			return fmt.Errorf("failed to make the path %s relative to the directory %s . Error: %q", path, baseDir, err)
+		}
+		contents, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read the file at path %s . Error: %q", path, err)
+		}
+		if len(contents) > 0 && contents[len(contents)-1] != '\n' {
+			contents = append(contents, '\n')
+		}
+		if _, err := fmt.Fprintf(w, "---\n# Source: %s\n", filepath.ToSlash(relPath)); err != nil {
+			return err
+		}
+		if _, err := w.Write(contents); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteResource writes the k8s resource to a file and returns the path of the file that was written.
+// The parent directories of the file are created if they don't exist.
+// If the file already exists the resource is appended to it, unless the options specify otherwise.
+// New files start with the header.
+func WriteResource(k8sResource parameterizertypes.K8sResourceT, outputPath string, opts WriteOptions) (string, error) {
+	return WriteResources([]parameterizertypes.K8sResourceT{k8sResource}, outputPath, opts)
+}
+
+// WriteResources writes the k8s resources to a file as separate YAML documents and returns the path of the file that was written.
+// If the file already exists the resources are appended to it, unless the options specify otherwise.
+// New files start with the header.
+func WriteResources(k8sResources []parameterizertypes.K8sResourceT, outputPath string, opts WriteOptions) (string, error) {
+	logger := getLogger(opts.Logger)
+	logger.Trace("start WriteResources")
+	defer logger.Trace("end WriteResources")
+	flags := os.O_CREATE | os.O_APPEND | os.O_WRONLY
+	if _, err := os.Stat(outputPath); err == nil {
+		switch opts.IfExists {
+		case ErrorOnExistingFile:
+			return outputPath, fmt.Errorf("the file at path %s already exists", outputPath)
+		case GeneratedFileOnExistingFile:
+			ext := filepath.Ext(outputPath)
+			generatedPath := strings.TrimSuffix(outputPath, ext) + ".generated" + ext
+			logger.Warnf("The file at path %s already exists. Writing to the file at path %s instead.", outputPath, generatedPath)
+			outputPath = generatedPath
+			flags = os.O_CREATE | os.O_TRUNC | os.O_WRONLY
+		case OverwriteExistingFile:
+			flags = os.O_CREATE | os.O_TRUNC | os.O_WRONLY
+		}
+	}
+	contents := ""
+	isNewFile := false
+	if fi, err := os.Stat(outputPath); os.IsNotExist(err) || flags&os.O_TRUNC != 0 || (err == nil && fi.Size() == 0) {
+		contents = getHeaderComment(opts.Header)
+		isNewFile = true
+	}
+	for i, k8sResource := range k8sResources {
+		yamlBytes, err := marshalResource(k8sResource, opts.StripHelmQuotes, opts.HelmTemplateKeys)
+		if err != nil {
+			return outputPath, err
+		}
+		contents += getDocumentStart(opts, isNewFile && i == 0, contents == "") + string(yamlBytes) + getDocumentEnd(opts)
+	}
+	if err := os.MkdirAll(filepath.Dir(outputPath), common.DefaultDirectoryPermission); err != nil {
+		return outputPath, fmt.Errorf("failed to create the parent directory of the file at path %s . Error: %q", outputPath, err)
 	}
 	// If the file doesn't exist, create it, or append to the file
-	f, err := os.OpenFile(outputPath, os.O_CREATE|os.O_APPEND|os.O_WRONLY, common.DefaultFilePermission)
+	f, err := os.OpenFile(outputPath, flags, common.DefaultFilePermission)
 	if err != nil {
-		return fmt.Errorf("failed to open the file at path %s for creating/appending. Error: %q", outputPath, err)
+		return outputPath, fmt.Errorf("failed to open the file at path %s for creating/appending. Error: %q", outputPath, err)
 	}
 	defer f.Close()
-	if _, err := f.Write([]byte("\n---\n" + string(yamlBytes) + "\n...\n")); err != nil {
-		return fmt.Errorf("failed to write to the file at path %s . Error: %q", outputPath, err)
+	if _, err := f.Write([]byte(contents)); err != nil {
+		return outputPath, fmt.Errorf("failed to write to the file at path %s . Error: %q", outputPath, err)
+	}
+	if err := f.Close(); err != nil {
+		return outputPath, err
 	}
-	return f.Close()
+	if opts.Verify {
+		if err := verifyResourceFile(outputPath, opts.StripHelmQuotes || len(opts.HelmTemplateKeys) > 0); err != nil {
+			return outputPath, fmt.Errorf("the file at path %s is not valid. Error: %q", outputPath, err)
+		}
+	}
+	if opts.IndexPath != "" {
+		if err := updateIndex(opts.IndexPath, k8sResources, outputPath); err != nil {
+			return outputPath, fmt.Errorf("failed to update the index file at path %s . Error: %q", opts.IndexPath, err)
+		}
+	}
+	return outputPath, nil
 }
 
-// writeResourceStripQuotesAndAppendToFile is like WriteResource but strips quotes around Helm templates and appends to file
-func writeResourceStripQuotesAndAppendToFile(k8sResource parameterizertypes.K8sResourceT, outputPath string) error {
-	logrus.Trace("start WriteResourceStripQuotesAndAppendToFile")
-	defer logrus.Trace("end WriteResourceStripQuotesAndAppendToFile")
-	yamlBytes, err := yaml.Marshal(k8sResource)
+// WriteResourcesByNamespace writes each k8s resource to a file named <name>-<kind>.yaml in a sub directory named after its namespace.
+// Cluster scoped resources are written to the _cluster sub directory and resources without a namespace to the _default sub directory.
+// These names are not valid namespace names, so they can't clash with the sub directories of the namespaces.
+// It returns the paths of the files written relative to the output directory.
+func WriteResourcesByNamespace(k8sResources []parameterizertypes.K8sResourceT, outputPath string) ([]string, error) {
+	filesWritten := []string{}
+	for _, k8sResource := range k8sResources {
+		dir, err := getNamespaceDir(k8sResource)
+		if err != nil {
+			return filesWritten, err
+		}
+		if err := os.MkdirAll(filepath.Join(outputPath, dir), common.DefaultDirectoryPermission); err != nil {
+			return filesWritten, fmt.Errorf("failed to create the directory at path %s . Error: %q", filepath.Join(outputPath, dir), err)
+		}
+		relPath := filepath.Join(dir, getResourceFilename(k8sResource))
+		if _, err := WriteResource(k8sResource, filepath.Join(outputPath, relPath), WriteOptions{}); err != nil {
+			return filesWritten, err
+		}
+		if !common.IsStringPresent(filesWritten, relPath) {
+			filesWritten = append(filesWritten, relPath)
+		}
+	}
+	return filesWritten, nil
+}
+
+// WriteResourcesByKind writes each k8s resource to a file named <name>-<kind>.yaml in the sub directory mapped to its kind.
+// The kinds are matched ignoring case. Resources of unmapped kinds are written to defaultDir, which can be empty to use the output directory.
+// The directories must be relative paths inside the output directory. The names and kinds in the file names are sanitized.
+// It returns the paths of the files written relative to the output directory.
+func WriteResourcesByKind(k8sResources []parameterizertypes.K8sResourceT, outputPath string, kindDirs map[string]string, defaultDir string) ([]string, error) {
+	filesWritten := []string{}
+	if err := validateKindDir(defaultDir); err != nil {
+		return filesWritten, err
+	}
+	for kind, dir := range kindDirs {
+		if err := validateKindDir(dir); err != nil {
+			return filesWritten, fmt.Errorf("the directory for the kind %s is invalid. Error: %q", kind, err)
+		}
+	}
+	for _, k8sResource := range k8sResources {
+		dir := getKindDir(k8sResource, kindDirs, defaultDir)
+		relPath := filepath.Join(dir, getResourceFilename(k8sResource))
+		if _, err := WriteResource(k8sResource, filepath.Join(outputPath, relPath), WriteOptions{}); err != nil {
+			return filesWritten, err
+		}
+		if !common.IsStringPresent(filesWritten, relPath) {
+			filesWritten = append(filesWritten, relPath)
+		}
+	}
+	return filesWritten, nil
+}
+
+// validateKindDir checks that the directory is a relative path that stays inside the output directory
+func validateKindDir(dir string) error {
+	if filepath.IsAbs(dir) {
+		return fmt.Errorf("the directory %s is an absolute path", dir)
+	}
+	for _, part := range strings.Split(filepath.ToSlash(dir), "/") {
+		if part == ".." {
+			return fmt.Errorf("the directory %s refers to a parent directory", dir)
+		}
+	}
+	return nil
+}
+
+// getKindDir returns the sub directory mapped to the kind of the k8s resource
+func getKindDir(k8sResource parameterizertypes.K8sResourceT, kindDirs map[string]string, defaultDir string) string {
+	value, ok := get("kind", k8sResource)
+	if !ok {
+		return defaultDir
+	}
+	kind := cast.ToString(value)
+	if dir, ok := kindDirs[kind]; ok {
+		return dir
+	}
+	for mappedKind, dir := range kindDirs {
+		if strings.EqualFold(mappedKind, kind) {
+			return dir
+		}
+	}
+	return defaultDir
+}
+
+const (
+	// clusterScopedDir is the sub directory for the cluster scoped k8s resources
+	clusterScopedDir = "_cluster"
+	// noNamespaceDir is the sub directory for the k8s resources without a namespace
+	noNamespaceDir = "_default"
+)
+
+// clusterScopedKinds are the kinds of the common k8s resources that don't belong to a namespace
+var clusterScopedKinds = []string{
+	"APIService", "CertificateSigningRequest", "ClusterRole", "ClusterRoleBinding", "CustomResourceDefinition",
+	"IngressClass", "MutatingWebhookConfiguration", "Namespace", "Node", "PersistentVolume", "PodSecurityPolicy",
+	"PriorityClass", "RuntimeClass", "StorageClass", "ValidatingWebhookConfiguration", "VolumeAttachment",
+}
+
+// getNamespaceDir returns the name of the sub directory for the namespace of the k8s resource.
+// It returns an error if the namespace is not a valid namespace name, since it could escape the output directory.
+func getNamespaceDir(k8sResource parameterizertypes.K8sResourceT) (string, error) {
+	if value, ok := get("kind", k8sResource); ok && common.IsStringPresent(clusterScopedKinds, cast.ToString(value)) {
+		return clusterScopedDir, nil
+	}
+	value, ok := get("metadata.namespace", k8sResource)
+	if !ok || cast.ToString(value) == "" {
+		return noNamespaceDir, nil
+	}
+	namespace := cast.ToString(value)
+	if errs := validation.IsDNS1123Label(namespace); len(errs) > 0 {
+		return "", fmt.Errorf("the namespace %s of the k8s resource %s is not a valid namespace name. Errors: %+v", namespace, getResourceFilename(k8sResource), errs)
+	}
+	return namespace, nil
+}
+
+// verifyResourceFile checks that every document in the file is a k8s resource with an apiVersion and kind.
+// If the Helm template quotes were stripped, the templates are replaced with plain values before parsing.
+func verifyResourceFile(path string, strippedHelmQuotes bool) error {
+	contents, err := os.ReadFile(path)
 	if err != nil {
-		logrus.Error("Error while Encoding object")
 		return err
 	}
-	strippedYamlBytes := stripHelmQuotesRegex.ReplaceAll(yamlBytes, []byte("$1"))
-	// If the file doesn't exist, create it, or append to the file
-	f, err := os.OpenFile(outputPath, os.O_CREATE|os.O_APPEND|os.O_WRONLY, common.DefaultFilePermission)
+	if strippedHelmQuotes {
+		contents = helmTemplateRegex.ReplaceAll(contents, []byte("helm-template"))
+	}
+	dec := yaml.NewDecoder(bytes.NewReader(contents))
+	for i := 0; ; i++ {
+		var doc interface{}
+		if err := dec.Decode(&doc); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return fmt.Errorf("failed to parse the document %d as yaml. Error: %q", i, err)
+		}
+		if doc == nil {
+			continue
+		}
+		for _, key := range []string{"apiVersion", "kind"} {
+			if value, ok := get(key, doc); !ok || cast.ToString(value) == "" {
+				return fmt.Errorf("the document %d is missing the %s", i, key)
+			}
+		}
+	}
+}
+
+// getDocumentStart returns the separator written before a document
+func getDocumentStart(opts WriteOptions, isFirstDocument, isStartOfFile bool) string {
+	if opts.OmitDocumentStart && isFirstDocument {
+		if isStartOfFile {
+			return ""
+		}
+		return "\n"
+	}
+	return "\n---\n"
+}
+
+// getDocumentEnd returns the separator written after a document
+func getDocumentEnd(opts WriteOptions) string {
+	if opts.OmitDocumentEnd {
+		return "\n"
+	}
+	return "\n...\n"
+}
+
+// updateIndex adds the k8s resources written to the output path to the index file.
+// The entries are sorted so that the index is the same on every run.
+func updateIndex(indexPath string, k8sResources []parameterizertypes.K8sResourceT, outputPath string) error {
+	entries := []IndexEntry{}
+	if _, err := os.Stat(indexPath); err == nil {
+		if err := common.ReadYaml(indexPath, &entries); err != nil {
+			return err
+		}
+	}
+	relPath, err := filepath.Rel(filepath.Dir(indexPath), outputPath)
 	if err != nil {
-		return fmt.Errorf("failed to open the file at path %s for creating/appending. Error: %q", outputPath, err)
+		return err
 	}
-	defer f.Close()
-	if _, err := f.Write([]byte("\n---\n" + string(strippedYamlBytes) + "\n...\n")); err != nil {
-		return fmt.Errorf("failed to write to the file at path %s . Error: %q", outputPath, err)
+	relPath = filepath.ToSlash(relPath)
+	for _, k := range k8sResources {
+		entry := IndexEntry{Path: relPath}
+		if value, ok := get("kind", k); ok {
+			entry.Kind = cast.ToString(value)
+		}
+		if value, ok := get("metadata.namespace", k); ok {
+			entry.Namespace = cast.ToString(value)
+		}
+		if value, ok := get("metadata.name", k); ok {
+			entry.Name = cast.ToString(value)
+		}
+		if !isIndexEntryPresent(entries, entry) {
+			entries = append(entries, entry)
+		}
 	}
-	return f.Close()
+	sort.SliceStable(entries, func(i, j int) bool {
+		a, b := entries[i], entries[j]
+		if a.Kind != b.Kind {
+			return a.Kind < b.Kind
+		}
+		if a.Namespace != b.Namespace {
+			return a.Namespace < b.Namespace
+		}
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+		return a.Path < b.Path
+	})
+	return common.WriteYaml(indexPath, entries)
 }
 
-// CollectParamsFromPath returns parameterizers found in a directory
-func CollectParamsFromPath(parameterizersDir string) (map[string][]parameterizertypes.ParameterizerT, error) {
+func isIndexEntryPresent(entries []IndexEntry, entry IndexEntry) bool {
+	for _, e := range entries {
+		if e == entry {
+			return true
+		}
+	}
+	return false
+}
+
+// CollectParamsFromPath returns parameterizers found in a directory.
+// If the logger is nil, the standard logger of logrus is used.
+func CollectParamsFromPath(logger *logrus.Logger, parameterizersDir string) (map[string][]parameterizertypes.ParameterizerT, error) {
 	yamlPaths, err := common.GetFilesByExt(parameterizersDir, []string{".yaml", ".yml"})
 	if err != nil {
 		return nil, err
@@ -343,9 +1752,99 @@ func CollectParamsFromPath(parameterizersDir string) (map[string][]parameterizer
 	for _, yamlPath := range yamlPaths {
 		var paramFile parameterizertypes.ParameterizerFileT
 		if err := common.ReadMove2KubeYamlStrict(yamlPath, &paramFile, parameterizertypes.ParameterizerKind); err == nil {
-			logrus.Debugf("found paramterizer yaml at path %s", yamlPath)
+			getLogger(logger).Debugf("found paramterizer yaml at path %s", yamlPath)
+			// the kind and apiVersion of the file apply to the parameterizers that don't specify their own
+			for i, p := range paramFile.Spec.Parameterizers {
+				if p.Kind == "" {
+					paramFile.Spec.Parameterizers[i].Kind = paramFile.Spec.Kind
+				}
+				if p.APIVersion == "" {
+					paramFile.Spec.Parameterizers[i].APIVersion = paramFile.Spec.APIVersion
+				}
+			}
 			params[paramFile.ObjectMeta.Name] = paramFile.Spec.Parameterizers
 		}
 	}
 	return params, nil
 }
+
+// PackKeyError is a syntax error in a key expression found in a pack file
+type PackKeyError struct {
+	Path string
+	Line int
+	Key  string
+	Err  error
+}
+
+func (e PackKeyError) Error() string {
+	return fmt.Sprintf("%s:%d: invalid key %q . Error: %q", e.Path, e.Line, e.Key, e.Err)
+}
+
+// ValidatePack checks the syntax of the target key expressions in all the Packaging and Parameterizer yamls in the pack directory.
+// It does not read or modify any source files. If the logger is nil, the standard logger of logrus is used.
+func ValidatePack(logger *logrus.Logger, packDir string) ([]PackKeyError, error) {
+	yamlPaths, err := common.GetFilesByExt(packDir, []string{".yaml", ".yml"})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the yaml files in the directory %s . Error: %q", packDir, err)
+	}
+	keyErrs := []PackKeyError{}
+	for _, yamlPath := range yamlPaths {
+		f, err := os.Open(yamlPath)
+		if err != nil {
+			return keyErrs, fmt.Errorf("failed to open the file at path %s . Error: %q", yamlPath, err)
+		}
+		dec := yaml.NewDecoder(f)
+		for {
+			doc := yaml.Node{}
+			if err := dec.Decode(&doc); err != nil {
+				if err != io.EOF {
+					getLogger(logger).Debugf("skipping the rest of the file at path %s since it is not valid yaml. Error: %q", yamlPath, err)
+				}
+				break
+			}
+			keyErrs = append(keyErrs, validatePackDocument(yamlPath, &doc)...)
+		}
+		f.Close()
+	}
+	return keyErrs, nil
+}
+
+func validatePackDocument(yamlPath string, doc *yaml.Node) []PackKeyError {
+	if len(doc.Content) == 0 {
+		return nil
+	}
+	root := doc.Content[0]
+	kindNode := getMappingValue(root, "kind")
+	if kindNode == nil || (kindNode.Value != parameterizertypes.PackagingKind && kindNode.Value != parameterizertypes.ParameterizerKind) {
+		return nil
+	}
+	paramsNode := getMappingValue(getMappingValue(root, "spec"), "parameterizers")
+	if paramsNode == nil || paramsNode.Kind != yaml.SequenceNode {
+		return nil
+	}
+	keyErrs := []PackKeyError{}
+	for _, paramNode := range paramsNode.Content {
+		targetNode := getMappingValue(paramNode, "target")
+		if targetNode == nil {
+			keyErrs = append(keyErrs, PackKeyError{Path: yamlPath, Line: paramNode.Line, Err: fmt.Errorf("the parameterizer is missing the target key")})
+			continue
+		}
+		if err := ValidateKey(targetNode.Value); err != nil {
+			keyErrs = append(keyErrs, PackKeyError{Path: yamlPath, Line: targetNode.Line, Key: targetNode.Value, Err: err})
+		}
+	}
+	return keyErrs
+}
+
+// getMappingValue returns the value node for the key in the yaml mapping node or nil if not found
+func getMappingValue(node *yaml.Node, key string) *yaml.Node {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
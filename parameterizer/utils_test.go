@@ -18,10 +18,20 @@ package parameterizer_test
 
 import (
 	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
+	"github.com/konveyor/move2kube/internal/common"
 	"github.com/konveyor/move2kube/parameterizer"
+	parameterizertypes "github.com/konveyor/move2kube/types/parameterizer"
+	"gopkg.in/yaml.v3"
+	k8syaml "k8s.io/apimachinery/pkg/util/yaml"
 )
 
 func TestGetSubKeys(t *testing.T) {
@@ -80,10 +90,10 @@ func TestGet2(t *testing.T) {
 		},
 	}
 	want := []parameterizer.RT{
-		{Key: []string{"contain ers", "[0]", "ports", "[0]"}, Value: map[string]interface{}{"name": "port1", "number": 8000}, Matches: map[string]string{"containerName": "nginx", "portName": "port1"}},
-		{Key: []string{"contain ers", "[0]", "ports", "[1]"}, Value: map[string]interface{}{"name": "port2", "number": 8080}, Matches: map[string]string{"containerName": "nginx", "portName": "port2"}},
-		{Key: []string{"contain ers", "[2]", "ports", "[0]"}, Value: map[string]interface{}{"name": "port1", "number": 1000}, Matches: map[string]string{"containerName": "nginx", "portName": "port1"}},
-		{Key: []string{"contain ers", "[2]", "ports", "[1]"}, Value: map[string]interface{}{"name": "port2", "number": 1080}, Matches: map[string]string{"containerName": "nginx", "portName": "port2"}},
+		{Key: []string{"contain ers", "[0]", "ports", "[0]"}, Value: map[string]interface{}{"name": "port1", "number": 8000}, Matches: map[string]string{"containerName": "nginx", "portName": "port1"}, Indices: map[string]int{"containerName": 0, "portName": 0}},
+		{Key: []string{"contain ers", "[0]", "ports", "[1]"}, Value: map[string]interface{}{"name": "port2", "number": 8080}, Matches: map[string]string{"containerName": "nginx", "portName": "port2"}, Indices: map[string]int{"containerName": 0, "portName": 1}},
+		{Key: []string{"contain ers", "[2]", "ports", "[0]"}, Value: map[string]interface{}{"name": "port1", "number": 1000}, Matches: map[string]string{"containerName": "nginx", "portName": "port1"}, Indices: map[string]int{"containerName": 2, "portName": 0}},
+		{Key: []string{"contain ers", "[2]", "ports", "[1]"}, Value: map[string]interface{}{"name": "port2", "number": 1080}, Matches: map[string]string{"containerName": "nginx", "portName": "port2"}, Indices: map[string]int{"containerName": 2, "portName": 1}},
 	}
 	results, err := parameterizer.GetAll(key, resource)
 	if err != nil {
@@ -93,3 +103,1588 @@ func TestGet2(t *testing.T) {
 		t.Fatalf("differences %+v", cmp.Diff(results, want))
 	}
 }
+
+func TestGetFirst(t *testing.T) {
+	resource := map[string]interface{}{
+		"containers": []interface{}{
+			map[string]interface{}{"name": "nginx", "image": "docker.io/foo/nginx:latest"},
+			map[string]interface{}{"name": "java", "image": "docker.io/bar/java:latest"},
+			map[string]interface{}{"name": "nginx", "image": "docker.io/foo/nginx:v1.2.0"},
+		},
+	}
+	t.Run("first match of a complex key", func(t *testing.T) {
+		key := `containers.[containerName:name=nginx].image`
+		want := parameterizer.RT{Key: []string{"containers", "[0]", "image"}, Value: "docker.io/foo/nginx:latest", Matches: map[string]string{"containerName": "nginx"}, Indices: map[string]int{"containerName": 0}}
+		result, ok, err := parameterizer.GetFirst(key, resource)
+		if err != nil {
+			t.Fatalf("failed to get the value for the key %s Error: %q", key, err)
+		}
+		if !ok {
+			t.Fatalf("expected to find a match for the key %s", key)
+		}
+		if !cmp.Equal(result, want) {
+			t.Fatalf("differences %+v", cmp.Diff(result, want))
+		}
+	})
+	t.Run("no match", func(t *testing.T) {
+		key := `containers.[containerName:name=python].image`
+		_, ok, err := parameterizer.GetFirst(key, resource)
+		if err != nil {
+			t.Fatalf("failed to get the value for the key %s Error: %q", key, err)
+		}
+		if ok {
+			t.Fatalf("expected no match for the key %s", key)
+		}
+	})
+}
+
+func TestSetAll(t *testing.T) {
+	key := `spec.containers.[containerName:name=nginx].image`
+	resource := map[string]interface{}{
+		"spec": map[string]interface{}{
+			"containers": []interface{}{
+				map[string]interface{}{"name": "nginx", "image": "docker.io/foo/nginx:latest"},
+				map[string]interface{}{"name": "java", "image": "docker.io/bar/java:latest"},
+				map[string]interface{}{"name": "nginx", "image": "docker.io/foo/nginx:v1.2.0"},
+			},
+		},
+	}
+	want := map[string]interface{}{
+		"spec": map[string]interface{}{
+			"containers": []interface{}{
+				map[string]interface{}{"name": "nginx", "image": "{{ .Values.image }}"},
+				map[string]interface{}{"name": "java", "image": "docker.io/bar/java:latest"},
+				map[string]interface{}{"name": "nginx", "image": "{{ .Values.image }}"},
+			},
+		},
+	}
+	updated, err := parameterizer.SetAll(key, "{{ .Values.image }}", resource)
+	if err != nil {
+		t.Fatalf("failed to set the values for the key %s Error: %q", key, err)
+	}
+	if updated != 2 {
+		t.Fatalf("expected 2 values to be updated. Actual: %d", updated)
+	}
+	if !cmp.Equal(resource, want) {
+		t.Fatalf("differences %+v", cmp.Diff(resource, want))
+	}
+}
+
+func TestTransformAll(t *testing.T) {
+	key := `containers.[containerName:name].image`
+	resource := map[string]interface{}{
+		"containers": []interface{}{
+			map[string]interface{}{"name": "nginx", "image": "nginx:latest"},
+			map[string]interface{}{"name": "java", "image": "java:latest"},
+		},
+	}
+	want := map[string]interface{}{
+		"containers": []interface{}{
+			map[string]interface{}{"name": "nginx", "image": "{{ .Values.nginx.image | default \"nginx:latest\" }}"},
+			map[string]interface{}{"name": "java", "image": "{{ .Values.java.image | default \"java:latest\" }}"},
+		},
+	}
+	err := parameterizer.TransformAll(key, resource, func(result parameterizer.RT) (interface{}, error) {
+		return fmt.Sprintf(`{{ .Values.%s.image | default "%s" }}`, result.Matches["containerName"], result.Value), nil
+	})
+	if err != nil {
+		t.Fatalf("failed to transform the values for the key %s Error: %q", key, err)
+	}
+	if !cmp.Equal(resource, want) {
+		t.Fatalf("differences %+v", cmp.Diff(resource, want))
+	}
+	wantErr := fmt.Errorf("some error")
+	err = parameterizer.TransformAll(key, resource, func(parameterizer.RT) (interface{}, error) { return nil, wantErr })
+	if err != wantErr {
+		t.Fatalf("expected the error from the function to be returned. Actual: %+v", err)
+	}
+}
+
+func TestWriteResources(t *testing.T) {
+	k1 := parameterizertypes.K8sResourceT{"kind": "Service", "metadata": map[string]interface{}{"name": "svc1"}, "spec": map[string]interface{}{"type": "{{ .Values.type }}"}}
+	k2 := parameterizertypes.K8sResourceT{"kind": "Service", "metadata": map[string]interface{}{"name": "svc2"}}
+	outputPath := filepath.Join(t.TempDir(), "services.yaml")
+	opts := parameterizer.WriteOptions{StripHelmQuotes: true, Header: "Generated by move2kube\nSource: '{{ .Values.foo }}'"}
+	if _, err := parameterizer.WriteResource(k1, outputPath, opts); err != nil {
+		t.Fatalf("failed to write the resource to the file at path %s . Error: %q", outputPath, err)
+	}
+	if _, err := parameterizer.WriteResources([]parameterizertypes.K8sResourceT{k2}, outputPath, opts); err != nil {
+		t.Fatalf("failed to write the resources to the file at path %s . Error: %q", outputPath, err)
+	}
+	contentBytes, err := ioutil.ReadFile(outputPath)
+	if err != nil {
+		t.Fatalf("failed to read the file at path %s . Error: %q", outputPath, err)
+	}
+	contents := string(contentBytes)
+	wantHeader := "# Generated by move2kube\n# Source: '{{ .Values.foo }}'\n"
+	if !strings.HasPrefix(contents, wantHeader) {
+		t.Fatalf("expected the file to start with the header:\n%s\nActual:\n%s", wantHeader, contents)
+	}
+	if strings.Count(contents, "# Generated by move2kube") != 1 {
+		t.Fatalf("expected the header to be written only once. Actual:\n%s", contents)
+	}
+	if !strings.Contains(contents, "type: {{ .Values.type }}") {
+		t.Fatalf("expected the quotes around the Helm template to be stripped. Actual:\n%s", contents)
+	}
+	if kindIdx, metadataIdx, specIdx := strings.Index(contents, "kind:"), strings.Index(contents, "metadata:"), strings.Index(contents, "spec:"); !(kindIdx < metadataIdx && metadataIdx < specIdx) {
+		t.Fatalf("expected the keys to be sorted. Actual:\n%s", contents)
+	}
+	if strings.Count(contents, "\n---\n") != 2 {
+		t.Fatalf("expected the file to contain 2 documents. Actual:\n%s", contents)
+	}
+}
+
+func TestGetParent(t *testing.T) {
+	config := map[string]interface{}{
+		"spec": map[string]interface{}{
+			"containers": []interface{}{
+				map[string]interface{}{"name": "c1", "image": "i1"},
+			},
+		},
+	}
+	t.Run("parent is a map", func(t *testing.T) {
+		parent, lastKey, ok := parameterizer.GetParent("spec.containers.[0].image", config)
+		if !ok {
+			t.Fatalf("failed to get the parent")
+		}
+		if lastKey != "image" {
+			t.Fatalf("expected the last key to be image . Actual: %s", lastKey)
+		}
+		parentMap, ok := parent.(map[string]interface{})
+		if !ok {
+			t.Fatalf("expected the parent to be a map. Actual: %+v of type %T", parent, parent)
+		}
+		parentMap["name"] = "c2"
+		want := "c2"
+		if name := config["spec"].(map[string]interface{})["containers"].([]interface{})[0].(map[string]interface{})["name"]; name != want {
+			t.Fatalf("expected the sibling to be modified in the config. Expected: %s Actual: %v", want, name)
+		}
+	})
+	t.Run("parent is an array", func(t *testing.T) {
+		parent, lastKey, ok := parameterizer.GetParent("spec.containers.[0]", config)
+		if !ok {
+			t.Fatalf("failed to get the parent")
+		}
+		if lastKey != "[0]" {
+			t.Fatalf("expected the last key to be [0] . Actual: %s", lastKey)
+		}
+		if _, ok := parent.([]interface{}); !ok {
+			t.Fatalf("expected the parent to be an array. Actual: %+v of type %T", parent, parent)
+		}
+	})
+	t.Run("missing key", func(t *testing.T) {
+		if _, _, ok := parameterizer.GetParent("spec.volumes.[0].name", config); ok {
+			t.Fatalf("expected the parent of a missing key to not be found")
+		}
+	})
+}
+
+func TestValidateKey(t *testing.T) {
+	validKeys := []string{
+		"spec.replicas",
+		"spec.template.spec.containers.[0].image",
+		"spec.template.spec.containers.[containerName:name].image",
+		"spec.template.spec.containers.[name=nginx].image",
+		`metadata.annotations."openshift.io/node-selector"`,
+		`metadata.annotations.'foo.[bar'`,
+		`spec.containers.[name='my.app'].image`,
+		"items.[metadata.name=web].spec",
+		"items.[svcName:metadata.name].spec",
+		"items.[0].spec",
+		"items.[10].spec",
+	}
+	for _, key := range validKeys {
+		if err := parameterizer.ValidateKey(key); err != nil {
+			t.Fatalf("expected the key %s to be valid. Error: %q", key, err)
+		}
+	}
+	invalidKeys := []string{
+		"",
+		"a.[bad",
+		"a.bad]",
+		"a.[b[c]]",
+		"a..b",
+		"a.b.",
+		`a."".b`,
+		`a."b.c`,
+		`a.b"c".d`,
+		`a.'b.c".d`,
+		"items.[00].spec",
+		"items.[010].spec",
+		"items.[99999999999999999999999].spec",
+	}
+	for _, key := range invalidKeys {
+		if err := parameterizer.ValidateKey(key); err == nil {
+			t.Fatalf("expected the key %s to be invalid", key)
+		}
+	}
+	if _, err := parameterizer.GetAll("a.[bad", map[string]interface{}{"a": []interface{}{}}); err == nil {
+		t.Fatalf("expected GetAll to fail for an invalid key")
+	}
+}
+
+func TestGetAllQuotedKeys(t *testing.T) {
+	config := map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": map[string]interface{}{"a.b/c": "v1"},
+		},
+		"spec": map[string]interface{}{
+			"containers": []interface{}{
+				map[string]interface{}{"name": "my.app", "image": "i1"},
+				map[string]interface{}{"name": "other", "image": "i2"},
+			},
+		},
+	}
+	testcases := []struct {
+		key  string
+		want []interface{}
+	}{
+		{key: `metadata.annotations.'a.b/c'`, want: []interface{}{"v1"}},
+		{key: `metadata.annotations."a.b/c"`, want: []interface{}{"v1"}},
+		{key: `spec.containers.[name='my.app'].image`, want: []interface{}{"i1"}},
+		{key: `spec.containers.[name="my.app"].image`, want: []interface{}{"i1"}},
+		{key: `'spec'.containers.[1].'image'`, want: []interface{}{"i2"}},
+	}
+	for _, testcase := range testcases {
+		t.Run(testcase.key, func(t *testing.T) {
+			results, err := parameterizer.GetAll(testcase.key, config)
+			if err != nil {
+				t.Fatalf("failed to get the key %s . Error: %q", testcase.key, err)
+			}
+			values := []interface{}{}
+			for _, result := range results {
+				values = append(values, result.Value)
+			}
+			if !cmp.Equal(values, testcase.want) {
+				t.Fatalf("failed to get the values for the key %s . Differences:\n%s", testcase.key, cmp.Diff(testcase.want, values))
+			}
+		})
+	}
+}
+
+func TestGetAllNestedMatchKey(t *testing.T) {
+	config := map[string]interface{}{
+		"items": []interface{}{
+			map[string]interface{}{"metadata": map[string]interface{}{"name": "web"}, "spec": "s1"},
+			map[string]interface{}{"spec": "s2"},
+			map[string]interface{}{"metadata": map[string]interface{}{"name": "db"}, "spec": "s3"},
+		},
+	}
+	results, err := parameterizer.GetAll("items.[metadata.name=web].spec", config)
+	if err != nil {
+		t.Fatalf("failed to get the key. Error: %q", err)
+	}
+	want := []parameterizer.RT{{Key: []string{"items", "[0]", "spec"}, Value: "s1", Matches: map[string]string{"metadata.name": "web"}, Indices: map[string]int{"metadata.name": 0}}}
+	if !cmp.Equal(results, want) {
+		t.Fatalf("failed to get the nested match. Differences:\n%s", cmp.Diff(want, results))
+	}
+	results, err = parameterizer.GetAll("items.[svcName:metadata.name].spec", config)
+	if err != nil {
+		t.Fatalf("failed to get the key. Error: %q", err)
+	}
+	want = []parameterizer.RT{
+		{Key: []string{"items", "[0]", "spec"}, Value: "s1", Matches: map[string]string{"svcName": "web"}, Indices: map[string]int{"svcName": 0}},
+		{Key: []string{"items", "[2]", "spec"}, Value: "s3", Matches: map[string]string{"svcName": "db"}, Indices: map[string]int{"svcName": 2}},
+	}
+	if !cmp.Equal(results, want) {
+		t.Fatalf("failed to get the nested matches. Differences:\n%s", cmp.Diff(want, results))
+	}
+}
+
+func TestWalkAll(t *testing.T) {
+	config := map[string]interface{}{
+		"spec": map[string]interface{}{
+			"containers": []interface{}{
+				map[string]interface{}{"name": "c1", "image": "i1"},
+				map[string]interface{}{"name": "c2", "image": "i2"},
+				map[string]interface{}{"name": "c3", "image": "i3"},
+			},
+		},
+	}
+	key := "spec.containers.[containerName:name].image"
+	t.Run("visit all the matches", func(t *testing.T) {
+		values := []interface{}{}
+		err := parameterizer.WalkAll(key, config, func(result parameterizer.RT) error {
+			values = append(values, result.Value)
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("failed to walk the key %s . Error: %q", key, err)
+		}
+		want := []interface{}{"i1", "i2", "i3"}
+		if !cmp.Equal(values, want) {
+			t.Fatalf("failed to visit all the matches. Differences:\n%s", cmp.Diff(want, values))
+		}
+		results, err := parameterizer.GetAll(key, config)
+		if err != nil {
+			t.Fatalf("failed to get the key %s . Error: %q", key, err)
+		}
+		if len(results) != len(want) {
+			t.Fatalf("expected GetAll to return %d results. Actual: %+v", len(want), results)
+		}
+	})
+	t.Run("stop when visit returns an error", func(t *testing.T) {
+		visitErr := fmt.Errorf("stop")
+		visited := 0
+		err := parameterizer.WalkAll(key, config, func(result parameterizer.RT) error {
+			visited++
+			if result.Matches["containerName"] == "c2" {
+				return visitErr
+			}
+			return nil
+		})
+		if err != visitErr {
+			t.Fatalf("expected the error returned by visit. Actual: %v", err)
+		}
+		if visited != 2 {
+			t.Fatalf("expected the walk to stop after 2 matches. Actual: %d", visited)
+		}
+	})
+}
+
+func TestStripHelmTemplateQuotes(t *testing.T) {
+	testcases := []struct {
+		input string
+		want  string
+	}{
+		{input: "image: '{{ .Values.image }}'\n", want: "image: {{ .Values.image }}\n"},
+		{input: "replicas: '{{ .Values.replicas }}'\nname: 'foo'\n", want: "replicas: {{ .Values.replicas }}\nname: 'foo'\n"},
+		{input: "name: foo\n", want: "name: foo\n"},
+		{
+			input: "data:\n  config.sh: |\n    echo '{{ .Values.greeting }}'\n\n    exit 0\n  name: '{{ .Values.name }}'\n",
+			want:  "data:\n  config.sh: |\n    echo '{{ .Values.greeting }}'\n\n    exit 0\n  name: {{ .Values.name }}\n",
+		},
+		{
+			input: "args:\n  - >-\n    '{{ .Values.arg }}'\n  - '{{ .Values.arg }}'\n",
+			want:  "args:\n  - >-\n    '{{ .Values.arg }}'\n  - {{ .Values.arg }}\n",
+		},
+	}
+	for _, testcase := range testcases {
+		if output := string(parameterizer.StripHelmTemplateQuotes([]byte(testcase.input))); output != testcase.want {
+			t.Fatalf("failed to strip the quotes properly. Differences:\n%s", cmp.Diff(testcase.want, output))
+		}
+	}
+	t.Run("config map with a block scalar", func(t *testing.T) {
+		configMap := map[string]interface{}{
+			"kind": "ConfigMap",
+			"data": map[string]interface{}{
+				"config.sh": "echo 'hello'\necho {{ .Values.greeting }}\n",
+				"name":      "{{ .Values.name }}",
+			},
+		}
+		yamlBytes, err := yaml.Marshal(configMap)
+		if err != nil {
+			t.Fatalf("failed to marshal the config map. Error: %q", err)
+		}
+		want := "data:\n    config.sh: |\n        echo 'hello'\n        echo {{ .Values.greeting }}\n    name: {{ .Values.name }}\nkind: ConfigMap\n"
+		if output := string(parameterizer.StripHelmTemplateQuotes(yamlBytes)); output != want {
+			t.Fatalf("failed to strip the quotes properly. Differences:\n%s", cmp.Diff(want, output))
+		}
+	})
+}
+
+func TestWriteResourceNestedPath(t *testing.T) {
+	k := parameterizertypes.K8sResourceT{"kind": "Service", "metadata": map[string]interface{}{"name": "svc1"}}
+	outputPath := filepath.Join(t.TempDir(), "templates", "default", "service.yaml")
+	if _, err := parameterizer.WriteResource(k, outputPath, parameterizer.WriteOptions{}); err != nil {
+		t.Fatalf("failed to write the resource to the nested path %s . Error: %q", outputPath, err)
+	}
+	if _, err := os.Stat(outputPath); err != nil {
+		t.Fatalf("expected the file at path %s to exist. Error: %q", outputPath, err)
+	}
+}
+
+func TestWriteResourceExistingFile(t *testing.T) {
+	k := parameterizertypes.K8sResourceT{"kind": "Service", "metadata": map[string]interface{}{"name": "svc1"}}
+	handEdited := "# hand edited\nkind: Service\n"
+	writeExistingFile := func(t *testing.T) string {
+		outputPath := filepath.Join(t.TempDir(), "service.yaml")
+		if err := ioutil.WriteFile(outputPath, []byte(handEdited), 0644); err != nil {
+			t.Fatalf("failed to write the file at path %s . Error: %q", outputPath, err)
+		}
+		return outputPath
+	}
+	checkUnchanged := func(t *testing.T, outputPath string) {
+		contentBytes, err := ioutil.ReadFile(outputPath)
+		if err != nil {
+			t.Fatalf("failed to read the file at path %s . Error: %q", outputPath, err)
+		}
+		if string(contentBytes) != handEdited {
+			t.Fatalf("expected the existing file to be unchanged. Actual:\n%s", string(contentBytes))
+		}
+	}
+	t.Run("error on existing file", func(t *testing.T) {
+		outputPath := writeExistingFile(t)
+		if _, err := parameterizer.WriteResource(k, outputPath, parameterizer.WriteOptions{IfExists: parameterizer.ErrorOnExistingFile}); err == nil {
+			t.Fatalf("expected an error since the file at path %s already exists", outputPath)
+		}
+		checkUnchanged(t, outputPath)
+	})
+	t.Run("write a generated file instead of the existing file", func(t *testing.T) {
+		outputPath := writeExistingFile(t)
+		writtenPath, err := parameterizer.WriteResource(k, outputPath, parameterizer.WriteOptions{IfExists: parameterizer.GeneratedFileOnExistingFile})
+		if err != nil {
+			t.Fatalf("failed to write the resource. Error: %q", err)
+		}
+		if want := filepath.Join(filepath.Dir(outputPath), "service.generated.yaml"); writtenPath != want {
+			t.Fatalf("expected the resource to be written to %s . Actual: %s", want, writtenPath)
+		}
+		checkUnchanged(t, outputPath)
+		contentBytes, err := ioutil.ReadFile(writtenPath)
+		if err != nil {
+			t.Fatalf("failed to read the file at path %s . Error: %q", writtenPath, err)
+		}
+		if !strings.Contains(string(contentBytes), "name: svc1") {
+			t.Fatalf("expected the resource to be written to the generated file. Actual:\n%s", string(contentBytes))
+		}
+	})
+	t.Run("overwrite the existing file", func(t *testing.T) {
+		outputPath := writeExistingFile(t)
+		if _, err := parameterizer.WriteResource(k, outputPath, parameterizer.WriteOptions{IfExists: parameterizer.OverwriteExistingFile}); err != nil {
+			t.Fatalf("failed to write the resource. Error: %q", err)
+		}
+		contentBytes, err := ioutil.ReadFile(outputPath)
+		if err != nil {
+			t.Fatalf("failed to read the file at path %s . Error: %q", outputPath, err)
+		}
+		if strings.Contains(string(contentBytes), "hand edited") || !strings.Contains(string(contentBytes), "name: svc1") {
+			t.Fatalf("expected the existing file to be replaced by the resource. Actual:\n%s", string(contentBytes))
+		}
+	})
+	t.Run("new files are written normally", func(t *testing.T) {
+		outputPath := filepath.Join(t.TempDir(), "service.yaml")
+		writtenPath, err := parameterizer.WriteResource(k, outputPath, parameterizer.WriteOptions{IfExists: parameterizer.ErrorOnExistingFile})
+		if err != nil {
+			t.Fatalf("failed to write the resource. Error: %q", err)
+		}
+		if writtenPath != outputPath {
+			t.Fatalf("expected the resource to be written to %s . Actual: %s", outputPath, writtenPath)
+		}
+	})
+}
+
+func TestWalk(t *testing.T) {
+	config := map[string]interface{}{
+		"spec": map[string]interface{}{
+			"replicas":   2,
+			"containers": []interface{}{map[string]interface{}{"name": "nginx", "image": "nginx:1.21"}},
+		},
+		"metadata": map[string]interface{}{"annotations": map[string]interface{}{"a.b/c": "v1"}},
+	}
+	paths := [][]string{}
+	parameterizer.Walk(config, func(path []string, value interface{}) {
+		paths = append(paths, path)
+		if _, err := parameterizer.SetAll(strings.Join(path, "."), "updated", config); err != nil {
+			t.Fatalf("failed to set the walked path %v . Error: %q", path, err)
+		}
+	})
+	want := [][]string{
+		{"metadata", "annotations", `"a.b/c"`},
+		{"spec", "containers", "[0]", "image"},
+		{"spec", "containers", "[0]", "name"},
+		{"spec", "replicas"},
+	}
+	if !cmp.Equal(paths, want) {
+		t.Fatalf("failed to walk the config. Differences:\n%s", cmp.Diff(want, paths))
+	}
+	if value, _, _ := parameterizer.GetFirst(`metadata.annotations."a.b/c"`, config); value.Value != "updated" {
+		t.Fatalf("expected the walked paths to be usable with SetAll. Actual: %+v", config)
+	}
+}
+
+func TestFlatten(t *testing.T) {
+	config := map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"name":        "web",
+			"annotations": map[string]interface{}{"a.b/c": "v1", `say "hi"`: "v2"},
+			"labels":      map[string]interface{}{},
+		},
+		"spec": map[string]interface{}{
+			"replicas": 2,
+			"containers": []interface{}{
+				map[string]interface{}{"name": "nginx", "args": []interface{}{"-a", "-b"}},
+				map[string]interface{}{"name": "java", "ports": []interface{}{}},
+			},
+		},
+	}
+	want := map[string]interface{}{
+		"metadata.name":                   "web",
+		`metadata.annotations."a.b/c"`:    "v1",
+		`metadata.annotations.'say "hi"'`: "v2",
+		"metadata.labels":                 map[string]interface{}{},
+		"spec.replicas":                   2,
+		"spec.containers.[0].name":        "nginx",
+		"spec.containers.[0].args.[0]":    "-a",
+		"spec.containers.[0].args.[1]":    "-b",
+		"spec.containers.[1].name":        "java",
+		"spec.containers.[1].ports":       []interface{}{},
+	}
+	flattened := parameterizer.Flatten(config)
+	if !cmp.Equal(flattened, want) {
+		t.Fatalf("failed to flatten the config. Differences:\n%s", cmp.Diff(want, flattened))
+	}
+	for key, value := range flattened {
+		result, ok, err := parameterizer.GetFirst(key, config)
+		if err != nil || !ok {
+			t.Fatalf("failed to get the flattened key %s . Error: %q", key, err)
+		}
+		if !cmp.Equal(result.Value, value) {
+			t.Fatalf("the flattened key %s has the wrong value. Differences:\n%s", key, cmp.Diff(value, result.Value))
+		}
+	}
+	unflattened, err := parameterizer.Unflatten(flattened)
+	if err != nil {
+		t.Fatalf("failed to unflatten the config. Error: %q", err)
+	}
+	if !cmp.Equal(unflattened, interface{}(config)) {
+		t.Fatalf("failed to round trip the config. Differences:\n%s", cmp.Diff(config, unflattened))
+	}
+	t.Run("top level slices and scalars", func(t *testing.T) {
+		for _, config := range []interface{}{[]interface{}{"a", map[string]interface{}{"b": "c"}}, "scalar"} {
+			unflattened, err := parameterizer.Unflatten(parameterizer.Flatten(config))
+			if err != nil {
+				t.Fatalf("failed to unflatten the config. Error: %q", err)
+			}
+			if !cmp.Equal(unflattened, config) {
+				t.Fatalf("failed to round trip the config. Differences:\n%s", cmp.Diff(config, unflattened))
+			}
+		}
+	})
+	t.Run("conflicting keys", func(t *testing.T) {
+		if _, err := parameterizer.Unflatten(map[string]interface{}{"a": 1, "a.b": 2}); err == nil {
+			t.Fatalf("expected an error for conflicting keys")
+		}
+		if _, err := parameterizer.Unflatten(map[string]interface{}{"a.[0]": 1, "a.b": 2}); err == nil {
+			t.Fatalf("expected an error for a key that is both a slice and a map")
+		}
+	})
+}
+
+func TestGetAllLenient(t *testing.T) {
+	config := map[string]interface{}{
+		"containers": []interface{}{
+			map[string]interface{}{"name": "nginx", "ports": []interface{}{map[string]interface{}{"containerPort": 80}}},
+			map[string]interface{}{"name": "java"},
+			"not an object",
+			map[string]interface{}{"name": "nginx", "ports": []interface{}{map[string]interface{}{"containerPort": 8080}}},
+		},
+	}
+	key := "containers.[name=nginx].ports.[0].containerPort"
+	if _, err := parameterizer.GetAll("containers.[name].ports.[0].containerPort", config); err == nil {
+		t.Fatalf("expected GetAll to fail since some of the elements don't match")
+	}
+	results, branchErrs, err := parameterizer.GetAllLenient("containers.[name].ports.[0].containerPort", config)
+	if err != nil {
+		t.Fatalf("failed to get the key. Error: %q", err)
+	}
+	values := []interface{}{}
+	for _, result := range results {
+		values = append(values, result.Value)
+	}
+	if want := []interface{}{80, 8080}; !cmp.Equal(values, want) {
+		t.Fatalf("failed to get the matching values. Differences:\n%s", cmp.Diff(want, values))
+	}
+	if len(branchErrs) != 2 {
+		t.Fatalf("expected an error for the element without ports and the element that is not an object. Actual: %+v", branchErrs)
+	}
+	results, branchErrs, err = parameterizer.GetAllLenient(key, config)
+	if err != nil || len(results) != 2 || len(branchErrs) != 1 {
+		t.Fatalf("expected 2 matches and 1 skipped branch. Actual: %+v %+v %q", results, branchErrs, err)
+	}
+	if _, _, err := parameterizer.GetAllLenient("containers.[bad", config); err == nil {
+		t.Fatalf("expected an error for an invalid key")
+	}
+}
+
+func TestGetAllLastIndex(t *testing.T) {
+	config := map[string]interface{}{
+		"containers": []interface{}{
+			map[string]interface{}{"name": "nginx", "args": []interface{}{"-a", "-b", "-c"}},
+			map[string]interface{}{"name": "java", "args": []interface{}{}},
+		},
+	}
+	results, err := parameterizer.GetAll("containers.[0].args.[last]", config)
+	if err != nil {
+		t.Fatalf("failed to get the key. Error: %q", err)
+	}
+	want := []parameterizer.RT{{Key: []string{"containers", "[0]", "args", "[2]"}, Value: "-c"}}
+	if !cmp.Equal(results, want) {
+		t.Fatalf("failed to get the last element. Differences:\n%s", cmp.Diff(want, results))
+	}
+	results, err = parameterizer.GetAll("containers.[last].name", config)
+	if err != nil {
+		t.Fatalf("failed to get the key. Error: %q", err)
+	}
+	if len(results) != 1 || results[0].Value != "java" {
+		t.Fatalf("expected the name of the last container. Actual: %+v", results)
+	}
+	if _, err := parameterizer.GetAll("containers.[1].args.[last]", config); err == nil || !strings.Contains(err.Error(), "empty slice") {
+		t.Fatalf("expected an error for an empty slice. Actual: %v", err)
+	}
+	if _, err := parameterizer.SetAll("containers.[0].args.[last]", "-d", config); err != nil {
+		t.Fatalf("failed to set the key. Error: %q", err)
+	}
+	if args := config["containers"].([]interface{})[0].(map[string]interface{})["args"]; !cmp.Equal(args, []interface{}{"-a", "-b", "-d"}) {
+		t.Fatalf("failed to set the last element. Actual: %+v", args)
+	}
+}
+
+func TestWriteResourcesIndex(t *testing.T) {
+	outDir := t.TempDir()
+	indexPath := filepath.Join(outDir, "index.yaml")
+	opts := parameterizer.WriteOptions{IndexPath: indexPath}
+	svc := parameterizertypes.K8sResourceT{"kind": "Service", "metadata": map[string]interface{}{"name": "web", "namespace": "ns1"}}
+	dep := parameterizertypes.K8sResourceT{"kind": "Deployment", "metadata": map[string]interface{}{"name": "web"}}
+	if _, err := parameterizer.WriteResources([]parameterizertypes.K8sResourceT{svc}, filepath.Join(outDir, "web-service.yaml"), opts); err != nil {
+		t.Fatalf("failed to write the resources. Error: %q", err)
+	}
+	if err := os.MkdirAll(filepath.Join(outDir, "deployments"), 0755); err != nil {
+		t.Fatalf("failed to create the directory. Error: %q", err)
+	}
+	if _, err := parameterizer.WriteResources([]parameterizertypes.K8sResourceT{dep}, filepath.Join(outDir, "deployments", "web-deployment.yaml"), opts); err != nil {
+		t.Fatalf("failed to write the resources. Error: %q", err)
+	}
+	entries := []parameterizer.IndexEntry{}
+	if err := common.ReadYaml(indexPath, &entries); err != nil {
+		t.Fatalf("failed to read the index file. Error: %q", err)
+	}
+	want := []parameterizer.IndexEntry{
+		{Kind: "Deployment", Name: "web", Path: "deployments/web-deployment.yaml"},
+		{Kind: "Service", Namespace: "ns1", Name: "web", Path: "web-service.yaml"},
+	}
+	if !cmp.Equal(entries, want) {
+		t.Fatalf("the index file is incorrect. Differences:\n%s", cmp.Diff(want, entries))
+	}
+}
+
+func TestCountAll(t *testing.T) {
+	config := map[string]interface{}{
+		"containers": []interface{}{
+			map[string]interface{}{"name": "nginx", "image": "i1"},
+			map[string]interface{}{"name": "java", "image": "i2"},
+			map[string]interface{}{"name": "nginx"},
+		},
+	}
+	testcases := []struct {
+		key  string
+		want int
+	}{
+		{key: "containers.[name].image", want: 2},
+		{key: "containers.[name=nginx].image", want: 1},
+		{key: "containers.[name=nginx]", want: 2},
+		{key: "containers.[5].image", want: 0},
+		{key: "volumes.[name].path", want: 0},
+	}
+	for _, testcase := range testcases {
+		count, err := parameterizer.CountAll(testcase.key, config)
+		if err != nil {
+			t.Fatalf("failed to count the matches for the key %s . Error: %q", testcase.key, err)
+		}
+		if count != testcase.want {
+			t.Fatalf("expected %d matches for the key %s . Actual: %d", testcase.want, testcase.key, count)
+		}
+	}
+	if _, err := parameterizer.CountAll("containers..image", config); err == nil {
+		t.Fatalf("expected an error for an invalid key")
+	}
+}
+
+func TestExists(t *testing.T) {
+	config := map[string]interface{}{
+		"containers": []interface{}{
+			map[string]interface{}{"name": "nginx", "image": "i1"},
+			map[string]interface{}{"name": "java"},
+		},
+	}
+	testcases := []struct {
+		key  string
+		want bool
+	}{
+		{key: "containers.[name].image", want: true},
+		{key: "containers.[name=java].image", want: false},
+		{key: "containers.[name=java]", want: true},
+		{key: "containers.[5].image", want: false},
+		{key: "volumes.[name].path", want: false},
+		{key: "containers..image", want: false},
+	}
+	for _, testcase := range testcases {
+		if exists := parameterizer.Exists(testcase.key, config); exists != testcase.want {
+			t.Fatalf("expected Exists to return %t for the key %s . Actual: %t", testcase.want, testcase.key, exists)
+		}
+	}
+}
+
+func TestGetAndSetByPointer(t *testing.T) {
+	config := map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": map[string]interface{}{"a.b/c": "v1", "x~y": "v2"},
+		},
+		"spec": map[string]interface{}{
+			"containers": []interface{}{
+				map[string]interface{}{"name": "nginx", "image": "i1"},
+				map[string]interface{}{"name": "java", "image": "i2"},
+			},
+		},
+	}
+	testcases := []struct {
+		pointer string
+		want    interface{}
+	}{
+		{pointer: "/spec/containers/1/image", want: "i2"},
+		{pointer: "/metadata/annotations/a.b~1c", want: "v1"},
+		{pointer: "/metadata/annotations/x~0y", want: "v2"},
+	}
+	for _, testcase := range testcases {
+		value, err := parameterizer.GetByPointer(testcase.pointer, config)
+		if err != nil {
+			t.Fatalf("failed to get the JSON pointer %s . Error: %q", testcase.pointer, err)
+		}
+		if !cmp.Equal(value, testcase.want) {
+			t.Fatalf("failed to get the JSON pointer %s . Differences:\n%s", testcase.pointer, cmp.Diff(testcase.want, value))
+		}
+	}
+	if err := parameterizer.SetByPointer("/spec/containers/0/image", "i3", config); err != nil {
+		t.Fatalf("failed to set the JSON pointer. Error: %q", err)
+	}
+	if value, err := parameterizer.GetByPointer("/spec/containers/0/image", config); err != nil || value != "i3" {
+		t.Fatalf("expected the image to be updated. Actual: %+v Error: %q", value, err)
+	}
+	for _, pointer := range []string{"spec/containers", "/spec/containers/-", "/spec/containers/5/image", "/spec/volumes"} {
+		if _, err := parameterizer.GetByPointer(pointer, config); err == nil {
+			t.Fatalf("expected an error for the JSON pointer %s", pointer)
+		}
+	}
+}
+
+func TestMarshalResources(t *testing.T) {
+	ks := []parameterizertypes.K8sResourceT{
+		{"kind": "Deployment", "metadata": map[string]interface{}{"name": "web"}, "spec": map[string]interface{}{"replicas": "{{ .Values.replicas }}"}},
+		{"kind": "Service", "metadata": map[string]interface{}{"name": "web"}},
+		{"kind": "Service", "metadata": map[string]interface{}{"name": "web"}, "spec": map[string]interface{}{"type": "NodePort"}},
+	}
+	marshalled, err := parameterizer.MarshalResources(ks, true)
+	if err != nil {
+		t.Fatalf("failed to marshal the resources. Error: %q", err)
+	}
+	want := map[string]string{
+		"web-deployment.yaml": "kind: Deployment\nmetadata:\n    name: web\nspec:\n    replicas: {{ .Values.replicas }}\n",
+		"web-service.yaml":    "kind: Service\nmetadata:\n    name: web\n---\nkind: Service\nmetadata:\n    name: web\nspec:\n    type: NodePort\n",
+	}
+	actual := map[string]string{}
+	for filename, yamlBytes := range marshalled {
+		actual[filename] = string(yamlBytes)
+	}
+	if !cmp.Equal(actual, want) {
+		t.Fatalf("failed to marshal the resources. Differences:\n%s", cmp.Diff(want, actual))
+	}
+	marshalled, err = parameterizer.MarshalResources(ks[:1], false)
+	if err != nil {
+		t.Fatalf("failed to marshal the resources. Error: %q", err)
+	}
+	if !strings.Contains(string(marshalled["web-deployment.yaml"]), "'{{ .Values.replicas }}'") {
+		t.Fatalf("expected the quotes to be kept. Actual:\n%s", string(marshalled["web-deployment.yaml"]))
+	}
+}
+
+func TestGetHeaderAndTimestamp(t *testing.T) {
+	if header := parameterizer.GetHeader("src", time.Time{}); strings.Contains(header, "Timestamp") {
+		t.Fatalf("expected the timestamp to be omitted. Actual:\n%s", header)
+	}
+	if header := parameterizer.GetHeader("src", time.Unix(0, 0)); !strings.Contains(header, "Timestamp: 1970-01-01T00:00:00Z") {
+		t.Fatalf("expected the timestamp in the header. Actual:\n%s", header)
+	}
+	oldValue, wasSet := os.LookupEnv("SOURCE_DATE_EPOCH")
+	t.Cleanup(func() {
+		if wasSet {
+			os.Setenv("SOURCE_DATE_EPOCH", oldValue)
+			return
+		}
+		os.Unsetenv("SOURCE_DATE_EPOCH")
+	})
+	os.Unsetenv("SOURCE_DATE_EPOCH")
+	if timestamp, err := parameterizer.GetTimestamp(true); err != nil || !timestamp.IsZero() {
+		t.Fatalf("expected the zero time for reproducible output. Actual: %s Error: %v", timestamp, err)
+	}
+	if timestamp, err := parameterizer.GetTimestamp(false); err != nil || timestamp.IsZero() {
+		t.Fatalf("expected the current time. Actual: %s Error: %v", timestamp, err)
+	}
+	os.Setenv("SOURCE_DATE_EPOCH", "1600000000")
+	for _, reproducible := range []bool{true, false} {
+		timestamp, err := parameterizer.GetTimestamp(reproducible)
+		if err != nil {
+			t.Fatalf("failed to get the timestamp. Error: %q", err)
+		}
+		if !timestamp.Equal(time.Unix(1600000000, 0)) {
+			t.Fatalf("expected the timestamp from SOURCE_DATE_EPOCH. Actual: %s", timestamp)
+		}
+	}
+	os.Setenv("SOURCE_DATE_EPOCH", "yesterday")
+	if _, err := parameterizer.GetTimestamp(false); err == nil {
+		t.Fatalf("expected an error for an invalid SOURCE_DATE_EPOCH")
+	}
+}
+
+func TestValidatePack(t *testing.T) {
+	packDir := t.TempDir()
+	packYaml := `apiVersion: move2kube.konveyor.io/v1alpha1
+kind: Packaging
+metadata:
+  name: pack
+spec:
+  paths:
+    - src: k8s
+  parameterizers:
+    - target: spec.replicas
+    - target: 'metadata.labels."app'
+---
+apiVersion: move2kube.konveyor.io/v1alpha1
+kind: Parameterizer
+metadata:
+  name: params
+spec:
+  parameterizers:
+    - target: spec.template.spec.containers.[containerName:name].image
+    - target: spec..replicas
+    - template: foo
+`
+	otherYaml := `kind: Deployment
+spec:
+  parameterizers:
+    - target: spec..replicas
+`
+	if err := ioutil.WriteFile(filepath.Join(packDir, "pack.yaml"), []byte(packYaml), common.DefaultFilePermission); err != nil {
+		t.Fatalf("failed to write the pack file. Error: %q", err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(packDir, "other.yaml"), []byte(otherYaml), common.DefaultFilePermission); err != nil {
+		t.Fatalf("failed to write the other file. Error: %q", err)
+	}
+	keyErrs, err := parameterizer.ValidatePack(nil, packDir)
+	if err != nil {
+		t.Fatalf("failed to validate the pack. Error: %q", err)
+	}
+	wantLines := []int{10, 19, 20}
+	if len(keyErrs) != len(wantLines) {
+		t.Fatalf("expected %d errors. Actual: %+v", len(wantLines), keyErrs)
+	}
+	for i, keyErr := range keyErrs {
+		if keyErr.Line != wantLines[i] || keyErr.Path != filepath.Join(packDir, "pack.yaml") {
+			t.Fatalf("expected error %d to be at line %d of pack.yaml. Actual: %s", i, wantLines[i], keyErr.Error())
+		}
+	}
+}
+
+func TestGetAllCaseInsensitive(t *testing.T) {
+	config := map[string]interface{}{
+		"Spec": map[string]interface{}{"Replicas": 2, "replicas": 3, "ServiceName": "web"},
+	}
+	if _, err := parameterizer.GetAll("spec.serviceName", config); err == nil {
+		t.Fatalf("expected an error since the keys are case sensitive by default")
+	}
+	opts := parameterizer.KeyOptions{CaseInsensitive: true}
+	results, err := parameterizer.GetAll("spec.serviceName", config, opts)
+	if err != nil {
+		t.Fatalf("failed to get the key. Error: %q", err)
+	}
+	want := []parameterizer.RT{{Key: []string{"Spec", "ServiceName"}, Value: "web"}}
+	if !cmp.Equal(results, want) {
+		t.Fatalf("failed to get the key ignoring case. Differences:\n%s", cmp.Diff(want, results))
+	}
+	results, err = parameterizer.GetAll("spec.replicas", config, opts)
+	if err != nil {
+		t.Fatalf("failed to get the key. Error: %q", err)
+	}
+	if len(results) != 1 || results[0].Value != 3 {
+		t.Fatalf("expected the exact match to be preferred. Actual: %+v", results)
+	}
+	if _, err := parameterizer.SetAll("SPEC.servicename", "api", config, opts); err != nil {
+		t.Fatalf("failed to set the key. Error: %q", err)
+	}
+	if actual := config["Spec"].(map[string]interface{})["ServiceName"]; actual != "api" {
+		t.Fatalf("failed to set the key ignoring case. Actual: %+v", actual)
+	}
+}
+
+func TestGetCompiled(t *testing.T) {
+	config := map[string]interface{}{
+		"spec": map[string]interface{}{
+			"containers": []interface{}{
+				map[string]interface{}{"name": "nginx", "image": "nginx:latest"},
+				map[string]interface{}{"name": "java", "image": "openjdk:11"},
+			},
+		},
+	}
+	key := "spec.containers.[containerName:name].image"
+	ck, err := parameterizer.CompileKey(key)
+	if err != nil {
+		t.Fatalf("failed to compile the key %s . Error: %q", key, err)
+	}
+	if ck.String() != key {
+		t.Fatalf("expected the compiled key to be %s . Actual: %s", key, ck.String())
+	}
+	want, err := parameterizer.GetAll(key, config)
+	if err != nil {
+		t.Fatalf("failed to get the key. Error: %q", err)
+	}
+	results, err := parameterizer.GetCompiled(ck, config)
+	if err != nil {
+		t.Fatalf("failed to get the compiled key. Error: %q", err)
+	}
+	if !cmp.Equal(results, want) {
+		t.Fatalf("the compiled key lookup differs from the string key lookup. Differences:\n%s", cmp.Diff(want, results))
+	}
+	if _, err := parameterizer.CompileKey(`spec."containers`); err == nil {
+		t.Fatalf("expected an error for an invalid key")
+	}
+	if _, err := parameterizer.GetCompiled(parameterizer.CompiledKey{}, config); err == nil {
+		t.Fatalf("expected an error for an empty compiled key")
+	}
+}
+
+func getBenchmarkConfig(depth int) (map[string]interface{}, string) {
+	config := map[string]interface{}{"leaf": "value"}
+	subKeys := []string{"leaf"}
+	for i := depth - 1; i >= 0; i-- {
+		subKey := fmt.Sprintf("level%d", i)
+		config = map[string]interface{}{subKey: config}
+		subKeys = append([]string{subKey}, subKeys...)
+	}
+	return config, strings.Join(subKeys, ".")
+}
+
+func BenchmarkGetAll(b *testing.B) {
+	config, key := getBenchmarkConfig(20)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := parameterizer.GetAll(key, config); err != nil {
+			b.Fatalf("failed to get the key. Error: %q", err)
+		}
+	}
+}
+
+func BenchmarkGetCompiled(b *testing.B) {
+	config, key := getBenchmarkConfig(20)
+	ck, err := parameterizer.CompileKey(key)
+	if err != nil {
+		b.Fatalf("failed to compile the key. Error: %q", err)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := parameterizer.GetCompiled(ck, config); err != nil {
+			b.Fatalf("failed to get the key. Error: %q", err)
+		}
+	}
+}
+
+func TestWriteResourcesByNamespace(t *testing.T) {
+	outputPath := t.TempDir()
+	k8sResources := []parameterizertypes.K8sResourceT{
+		{"kind": "Deployment", "metadata": map[string]interface{}{"name": "web", "namespace": "ns-a"}},
+		{"kind": "Service", "metadata": map[string]interface{}{"name": "web", "namespace": "ns-b"}},
+		{"kind": "ClusterRole", "metadata": map[string]interface{}{"name": "reader"}},
+		{"kind": "ConfigMap", "metadata": map[string]interface{}{"name": "config"}},
+	}
+	filesWritten, err := parameterizer.WriteResourcesByNamespace(k8sResources, outputPath)
+	if err != nil {
+		t.Fatalf("failed to write the resources. Error: %q", err)
+	}
+	want := []string{
+		filepath.Join("ns-a", "web-deployment.yaml"),
+		filepath.Join("ns-b", "web-service.yaml"),
+		filepath.Join("_cluster", "reader-clusterrole.yaml"),
+		filepath.Join("_default", "config-configmap.yaml"),
+	}
+	if !cmp.Equal(filesWritten, want) {
+		t.Fatalf("the files written are incorrect. Differences:\n%s", cmp.Diff(want, filesWritten))
+	}
+	for _, relPath := range want {
+		if _, err := os.Stat(filepath.Join(outputPath, relPath)); err != nil {
+			t.Fatalf("expected the file %s to be written. Error: %q", relPath, err)
+		}
+	}
+}
+
+func TestWriteResourcesByNamespaceUnsafePaths(t *testing.T) {
+	outputPath := filepath.Join(t.TempDir(), "out")
+	k8sResources := []parameterizertypes.K8sResourceT{
+		{"kind": "Deployment", "metadata": map[string]interface{}{"name": "../../web", "namespace": "cluster"}},
+		{"kind": "ConfigMap", "metadata": map[string]interface{}{"name": "..", "namespace": "default"}},
+	}
+	filesWritten, err := parameterizer.WriteResourcesByNamespace(k8sResources, outputPath)
+	if err != nil {
+		t.Fatalf("failed to write the resources. Error: %q", err)
+	}
+	want := []string{
+		filepath.Join("cluster", "web-deployment.yaml"),
+		filepath.Join("default", "unnamed-configmap.yaml"),
+	}
+	if !cmp.Equal(filesWritten, want) {
+		t.Fatalf("the files written are incorrect. Differences:\n%s", cmp.Diff(want, filesWritten))
+	}
+	for _, namespace := range []string{"../../escaped", "/tmp", "a/b", ".."} {
+		k8sResource := parameterizertypes.K8sResourceT{"kind": "Service", "metadata": map[string]interface{}{"name": "web", "namespace": namespace}}
+		if _, err := parameterizer.WriteResourcesByNamespace([]parameterizertypes.K8sResourceT{k8sResource}, outputPath); err == nil {
+			t.Fatalf("expected an error for the namespace %s", namespace)
+		}
+	}
+}
+
+func TestWriteResourcesByKind(t *testing.T) {
+	k8sResources := []parameterizertypes.K8sResourceT{
+		{"kind": "Deployment", "metadata": map[string]interface{}{"name": "web"}},
+		{"kind": "Service", "metadata": map[string]interface{}{"name": "web"}},
+		{"kind": "ConfigMap", "metadata": map[string]interface{}{"name": "config"}},
+	}
+	kindDirs := map[string]string{"Deployment": "deployments", "service": "services"}
+	testcases := []struct {
+		name       string
+		defaultDir string
+		want       []string
+	}{
+		{name: "unmapped kinds in the output directory", want: []string{
+			filepath.Join("deployments", "web-deployment.yaml"),
+			filepath.Join("services", "web-service.yaml"),
+			"config-configmap.yaml",
+		}},
+		{name: "unmapped kinds in the default directory", defaultDir: "others", want: []string{
+			filepath.Join("deployments", "web-deployment.yaml"),
+			filepath.Join("services", "web-service.yaml"),
+			filepath.Join("others", "config-configmap.yaml"),
+		}},
+	}
+	for _, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			outputPath := t.TempDir()
+			filesWritten, err := parameterizer.WriteResourcesByKind(k8sResources, outputPath, kindDirs, testcase.defaultDir)
+			if err != nil {
+				t.Fatalf("failed to write the resources. Error: %q", err)
+			}
+			if !cmp.Equal(filesWritten, testcase.want) {
+				t.Fatalf("the files written are incorrect. Differences:\n%s", cmp.Diff(testcase.want, filesWritten))
+			}
+			for _, relPath := range testcase.want {
+				if _, err := os.Stat(filepath.Join(outputPath, relPath)); err != nil {
+					t.Fatalf("expected the file %s to be written. Error: %q", relPath, err)
+				}
+			}
+		})
+	}
+}
+
+func TestGetAllInvalidIndex(t *testing.T) {
+	config := map[string]interface{}{"items": []interface{}{"a", "b", "c", "d", "e", "f", "g", "h", "i"}}
+	for _, key := range []string{"items.[00]", "items.[010]", "items.[99999999999999999999999]"} {
+		if _, err := parameterizer.GetAll(key, config); err == nil {
+			t.Fatalf("expected an error for the key %s", key)
+		}
+		if _, err := parameterizer.SetAll(key, "z", config); err == nil {
+			t.Fatalf("expected an error for the key %s", key)
+		}
+	}
+	if _, err := parameterizer.GetAll("items.[9223372036854775807]", config); err == nil || !strings.Contains(err.Error(), "out of range") {
+		t.Fatalf("expected an out of range error for a very large index. Actual: %v", err)
+	}
+}
+
+func TestGetAllRange(t *testing.T) {
+	config := map[string]interface{}{"items": []interface{}{"a", "b", "c", "d", "e"}}
+	testcases := []struct {
+		key  string
+		want []string
+	}{
+		{key: "items.[1:3]", want: []string{"b", "c"}},
+		{key: "items.[:2]", want: []string{"a", "b"}},
+		{key: "items.[3:]", want: []string{"d", "e"}},
+		{key: "items.[-2:]", want: []string{"d", "e"}},
+		{key: "items.[2:100]", want: []string{"c", "d", "e"}},
+		{key: "items.[4:2]", want: []string{}},
+		{key: "items.[:]", want: []string{"a", "b", "c", "d", "e"}},
+		{key: "items.[*]", want: []string{"a", "b", "c", "d", "e"}},
+	}
+	for _, testcase := range testcases {
+		results, err := parameterizer.GetAll(testcase.key, config)
+		if err != nil {
+			t.Fatalf("failed to get the key %s . Error: %q", testcase.key, err)
+		}
+		values := []string{}
+		for _, result := range results {
+			values = append(values, result.Value.(string))
+		}
+		if !cmp.Equal(values, testcase.want) {
+			t.Fatalf("failed to get the correct values for the key %s . Expected: %+v Actual: %+v", testcase.key, testcase.want, values)
+		}
+	}
+	deployment := map[string]interface{}{"containers": []interface{}{
+		map[string]interface{}{"name": "a", "image": "a:1"},
+		map[string]interface{}{"name": "b", "image": "b:1"},
+		map[string]interface{}{"name": "c", "image": "c:1"},
+	}}
+	count, err := parameterizer.SetAll("containers.[:2].image", "z:2", deployment)
+	if err != nil || count != 2 {
+		t.Fatalf("failed to set the images of the first two containers. Count: %d Error: %v", count, err)
+	}
+	images, err := parameterizer.GetAll("containers.[*].image", deployment)
+	if err != nil {
+		t.Fatalf("failed to get the images. Error: %q", err)
+	}
+	if len(images) != 3 || images[0].Value != "z:2" || images[1].Value != "z:2" || images[2].Value != "c:1" {
+		t.Fatalf("expected only the first two images to be updated. Actual: %+v", images)
+	}
+	if !cmp.Equal(images[2].Key, []string{"containers", "[2]", "image"}) {
+		t.Fatalf("expected the key to contain the concrete index. Actual: %+v", images[2].Key)
+	}
+	for _, key := range []string{"items.[01:3]", "items.[1:99999999999999999999999]"} {
+		if err := parameterizer.ValidateKey(key); err == nil {
+			t.Fatalf("expected the key %s to be invalid", key)
+		}
+	}
+}
+
+func TestSetAllListValue(t *testing.T) {
+	resource := map[string]interface{}{"containers": []interface{}{
+		map[string]interface{}{"name": "a", "args": []interface{}{"--port", "8080"}},
+		map[string]interface{}{"name": "b", "args": []interface{}{"--debug"}},
+	}}
+	newArgs := []interface{}{"--port", "9090"}
+	updated, err := parameterizer.SetAll("containers.[*].args", newArgs, resource)
+	if err != nil || updated != 2 {
+		t.Fatalf("failed to set the list values. Updated: %d Error: %v", updated, err)
+	}
+	results, err := parameterizer.GetAll("containers.[*].args", resource)
+	if err != nil {
+		t.Fatalf("failed to get the list values. Error: %q", err)
+	}
+	for _, result := range results {
+		if !cmp.Equal(result.Value, newArgs) {
+			t.Fatalf("expected the whole list to be replaced. Actual: %+v", result.Value)
+		}
+	}
+	results[0].Value.([]interface{})[1] = "1234"
+	if !cmp.Equal(results[1].Value, newArgs) {
+		t.Fatalf("expected each key to get its own copy of the list. Actual: %+v", results[1].Value)
+	}
+}
+
+func TestSetNullAndDelete(t *testing.T) {
+	resource := map[string]interface{}{
+		"metadata": map[string]interface{}{"name": "web", "namespace": "prod", "labels": map[string]interface{}{"app": "web"}},
+		"spec": map[string]interface{}{"containers": []interface{}{
+			map[string]interface{}{"name": "web"},
+			map[string]interface{}{"name": "debug"},
+			map[string]interface{}{"name": "sidecar"},
+			map[string]interface{}{"name": "debug"},
+		}},
+	}
+	if updated, err := parameterizer.SetAll("metadata.namespace", nil, resource); err != nil || updated != 1 {
+		t.Fatalf("failed to set the key to null. Updated: %d Error: %v", updated, err)
+	}
+	if deleted, err := parameterizer.Delete("metadata.labels", resource); err != nil || deleted != 1 {
+		t.Fatalf("failed to delete the key. Deleted: %d Error: %v", deleted, err)
+	}
+	if deleted, err := parameterizer.Delete("spec.containers.[name=debug]", resource); err != nil || deleted != 2 {
+		t.Fatalf("failed to delete the array elements. Deleted: %d Error: %v", deleted, err)
+	}
+	yamlBytes, err := yaml.Marshal(resource)
+	if err != nil {
+		t.Fatalf("failed to marshal the resource. Error: %q", err)
+	}
+	want := `metadata:
+    name: web
+    namespace: null
+spec:
+    containers:
+        - name: web
+        - name: sidecar
+`
+	if string(yamlBytes) != want {
+		t.Fatalf("expected the null value to be written and the deleted keys to be absent. Differences:\n%s", cmp.Diff(want, string(yamlBytes)))
+	}
+	if _, err := parameterizer.Delete("metadata.annotations", resource); err == nil {
+		t.Fatalf("expected an error for a key that doesn't exist")
+	}
+}
+
+func TestGetAllMapEntries(t *testing.T) {
+	resource := map[string]interface{}{
+		"metadata": map[string]interface{}{"labels": map[string]interface{}{
+			"tier":                   "web",
+			"app.kubernetes.io/name": "web",
+			"team":                   "payments",
+		}},
+		"ports": map[string]interface{}{
+			"https": map[string]interface{}{"port": "443", "protocol": "TCP"},
+			"dns":   map[string]interface{}{"port": "53", "protocol": "UDP"},
+			"http":  map[string]interface{}{"port": "80", "protocol": "TCP"},
+		},
+	}
+	results, err := parameterizer.GetAll("metadata.labels.[label:value=web]", resource)
+	if err != nil {
+		t.Fatalf("failed to get the labels. Error: %q", err)
+	}
+	labels := []string{}
+	for _, result := range results {
+		labels = append(labels, result.Matches["label"])
+		if result.Value != "web" {
+			t.Fatalf("expected the value of the matched label to be web. Actual: %+v", result.Value)
+		}
+	}
+	if want := []string{"app.kubernetes.io/name", "tier"}; !cmp.Equal(labels, want) {
+		t.Fatalf("expected the labels to be matched in sorted order. Expected: %+v Actual: %+v", want, labels)
+	}
+	results, err = parameterizer.GetAll("ports.[portName:protocol=TCP].port", resource)
+	if err != nil {
+		t.Fatalf("failed to get the ports. Error: %q", err)
+	}
+	if len(results) != 2 || results[0].Matches["portName"] != "http" || results[1].Matches["portName"] != "https" {
+		t.Fatalf("expected the TCP ports to be matched in sorted order. Actual: %+v", results)
+	}
+	if !cmp.Equal(results[1].Key, []string{"ports", "https", "port"}) || results[1].Value != "443" {
+		t.Fatalf("expected the key to contain the matched map key. Actual: %+v", results[1])
+	}
+	updated, err := parameterizer.SetAll("metadata.labels.[label:value=web]", "api", resource)
+	if err != nil || updated != 2 {
+		t.Fatalf("failed to set the matched labels. Updated: %d Error: %v", updated, err)
+	}
+	if labels := resource["metadata"].(map[string]interface{})["labels"].(map[string]interface{}); labels["tier"] != "api" || labels["team"] != "payments" {
+		t.Fatalf("expected only the matched labels to be updated. Actual: %+v", labels)
+	}
+}
+
+func TestParseKey(t *testing.T) {
+	two, five := 2, 5
+	segments, err := parameterizer.ParseKey(`spec."app.io/name".containers.[containerName:name=nginx].ports.[0].env.[last].args.[2:5].volumes.[*]`)
+	if err != nil {
+		t.Fatalf("failed to parse the key. Error: %q", err)
+	}
+	want := []parameterizer.KeySegment{
+		{Type: parameterizer.MapKeySegment, Raw: "spec", MapKey: "spec"},
+		{Type: parameterizer.MapKeySegment, Raw: "app.io/name", MapKey: "app.io/name"},
+		{Type: parameterizer.MapKeySegment, Raw: "containers", MapKey: "containers"},
+		{Type: parameterizer.SelectorSegment, Raw: "[containerName:name=nginx]", MatchName: "containerName", MatchKey: "name", MatchValue: "nginx"},
+		{Type: parameterizer.MapKeySegment, Raw: "ports", MapKey: "ports"},
+		{Type: parameterizer.ArrayIndexSegment, Raw: "[0]", Index: 0},
+		{Type: parameterizer.MapKeySegment, Raw: "env", MapKey: "env"},
+		{Type: parameterizer.ArrayIndexSegment, Raw: "[last]", Last: true},
+		{Type: parameterizer.MapKeySegment, Raw: "args", MapKey: "args"},
+		{Type: parameterizer.ArrayRangeSegment, Raw: "[2:5]", Start: &two, End: &five},
+		{Type: parameterizer.MapKeySegment, Raw: "volumes", MapKey: "volumes"},
+		{Type: parameterizer.ArrayRangeSegment, Raw: "[*]"},
+	}
+	if !cmp.Equal(segments, want) {
+		t.Fatalf("failed to parse the key correctly. Differences:\n%s", cmp.Diff(want, segments))
+	}
+	if _, err := parameterizer.ParseKey("spec..containers"); err == nil {
+		t.Fatalf("expected an error for an invalid key")
+	}
+}
+
+func TestCommonPrefix(t *testing.T) {
+	deployment := map[string]interface{}{"spec": map[string]interface{}{"template": map[string]interface{}{"spec": map[string]interface{}{
+		"containers": []interface{}{
+			map[string]interface{}{"name": "a", "image": "a:1"},
+			map[string]interface{}{"name": "b", "image": "b:1"},
+		},
+	}}}}
+	results, err := parameterizer.GetAll("spec.template.spec.containers.[*].image", deployment)
+	if err != nil {
+		t.Fatalf("failed to get the images. Error: %q", err)
+	}
+	if prefix := parameterizer.CommonPrefix(results); !cmp.Equal(prefix, []string{"spec", "template", "spec", "containers"}) {
+		t.Fatalf("failed to get the common prefix. Actual: %+v", prefix)
+	}
+	if prefix := parameterizer.CommonPrefix(results[:1]); !cmp.Equal(prefix, results[0].Key) {
+		t.Fatalf("expected the prefix of a single match to be its key. Actual: %+v", prefix)
+	}
+	if prefix := parameterizer.CommonPrefix(nil); len(prefix) != 0 {
+		t.Fatalf("expected an empty prefix when there are no matches. Actual: %+v", prefix)
+	}
+}
+
+func TestGetAllFrom(t *testing.T) {
+	deployment := map[string]interface{}{
+		"spec": map[string]interface{}{
+			"template": map[string]interface{}{
+				"spec": map[string]interface{}{
+					"containers": []interface{}{
+						map[string]interface{}{"name": "nginx", "image": "nginx:latest"},
+					},
+				},
+			},
+		},
+	}
+	list := map[string]interface{}{"items": []interface{}{map[string]interface{}{"kind": "Service"}, deployment}}
+	subtree, ok, err := parameterizer.GetFirst("items.[1]", list)
+	if err != nil || !ok {
+		t.Fatalf("failed to get the deployment from the list. Error: %v", err)
+	}
+	results, err := parameterizer.GetAllFrom(parameterizer.GetSubKeys("spec.template.spec.containers.[containerName:name].image"), subtree.Value)
+	if err != nil {
+		t.Fatalf("failed to get the key from the subtree. Error: %q", err)
+	}
+	want := []parameterizer.RT{{
+		Key:     []string{"spec", "template", "spec", "containers", "[0]", "image"},
+		Value:   "nginx:latest",
+		Matches: map[string]string{"containerName": "nginx"},
+		Indices: map[string]int{"containerName": 0},
+	}}
+	if !cmp.Equal(results, want) {
+		t.Fatalf("failed to get the key relative to the subtree. Differences:\n%s", cmp.Diff(want, results))
+	}
+	if _, err := parameterizer.GetAllFrom(nil, subtree.Value); err == nil {
+		t.Fatalf("expected an error when no sub keys are given")
+	}
+	if _, err := parameterizer.GetAllFrom([]string{"spec", ""}, subtree.Value); err == nil {
+		t.Fatalf("expected an error for an empty sub key")
+	}
+}
+
+func TestWriteResourcesDocumentMarkers(t *testing.T) {
+	k8sResources := []parameterizertypes.K8sResourceT{
+		{"apiVersion": "v1", "kind": "Service", "metadata": map[string]interface{}{"name": "web"}},
+		{"apiVersion": "apps/v1", "kind": "Deployment", "metadata": map[string]interface{}{"name": "web"}},
+	}
+	testcases := map[string]parameterizer.WriteOptions{
+		"default":         {},
+		"no start marker": {OmitDocumentStart: true},
+		"no end marker":   {OmitDocumentEnd: true},
+		"no markers":      {OmitDocumentStart: true, OmitDocumentEnd: true, Header: "generated"},
+	}
+	for name, opts := range testcases {
+		t.Run(name, func(t *testing.T) {
+			outputPath := filepath.Join(t.TempDir(), "resources.yaml")
+			if _, err := parameterizer.WriteResources(k8sResources, outputPath, opts); err != nil {
+				t.Fatalf("failed to write the resources. Error: %q", err)
+			}
+			if _, err := parameterizer.WriteResource(k8sResources[0], outputPath, opts); err != nil {
+				t.Fatalf("failed to append the resource. Error: %q", err)
+			}
+			f, err := os.Open(outputPath)
+			if err != nil {
+				t.Fatalf("failed to open the file at path %s . Error: %q", outputPath, err)
+			}
+			defer f.Close()
+			// decode the file the same way kubectl apply -f does
+			decoder := k8syaml.NewYAMLOrJSONDecoder(f, 4096)
+			kinds := []string{}
+			for {
+				obj := map[string]interface{}{}
+				if err := decoder.Decode(&obj); err != nil {
+					if err == io.EOF {
+						break
+					}
+					t.Fatalf("failed to decode the file at path %s . Error: %q", outputPath, err)
+				}
+				if len(obj) > 0 {
+					kinds = append(kinds, fmt.Sprint(obj["kind"]))
+				}
+			}
+			if want := []string{"Service", "Deployment", "Service"}; !cmp.Equal(kinds, want) {
+				contents, _ := ioutil.ReadFile(outputPath)
+				t.Fatalf("expected the kinds %+v . Actual: %+v\nContents:\n%s", want, kinds, contents)
+			}
+		})
+	}
+}
+
+func TestWriteResourcesVerify(t *testing.T) {
+	opts := parameterizer.WriteOptions{Verify: true, StripHelmQuotes: true}
+	valid := parameterizertypes.K8sResourceT{
+		"apiVersion": "apps/v1",
+		"kind":       "Deployment",
+		"metadata":   map[string]interface{}{"name": "web"},
+		"spec":       map[string]interface{}{"replicas": "{{ index .Values \"common\" \"replicas\" }}"},
+	}
+	if _, err := parameterizer.WriteResource(valid, filepath.Join(t.TempDir(), "valid.yaml"), opts); err != nil {
+		t.Fatalf("expected the resource to be verified. Error: %q", err)
+	}
+	invalid := parameterizertypes.K8sResourceT{"apiVersion": "v1", "metadata": map[string]interface{}{"name": "web"}}
+	invalidPath := filepath.Join(t.TempDir(), "invalid.yaml")
+	_, err := parameterizer.WriteResource(invalid, invalidPath, opts)
+	if err == nil || !strings.Contains(err.Error(), invalidPath) || !strings.Contains(err.Error(), "kind") {
+		t.Fatalf("expected an error identifying the file and the missing kind. Actual: %v", err)
+	}
+	if _, err := parameterizer.WriteResource(invalid, filepath.Join(t.TempDir(), "unverified.yaml"), parameterizer.WriteOptions{}); err != nil {
+		t.Fatalf("expected the resource to not be verified by default. Error: %q", err)
+	}
+}
+
+func TestParameterizeImages(t *testing.T) {
+	deployment := parameterizertypes.K8sResourceT{
+		"apiVersion": "apps/v1",
+		"kind":       "Deployment",
+		"metadata":   map[string]interface{}{"name": "web"},
+		"spec": map[string]interface{}{"template": map[string]interface{}{"spec": map[string]interface{}{
+			"initContainers": []interface{}{map[string]interface{}{"name": "init", "image": "busybox:1.33"}},
+			"containers": []interface{}{
+				map[string]interface{}{"name": "nginx", "image": "nginx:1.21"},
+				map[string]interface{}{"name": "sidecar", "image": "envoy:1.18"},
+			},
+		}}},
+	}
+	values, helmTemplateKeys, err := parameterizer.ParameterizeImages(deployment)
+	if err != nil {
+		t.Fatalf("failed to parameterize the images. Error: %q", err)
+	}
+	wantValues := parameterizertypes.HelmValuesT{"images": map[string]interface{}{"web": map[string]interface{}{
+		"init": "busybox:1.33", "nginx": "nginx:1.21", "sidecar": "envoy:1.18",
+	}}}
+	if !cmp.Equal(values, wantValues) {
+		t.Fatalf("failed to get the Helm values. Differences:\n%s", cmp.Diff(wantValues, values))
+	}
+	if len(helmTemplateKeys) != 3 {
+		t.Fatalf("expected 3 Helm template keys. Actual: %+v", helmTemplateKeys)
+	}
+	marshalled, err := parameterizer.MarshalResources([]parameterizertypes.K8sResourceT{deployment}, true)
+	if err != nil {
+		t.Fatalf("failed to marshal the k8s resource. Error: %q", err)
+	}
+	yamlStr := string(marshalled["web-deployment.yaml"])
+	for _, want := range []string{`image: {{ index .Values "images" "web" "nginx" }}`, `image: {{ index .Values "images" "web" "init" }}`} {
+		if !strings.Contains(yamlStr, want) {
+			t.Fatalf("expected the image to be replaced by %s . Actual:\n%s", want, yamlStr)
+		}
+	}
+	service := parameterizertypes.K8sResourceT{"apiVersion": "v1", "kind": "Service", "metadata": map[string]interface{}{"name": "web"}}
+	if values, _, err := parameterizer.ParameterizeImages(service); err != nil || len(values) != 0 {
+		t.Fatalf("expected no images for a service. Values: %+v Error: %v", values, err)
+	}
+}
+
+func TestEscapedBrackets(t *testing.T) {
+	config := map[string]interface{}{"metadata": map[string]interface{}{"annotations": map[string]interface{}{
+		"a[b]":         "v1",
+		"x.io/list[0]": "v2",
+	}}}
+	if err := parameterizer.ValidateKey(`metadata.annotations.a\[b\]`); err != nil {
+		t.Fatalf("expected the key with escaped brackets to be valid. Error: %q", err)
+	}
+	result, ok, err := parameterizer.GetFirst(`metadata.annotations.a\[b\]`, config)
+	if err != nil || !ok || result.Value != "v1" {
+		t.Fatalf("failed to get the map key with brackets. Result: %+v Error: %v", result, err)
+	}
+	if updated, err := parameterizer.SetAll(`metadata.annotations."x.io/list\[0\]"`, "updated", config); err != nil || updated != 1 {
+		t.Fatalf("failed to set the quoted map key with brackets. Updated: %d Error: %v", updated, err)
+	}
+	if value := config["metadata"].(map[string]interface{})["annotations"].(map[string]interface{})["x.io/list[0]"]; value != "updated" {
+		t.Fatalf("expected the map key with brackets to be updated. Actual: %+v", config)
+	}
+	if _, err := parameterizer.GetAll(`metadata.annotations.a[b]`, config); err == nil {
+		t.Fatalf("expected an error for the unescaped brackets")
+	}
+	want := []string{`metadata.annotations.a\[b\]`, `metadata.annotations."x.io/list\[0\]"`}
+	flattened := parameterizer.Flatten(config)
+	for _, key := range want {
+		if _, ok := flattened[key]; !ok {
+			t.Fatalf("expected the flattened key %s . Actual: %+v", key, flattened)
+		}
+	}
+	unflattened, err := parameterizer.Unflatten(flattened)
+	if err != nil || !cmp.Equal(unflattened, interface{}(config)) {
+		t.Fatalf("failed to round trip the config with brackets in the map keys. Error: %v Differences:\n%s", err, cmp.Diff(config, unflattened))
+	}
+}
+
+func TestWriteFilesToStream(t *testing.T) {
+	baseDir := t.TempDir()
+	files := map[string]string{
+		"a/deployment.yaml": "kind: Deployment\n",
+		"b/service.yaml":    "kind: Service",
+	}
+	for relPath, contents := range files {
+		path := filepath.Join(baseDir, relPath)
+		if err := os.MkdirAll(filepath.Dir(path), common.DefaultDirectoryPermission); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(contents), common.DefaultFilePermission); err != nil {
+			t.Fatal(err)
+		}
+	}
+	paths := []string{
+		filepath.Join(baseDir, "a/deployment.yaml"),
+		filepath.Join(baseDir, "b/service.yaml"),
+		filepath.Join(baseDir, "a/deployment.yaml"),
+	}
+	out := strings.Builder{}
+	if err := parameterizer.WriteFilesToStream(&out, baseDir, paths); err != nil {
+		t.Fatalf("failed to write the files to the stream. Error: %q", err)
+	}
+	want := "---\n# Source: a/deployment.yaml\nkind: Deployment\n---\n# Source: b/service.yaml\nkind: Service\n"
+	if out.String() != want {
+		t.Fatalf("the stream is wrong. Difference:\n%s", cmp.Diff(want, out.String()))
+	}
+	if err := parameterizer.WriteFilesToStream(&out, baseDir, []string{filepath.Join(baseDir, "missing.yaml")}); err == nil {
+		t.Fatalf("expected an error for a missing file")
+	}
+}
+
+func TestMove(t *testing.T) {
+	testcases := []struct {
+		name   string
+		src    string
+		dst    string
+		config string
+		want   string
+	}{
+		{name: "rename a key", src: "spec.replicas", dst: "spec.scale.replicas", config: "spec: {replicas: 2}", want: "spec: {scale: {replicas: 2}}"},
+		{name: "move into a descendant", src: "a", dst: "a.b", config: "a: {c: 1}", want: "a: {b: {c: 1}}"},
+		{name: "move to an ancestor", src: "a.b.c", dst: "a", config: "a: {b: {c: 1}}", want: "a: 1"},
+		{name: "same key", src: "a.b", dst: `"a".b`, config: "a: {b: 1}", want: "a: {b: 1}"},
+		{name: "overwrite an existing key", src: "a", dst: "b", config: "{a: 1, b: 2}", want: "b: 1"},
+		{name: "array element", src: "containers.[name=web].image", dst: "images.web", config: "containers: [{name: web, image: nginx}]", want: "{containers: [{name: web}], images: {web: nginx}}"},
+		{name: "within the same array", src: "a.[0]", dst: "a.[1]", config: "a: [1, 2, 3]", want: "a: [2, 1]"},
+	}
+	for _, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			config := map[string]interface{}{}
+			if err := yaml.Unmarshal([]byte(testcase.config), &config); err != nil {
+				t.Fatal(err)
+			}
+			want := map[string]interface{}{}
+			if err := yaml.Unmarshal([]byte(testcase.want), &want); err != nil {
+				t.Fatal(err)
+			}
+			if err := parameterizer.Move(testcase.src, testcase.dst, config); err != nil {
+				t.Fatalf("failed to move the key %s to %s . Error: %q", testcase.src, testcase.dst, err)
+			}
+			if !cmp.Equal(config, want) {
+				t.Fatalf("the config is wrong after the move. Difference:\n%s", cmp.Diff(want, config))
+			}
+		})
+	}
+	config := map[string]interface{}{"a": []interface{}{1, 2}, "b": 3}
+	if err := parameterizer.Move("c", "d", config); err == nil {
+		t.Fatalf("expected an error for a source key that doesn't exist")
+	}
+	if err := parameterizer.Move("a.[*]", "d", config); err == nil {
+		t.Fatalf("expected an error for a source key that matches several keys")
+	}
+	if err := parameterizer.Move("b", "a.[5]", config); err == nil {
+		t.Fatalf("expected an error for a destination that can't be set")
+	}
+	want := map[string]interface{}{"a": []interface{}{1, 2}, "b": 3}
+	if !cmp.Equal(config, want) {
+		t.Fatalf("expected the config to be unchanged after a failed move. Difference:\n%s", cmp.Diff(want, config))
+	}
+}
+
+func TestWriteResourcesByKindUnsafeDirs(t *testing.T) {
+	outputPath := filepath.Join(t.TempDir(), "out")
+	k8sResources := []parameterizertypes.K8sResourceT{
+		{"kind": "Deployment", "metadata": map[string]interface{}{"name": "../../web"}},
+	}
+	filesWritten, err := parameterizer.WriteResourcesByKind(k8sResources, outputPath, map[string]string{"deployment": filepath.Join("apps", "deployments")}, "")
+	if err != nil {
+		t.Fatalf("failed to write the resources. Error: %q", err)
+	}
+	want := []string{filepath.Join("apps", "deployments", "web-deployment.yaml")}
+	if !cmp.Equal(filesWritten, want) {
+		t.Fatalf("the files written are incorrect. Differences:\n%s", cmp.Diff(want, filesWritten))
+	}
+	for _, dir := range []string{"../escaped", "apps/../../escaped", "..", "/tmp"} {
+		if _, err := parameterizer.WriteResourcesByKind(k8sResources, outputPath, map[string]string{"deployment": dir}, ""); err == nil {
+			t.Fatalf("expected an error for the kind directory %s", dir)
+		}
+		if _, err := parameterizer.WriteResourcesByKind(k8sResources, outputPath, nil, dir); err == nil {
+			t.Fatalf("expected an error for the default directory %s", dir)
+		}
+	}
+}
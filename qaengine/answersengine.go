@@ -0,0 +1,99 @@
+/*
+ *  Copyright IBM Corporation 2021
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package qaengine
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+
+	qatypes "github.com/konveyor/move2kube/types/qaengine"
+	"github.com/sirupsen/logrus"
+)
+
+// AnswersEngine returns answers from a JSON file that maps the question ids to their answers.
+// Example: {"move2kube.target.registry.url": "quay.io", "move2kube.services.\"web\".enable": true}
+type AnswersEngine struct {
+	answersPath string
+	answers     map[string]interface{}
+}
+
+// NewAnswersEngine creates a new instance of answers engine
+func NewAnswersEngine(answersPath string) *AnswersEngine {
+	return &AnswersEngine{answersPath: answersPath}
+}
+
+// StartEngine loads the answers from the file.
+// Each answer must be a string, a bool or an array of strings.
+func (e *AnswersEngine) StartEngine() error {
+	answersBytes, err := ioutil.ReadFile(e.answersPath)
+	if err != nil {
+		return fmt.Errorf("failed to read the answers file at path %s . Error: %q", e.answersPath, err)
+	}
+	answers := map[string]interface{}{}
+	if err := json.Unmarshal(answersBytes, &answers); err != nil {
+		return fmt.Errorf("the answers file at path %s is not a JSON object mapping the question ids to their answers. Error: %q", e.answersPath, err)
+	}
+	for id, answer := range answers {
+		if err := validateAnswerFormat(answer); err != nil {
+			return fmt.Errorf("the answer to the question %s in the answers file at path %s is invalid. Error: %q", id, e.answersPath, err)
+		}
+	}
+	e.answers = answers
+	return nil
+}
+
+// IsInteractiveEngine returns true if the engine interacts with the user
+func (*AnswersEngine) IsInteractiveEngine() bool {
+	return false
+}
+
+// FetchAnswer fetches the answer from the answers file.
+// If the file doesn't have an answer for the problem, the problem is returned without an answer.
+func (e *AnswersEngine) FetchAnswer(prob qatypes.Problem) (qatypes.Problem, error) {
+	answer, ok := e.answers[prob.ID]
+	if !ok {
+		return prob, nil
+	}
+	logrus.Debugf("Using the answers file %s to answer the question %s", e.answersPath, prob.ID)
+	if err := prob.SetAnswer(answer); err != nil {
+		logrus.Warnf("Ignoring the answer to the question %s in the answers file %s . Error: %q", prob.ID, e.answersPath, err)
+		return prob, err
+	}
+	return prob, nil
+}
+
+// validateAnswerFormat returns an error if the answer is not a string, a bool or an array of strings
+func validateAnswerFormat(answer interface{}) error {
+	switch answer := answer.(type) {
+	case string, bool:
+		return nil
+	case []interface{}:
+		for _, item := range answer {
+			if _, ok := item.(string); !ok {
+				return fmt.Errorf("expected an array of strings. Actual array contains %+v of type %T", item, item)
+			}
+		}
+		return nil
+	}
+	return fmt.Errorf("expected a string, a bool or an array of strings. Actual value %+v is of type %T", answer, answer)
+}
+
+// AddAnswersFile adds the answers from the JSON file at the highest priority
+func AddAnswersFile(answersPath string) error {
+	return AddEngineHighestPriority(NewAnswersEngine(answersPath))
+}
@@ -0,0 +1,78 @@
+/*
+ *  Copyright IBM Corporation 2021
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package qaengine
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/konveyor/move2kube/internal/common"
+)
+
+func writeAnswersFile(t *testing.T, contents string) string {
+	t.Helper()
+	answersPath := filepath.Join(t.TempDir(), "answers.json")
+	if err := ioutil.WriteFile(answersPath, []byte(contents), common.DefaultFilePermission); err != nil {
+		t.Fatalf("Failed to write the answers file at path %s . Error: %q", answersPath, err)
+	}
+	return answersPath
+}
+
+func TestAnswersEngine(t *testing.T) {
+	engines = []Engine{}
+	AddEngine(NewDefaultEngine())
+	answersPath := writeAnswersFile(t, `{
+		"move2kube.answersinput": "quay.io",
+		"move2kube.answersconfirm": true,
+		"move2kube.answersmultiselect": ["Option B", "Option D"],
+		"move2kube.answerswrongtype": "yes"
+	}`)
+	if err := AddAnswersFile(answersPath); err != nil {
+		t.Fatalf("Failed to add the answers file. Error: %q", err)
+	}
+
+	if answer := FetchStringAnswer("move2kube.answersinput", "Enter the name of the registry : ", nil, common.DefaultRegistryURL); answer != "quay.io" {
+		t.Fatalf("Fetched answer was different from the answers file. Fetched answer: %s, expected answer: %s ", answer, "quay.io")
+	}
+	if answer := FetchBoolAnswer("move2kube.answersconfirm", "Test description", nil, false); !answer {
+		t.Fatalf("Fetched answer was different from the answers file. Fetched answer: %t, expected answer: %t ", answer, true)
+	}
+	opts := []string{"Option A", "Option B", "Option C", "Option D"}
+	want := []string{"Option B", "Option D"}
+	if answer := FetchMultiSelectAnswer("move2kube.answersmultiselect", "Test description", nil, []string{"Option A"}, opts); !cmp.Equal(answer, want) {
+		t.Fatalf("Fetched answer was different from the answers file. Fetched answer: %s, expected answer: %s ", answer, want)
+	}
+	if answer := FetchBoolAnswer("move2kube.answerswrongtype", "Test description", nil, false); answer {
+		t.Fatalf("Expected the default answer since the answer in the file has the wrong type. Fetched answer: %t", answer)
+	}
+	if answer := FetchStringAnswer("move2kube.answersmissing", "Test description", nil, "default"); answer != "default" {
+		t.Fatalf("Fetched answer was different from the default one. Fetched answer: %s, expected answer: %s ", answer, "default")
+	}
+}
+
+func TestAnswersEngineInvalidFile(t *testing.T) {
+	for _, contents := range []string{`["not", "an", "object"]`, `{"move2kube.a": 1}`, `{"move2kube.a": {"b": "c"}}`, `{"move2kube.a": ["b", 1]}`, `{"move2kube.a": null}`, `{`} {
+		if err := NewAnswersEngine(writeAnswersFile(t, contents)).StartEngine(); err == nil {
+			t.Fatalf("Expected an error for the answers file %s", contents)
+		}
+	}
+	if err := NewAnswersEngine(filepath.Join(t.TempDir(), "missing.json")).StartEngine(); err == nil {
+		t.Fatalf("Expected an error for an answers file that doesn't exist")
+	}
+}
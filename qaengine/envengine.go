@@ -0,0 +1,113 @@
+/*
+ *  Copyright IBM Corporation 2021
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package qaengine
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/konveyor/move2kube/internal/common"
+	qatypes "github.com/konveyor/move2kube/types/qaengine"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// envKeyPrefix is the prefix of the environment variables that contain answers
+	envKeyPrefix = "MOVE2KUBE_QA_"
+)
+
+var invalidEnvKeyCharsRegex = regexp.MustCompile(`[^A-Z0-9]+`)
+
+// EnvEngine returns answers from environment variables
+type EnvEngine struct {
+}
+
+// NewEnvEngine creates a new instance of env engine
+func NewEnvEngine() *EnvEngine {
+	return new(EnvEngine)
+}
+
+// StartEngine starts the env qa engine
+func (*EnvEngine) StartEngine() error {
+	return nil
+}
+
+// IsInteractiveEngine returns true if the engine interacts with the user
+func (*EnvEngine) IsInteractiveEngine() bool {
+	return false
+}
+
+// FetchAnswer fetches the answer from the environment variable corresponding to the problem id.
+// If the environment variable is not set, the problem is returned without an answer.
+func (*EnvEngine) FetchAnswer(prob qatypes.Problem) (qatypes.Problem, error) {
+	envKey := GetEnvKey(prob.ID)
+	value, ok := os.LookupEnv(envKey)
+	if !ok {
+		return prob, nil
+	}
+	logrus.Debugf("Using the environment variable %s to answer the question %s", envKey, prob.ID)
+	var ans interface{} = value
+	switch prob.Type {
+	case qatypes.ConfirmSolutionFormType:
+		boolAns, err := strconv.ParseBool(strings.TrimSpace(value))
+		if err != nil {
+			return prob, fmt.Errorf("failed to parse the value %s of the environment variable %s as a bool. Error: %q", value, envKey, err)
+		}
+		ans = boolAns
+	case qatypes.MultiSelectSolutionFormType:
+		multiAns := []string{}
+		for _, v := range strings.Split(value, ",") {
+			if v = strings.TrimSpace(v); v != "" {
+				multiAns = append(multiAns, v)
+			}
+		}
+		ans = multiAns
+	}
+	err := prob.SetAnswer(ans)
+	return prob, err
+}
+
+// AddEnvEngine adds the env engine just before the last engine, which is the interactive or the default engine.
+// The answers in environment variables take priority over the last engine, but not over the configs and the caches.
+func AddEnvEngine() error {
+	e := NewEnvEngine()
+	if err := e.StartEngine(); err != nil {
+		return fmt.Errorf("failed to start the engine: %T\n%v\nError: %s", e, e, err)
+	}
+	if len(engines) == 0 {
+		engines = append(engines, e)
+		return nil
+	}
+	last := len(engines) - 1
+	engines = append(engines[:last], e, engines[last])
+	return nil
+}
+
+// GetEnvKey returns the name of the environment variable that can be used to answer the question with the given id.
+// The leading "move2kube." is removed from the id, every run of characters other than letters and digits
+// is replaced with an underscore and the result is upper cased and prefixed with MOVE2KUBE_QA_
+// Example: move2kube.services."my-svc".imagename -> MOVE2KUBE_QA_SERVICES_MY_SVC_IMAGENAME
+// The mapping is not one to one. Ids that only differ in case or punctuation, like a-b and a.b,
+// map to the same environment variable and so all of them get the same answer.
+func GetEnvKey(probID string) string {
+	key := strings.TrimPrefix(probID, common.BaseKey+common.Delim)
+	key = invalidEnvKeyCharsRegex.ReplaceAllLiteralString(strings.ToUpper(key), "_")
+	return envKeyPrefix + strings.Trim(key, "_")
+}
@@ -0,0 +1,140 @@
+/*
+ *  Copyright IBM Corporation 2021
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package qaengine
+
+import (
+	"os"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/konveyor/move2kube/internal/common"
+	"github.com/sirupsen/logrus"
+)
+
+func setEnv(t *testing.T, key, value string) {
+	t.Helper()
+	if err := os.Setenv(key, value); err != nil {
+		t.Fatalf("Failed to set the environment variable %s . Error: %q", key, err)
+	}
+	t.Cleanup(func() { os.Unsetenv(key) })
+}
+
+func TestGetEnvKey(t *testing.T) {
+	testcases := []struct {
+		probID string
+		want   string
+	}{
+		{probID: common.ConfigServicesKey + common.Delim + `"my-svc"` + common.Delim + "imagename", want: "MOVE2KUBE_QA_SERVICES_MY_SVC_IMAGENAME"},
+		{probID: common.ConfigTargetKey + common.Delim + "registry.url", want: "MOVE2KUBE_QA_TARGET_REGISTRY_URL"},
+		{probID: "foo.bar", want: "MOVE2KUBE_QA_FOO_BAR"},
+	}
+	for _, testcase := range testcases {
+		if envKey := GetEnvKey(testcase.probID); envKey != testcase.want {
+			t.Fatalf("Failed to get the environment variable name for %s . Expected: %s Actual: %s", testcase.probID, testcase.want, envKey)
+		}
+	}
+}
+
+func TestEnvEngine(t *testing.T) {
+	logrus.SetLevel(logrus.DebugLevel)
+
+	t.Run("input type problem", func(t *testing.T) {
+		engines = []Engine{}
+		AddEngine(NewEnvEngine())
+		AddEngine(NewDefaultEngine())
+
+		key := common.BaseKey + common.Delim + "envinput"
+		want := "quay.io"
+		setEnv(t, GetEnvKey(key), want)
+		answer := FetchStringAnswer(key, "Enter the name of the registry : ", []string{"Ex : " + common.DefaultRegistryURL}, common.DefaultRegistryURL)
+		if answer != want {
+			t.Fatalf("Fetched answer was different from the environment variable. Fetched answer: %s, expected answer: %s ", answer, want)
+		}
+	})
+
+	t.Run("confirm type problem", func(t *testing.T) {
+		engines = []Engine{}
+		AddEngine(NewEnvEngine())
+		AddEngine(NewDefaultEngine())
+
+		key := common.BaseKey + common.Delim + "envconfirm"
+		setEnv(t, GetEnvKey(key), "true")
+		answer := FetchBoolAnswer(key, "Test description", []string{"Test context"}, false)
+		if !answer {
+			t.Fatalf("Fetched answer was different from the environment variable. Fetched answer: %t, expected answer: %t ", answer, true)
+		}
+	})
+
+	t.Run("multi-select type problem", func(t *testing.T) {
+		engines = []Engine{}
+		AddEngine(NewEnvEngine())
+		AddEngine(NewDefaultEngine())
+
+		key := common.BaseKey + common.Delim + "envmultiselect"
+		setEnv(t, GetEnvKey(key), "Option B, Option D")
+		opts := []string{"Option A", "Option B", "Option C", "Option D"}
+		answer := FetchMultiSelectAnswer(key, "Test description", []string{"Test context"}, []string{"Option A"}, opts)
+		want := []string{"Option B", "Option D"}
+		if !cmp.Equal(answer, want) {
+			t.Fatalf("Fetched answer was different from the environment variable. Fetched answer: %s, expected answer: %s ", answer, want)
+		}
+	})
+
+	t.Run("ids that only differ in punctuation", func(t *testing.T) {
+		engines = []Engine{}
+		AddEngine(NewDefaultEngine())
+		if err := AddEnvEngine(); err != nil {
+			t.Fatalf("Failed to add the env engine. Error: %q", err)
+		}
+
+		dashKey := common.BaseKey + common.Delim + "env-collision"
+		dotKey := common.BaseKey + common.Delim + "env.collision"
+		if GetEnvKey(dashKey) != GetEnvKey(dotKey) {
+			t.Fatalf("Expected the ids %s and %s to map to the same environment variable. Actual: %s and %s", dashKey, dotKey, GetEnvKey(dashKey), GetEnvKey(dotKey))
+		}
+		want := "shared"
+		setEnv(t, GetEnvKey(dashKey), want)
+		for _, key := range []string{dashKey, dotKey} {
+			if answer := FetchStringAnswer(key, "Test description", nil, "default"); answer != want {
+				t.Fatalf("Fetched answer for %s was different from the environment variable. Fetched answer: %s, expected answer: %s ", key, answer, want)
+			}
+		}
+	})
+
+	t.Run("environment variable not set", func(t *testing.T) {
+		engines = []Engine{}
+		AddEngine(NewEnvEngine())
+		AddEngine(NewDefaultEngine())
+
+		key := common.BaseKey + common.Delim + "envnotset"
+		answer := FetchStringAnswer(key, "Test description", nil, "default")
+		if answer != "default" {
+			t.Fatalf("Fetched answer was different from the default one. Fetched answer: %s, expected answer: %s ", answer, "default")
+		}
+	})
+}
+
+func TestStartEngineIgnoresEnv(t *testing.T) {
+	engines = []Engine{}
+	StartEngine(true, 0, false)
+
+	key := common.BaseKey + common.Delim + "envignored"
+	setEnv(t, GetEnvKey(key), "quay.io")
+	if answer := FetchStringAnswer(key, "Test description", nil, "default"); answer != "default" {
+		t.Fatalf("Expected the environment variable to be ignored unless the env engine is added. Fetched answer: %s, expected answer: %s ", answer, "default")
+	}
+}
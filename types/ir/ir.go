@@ -25,6 +25,7 @@ import (
 	"github.com/konveyor/move2kube/internal/common/deepcopy"
 	transformertypes "github.com/konveyor/move2kube/types/transformer"
 	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/apimachinery/pkg/util/strategicpatch"
 	core "k8s.io/kubernetes/pkg/apis/core"
 	networking "k8s.io/kubernetes/pkg/apis/networking"
@@ -45,6 +46,11 @@ const (
 	CNBContainerBuildTypeValue ContainerBuildTypeValue = "CNB"
 )
 
+const (
+	// DockerfileContainerBuildArtifactTypeValue represents the path to the Dockerfile used for the container build
+	DockerfileContainerBuildArtifactTypeValue ContainerBuildArtifactTypeValue = "Dockerfile"
+)
+
 // IR is the intermediate representation filled by source transformers
 type IR struct {
 	Name            string
@@ -66,7 +72,9 @@ type Service struct {
 	Networks                    []string
 	ServiceRelPath              string //Ingress fan-out path
 	OnlyIngress                 bool
-	Daemon                      bool //Gets converted to DaemonSet
+	Daemon                      bool                //Gets converted to DaemonSet
+	ServiceType                 core.ServiceType    // Optional. Overrides the service type chosen during transformation
+	MinAvailable                *intstr.IntOrString // Optional. Creates a PodDisruptionBudget that keeps this many pods of the service available
 }
 
 // Port is a port number with an optional port name.
@@ -76,6 +84,8 @@ type Port networking.ServiceBackendPort
 type ServiceToPodPortForwarding struct {
 	ServicePort Port
 	PodPort     Port
+	Protocol    core.Protocol // Optional. Defaults to TCP
+	NodePort    int32         // Optional. Only used by NodePort services
 }
 
 // ContainerBuildTypeValue stores the container build type
@@ -180,6 +190,12 @@ func (service *Service) merge(nService Service) {
 	if nService.ServiceRelPath != "" {
 		service.ServiceRelPath = nService.ServiceRelPath
 	}
+	if nService.ServiceType != "" {
+		service.ServiceType = nService.ServiceType
+	}
+	if nService.MinAvailable != nil {
+		service.MinAvailable = nService.MinAvailable
+	}
 	service.OnlyIngress = service.OnlyIngress && nService.OnlyIngress
 	service.Daemon = service.Daemon && nService.Daemon
 	// TODO: Check if this needs a more intelligent merge
@@ -187,20 +203,27 @@ func (service *Service) merge(nService Service) {
 }
 
 // AddPortForwarding adds a new port forwarding to the service.
-func (service *Service) AddPortForwarding(servicePort Port, podPort Port) error {
+// If the protocol is empty, TCP is used. The same port number can be forwarded once for each protocol.
+func (service *Service) AddPortForwarding(servicePort Port, podPort Port, protocol core.Protocol) error {
+	getProtocol := func(p core.Protocol) core.Protocol {
+		if p == "" {
+			return core.ProtocolTCP
+		}
+		return p
+	}
 	for _, forwarding := range service.ServiceToPodPortForwardings {
 		if servicePort.Name != "" && forwarding.ServicePort.Name == servicePort.Name {
 			err := fmt.Errorf("the port name %s on %s service is already in use. Not adding the new forwarding", servicePort.Name, service.Name)
 			logrus.Warn(err)
 			return err
 		}
-		if forwarding.ServicePort.Number == servicePort.Number {
-			err := fmt.Errorf("the port number %d on %s service is already in use. Not adding the new forwarding", servicePort.Number, service.Name)
+		if forwarding.ServicePort.Number == servicePort.Number && getProtocol(forwarding.Protocol) == getProtocol(protocol) {
+			err := fmt.Errorf("the port number %d with the protocol %s on %s service is already in use. Not adding the new forwarding", servicePort.Number, getProtocol(protocol), service.Name)
 			logrus.Warn(err)
 			return err
 		}
 	}
-	newForwarding := ServiceToPodPortForwarding{ServicePort: servicePort, PodPort: podPort}
+	newForwarding := ServiceToPodPortForwarding{ServicePort: servicePort, PodPort: podPort, Protocol: protocol}
 	service.ServiceToPodPortForwardings = append(service.ServiceToPodPortForwardings, newForwarding)
 	return nil
 }
@@ -265,6 +288,15 @@ func (c *ContainerBuild) Merge(newc ContainerBuild) bool {
 	return true
 }
 
+// GetDockerfilePath returns the path to the Dockerfile used for the container build.
+// If it is not set, the Dockerfile (or Containerfile) is assumed to be in the context directory.
+func (c *ContainerBuild) GetDockerfilePath() string {
+	if dockerfilePaths := c.Artifacts[DockerfileContainerBuildArtifactTypeValue]; len(dockerfilePaths) > 0 {
+		return dockerfilePaths[0]
+	}
+	return common.GetDockerfilePathInDir(c.ContextPath)
+}
+
 // AddExposedPort adds an exposed port to a container
 func (c *ContainerImage) AddExposedPort(port int) {
 	if !common.IsIntPresent(c.ExposedPorts, port) {
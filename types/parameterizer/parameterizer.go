@@ -58,6 +58,12 @@ type PackagingSpecPathT struct {
 	Kustomize     string   `yaml:"kustomize,omitempty" json:"kustomize,omitempty"`
 	OCTemplates   string   `yaml:"openshiftTemplates,omitempty" json:"openshiftTemplates,omitempty"`
 	Envs          []string `yaml:"envs,omitempty" json:"envs,omitempty"`
+	// Kubernetes is the directory for the concrete k8s resources and the patch files. It is only written when selected
+	Kubernetes string `yaml:"kubernetes,omitempty" json:"kubernetes,omitempty"`
+	// OverwriteValues overwrites the existing Helm values files instead of merging the new values into them
+	OverwriteValues bool `yaml:"overwriteValues,omitempty" json:"overwriteValues,omitempty"`
+	// HelmChartVersion is the version in the Chart.yaml. It must be a semantic version. Defaults to 0.1.0
+	HelmChartVersion string `yaml:"helmChartVersion,omitempty" json:"helmChartVersion,omitempty"`
 }
 
 // ParameterizerFileT is the file format for the parameterizers
@@ -70,6 +76,9 @@ type ParameterizerFileT struct {
 // ParameterizerSpecT is the spec inside the parameterizers file
 type ParameterizerSpecT struct {
 	Parameterizers []ParameterizerT `yaml:"parameterizers" json:"parameterizers"`
+	// Kind and APIVersion restrict all the parameterizers in the file to the k8s resources with that kind and apiVersion
+	Kind       string `yaml:"kind,omitempty" json:"kind,omitempty"`
+	APIVersion string `yaml:"apiVersion,omitempty" json:"apiVersion,omitempty"`
 }
 
 // ParameterizerT is a paramterizer
@@ -81,6 +90,12 @@ type ParameterizerT struct {
 	Question   *qaengine.Problem `yaml:"question,omitempty" json:"question,omitempty"`
 	Filters    []FilterT         `yaml:"filters,omitempty" json:"filters,omitempty"`
 	Parameters []ParameterT      `yaml:"parameters,omitempty" json:"parameters,omitempty"`
+	// Description is written as a comment above the parameterized values in the Helm values files
+	Description string `yaml:"description,omitempty" json:"description,omitempty"`
+	// Kind and APIVersion restrict the parameterizer to the k8s resources with that kind and apiVersion. Empty matches all.
+	// Unlike the filters, they are matched exactly and are checked before the target key is looked up.
+	Kind       string `yaml:"kind,omitempty" json:"kind,omitempty"`
+	APIVersion string `yaml:"apiVersion,omitempty" json:"apiVersion,omitempty"`
 }
 
 // FilterT is used to choose the k8s resources that the parameterizer should be applied on
@@ -98,6 +113,8 @@ type ParameterT struct {
 	HelmTemplate      string            `yaml:"helmTemplate,omitempty" json:"helmTemplate,omitempty"`
 	OpenshiftTemplate string            `yaml:"openshiftTemplate,omitempty" json:"openshiftTemplate,omitempty"`
 	Values            []ParameterValueT `yaml:"values,omitempty" json:"values,omitempty"`
+	// Description overrides the description of the parameterizer for this parameter
+	Description string `yaml:"description,omitempty" json:"description,omitempty"`
 }
 
 // ParameterValueT is used to specify the value for a parameter in different contexts
@@ -132,6 +149,12 @@ type PatchT struct {
 	Value interface{} `yaml:"value"`
 }
 
+// KubernetesPatchT contains the json patches for a single k8s resource in the patch file of the kubernetes output
+type KubernetesPatchT struct {
+	Target PatchMetadataTargetT `yaml:"target"`
+	Patch  []PatchT             `yaml:"patch"`
+}
+
 // OCParamT is the type for a single Openshift Templates parameter
 type OCParamT struct {
 	Name  string `yaml:"name"`
@@ -161,6 +184,8 @@ const (
 	TargetKustomize ParamTargetT = "kustomize"
 	// TargetOCTemplates is used when the target is the parameterization of Openshift Templates
 	TargetOCTemplates ParamTargetT = "openshifttemplates"
+	// TargetKubernetes is used when the parameterized fields are left concrete and the changes for each env go into a separate patch file
+	TargetKubernetes ParamTargetT = "kubernetes"
 	// ParamQuesIDPrefix is used as a prefix when the key is not specified in the questions in a parameterizer
 	ParamQuesIDPrefix = common.BaseKey + common.Delim + "parameterization"
 )
@@ -29,4 +29,45 @@ const DockerfilePathType transformertypes.PathType = "Dockerfile"
 const (
 	// DockerfileTemplateConfigConfigType stores the imagename for the dockerfile
 	DockerfileTemplateConfigConfigType transformertypes.ConfigType = "DockerfileTemplateConfig"
+	// DockerfileMetadataConfigType stores the metadata extracted from the dockerfile
+	DockerfileMetadataConfigType transformertypes.ConfigType = "DockerfileMetadata"
 )
+
+// DockerfileMetadataConfig stores the metadata extracted from the dockerfile
+type DockerfileMetadataConfig struct {
+	// CopySources are the paths in the build context that are used by the COPY and ADD instructions
+	CopySources []string `yaml:"copySources,omitempty" json:"copySources,omitempty"`
+	// BuildArgs are the build arguments declared using the ARG instructions
+	BuildArgs []DockerfileBuildArg `yaml:"buildArgs,omitempty" json:"buildArgs,omitempty"`
+	// IgnorePatterns are the patterns from the .dockerignore file in the order they were specified.
+	// Patterns starting with ! are exceptions. If it is empty, all the files in the build context are included.
+	IgnorePatterns []string `yaml:"ignorePatterns,omitempty" json:"ignorePatterns,omitempty"`
+	// BaseImages are the images used by the FROM instructions in the order they appear. The last one is the base of the final stage.
+	BaseImages []DockerfileBaseImage `yaml:"baseImages,omitempty" json:"baseImages,omitempty"`
+	// HasHealthcheck is true if the final stage has a HEALTHCHECK instruction that is not HEALTHCHECK NONE
+	HasHealthcheck bool `yaml:"hasHealthcheck,omitempty" json:"hasHealthcheck,omitempty"`
+	// Labels are the labels set by the LABEL instructions in the final stage
+	Labels map[string]string `yaml:"labels,omitempty" json:"labels,omitempty"`
+}
+
+// DockerfileBaseImage is the image used by a FROM instruction
+type DockerfileBaseImage struct {
+	// Reference is the image as written in the dockerfile
+	Reference  string `yaml:"reference" json:"reference"`
+	Repository string `yaml:"repository" json:"repository"`
+	Tag        string `yaml:"tag,omitempty" json:"tag,omitempty"`
+	Digest     string `yaml:"digest,omitempty" json:"digest,omitempty"`
+	// Stage is the name given to the stage using FROM ... AS <name>
+	Stage string `yaml:"stage,omitempty" json:"stage,omitempty"`
+	// FromStage is true if the reference is the name of an earlier stage instead of an image
+	FromStage bool `yaml:"fromStage,omitempty" json:"fromStage,omitempty"`
+}
+
+// DockerfileBuildArg is a build argument declared in the dockerfile
+type DockerfileBuildArg struct {
+	Name string `yaml:"name" json:"name"`
+	// Default is nil if the build argument doesn't have a default value
+	Default *string `yaml:"default,omitempty" json:"default,omitempty"`
+	// Global is true if the build argument is declared before the first FROM instruction
+	Global bool `yaml:"global,omitempty" json:"global,omitempty"`
+}